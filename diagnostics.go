@@ -0,0 +1,104 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	steputiltools "github.com/bitrise-io/go-steputils/tools"
+	"github.com/bitrise-io/go-utils/log"
+	"github.com/bitrise-steplib/steps-xamarin-archive/go-xamarin/tools/buildtools/diagnostics"
+)
+
+const (
+	buildErrorsEnvKey       = "BITRISE_XAMARIN_BUILD_ERRORS"
+	buildErrorCountEnvKey   = "BITRISE_XAMARIN_BUILD_ERROR_COUNT"
+	buildWarningCountEnvKey = "BITRISE_XAMARIN_BUILD_WARNING_COUNT"
+
+	maxSummaryErrorsPerProject = 10
+
+	// the exported errors are limited, env vars are not meant to hold the whole build log
+	maxExportedErrors      = 50
+	maxExportedErrorsBytes = 8 * 1024
+)
+
+// filterDiagnostics returns the diagnostics of the given severity.
+func filterDiagnostics(diags []diagnostics.Diagnostic, severity diagnostics.Severity) []diagnostics.Diagnostic {
+	var filtered []diagnostics.Diagnostic
+	for _, diagnostic := range diags {
+		if diagnostic.Severity == severity {
+			filtered = append(filtered, diagnostic)
+		}
+	}
+	return filtered
+}
+
+// printFailureSummary logs the number of errors and warnings and the first errors of every project.
+func printFailureSummary(diags []diagnostics.Diagnostic) {
+	errors := filterDiagnostics(diags, diagnostics.SeverityError)
+	warnings := filterDiagnostics(diags, diagnostics.SeverityWarning)
+
+	fmt.Println()
+	log.Errorf("Build failed with %d error(s) and %d warning(s)", len(errors), len(warnings))
+
+	var projectNames []string
+	errorsByProject := map[string][]diagnostics.Diagnostic{}
+	for _, diagnostic := range errors {
+		if _, ok := errorsByProject[diagnostic.Project]; !ok {
+			projectNames = append(projectNames, diagnostic.Project)
+		}
+		errorsByProject[diagnostic.Project] = append(errorsByProject[diagnostic.Project], diagnostic)
+	}
+
+	for _, projectName := range projectNames {
+		projectErrors := errorsByProject[projectName]
+
+		fmt.Println()
+		log.Errorf("%s: %d error(s)", projectName, len(projectErrors))
+		for i, diagnostic := range projectErrors {
+			if i == maxSummaryErrorsPerProject {
+				log.Printf("  ... and %d more", len(projectErrors)-maxSummaryErrorsPerProject)
+				break
+			}
+			log.Printf("  %s", diagnostic)
+		}
+	}
+}
+
+// exportedErrors returns the errors to export, one per line. At most maxExportedErrors errors
+// and maxExportedErrorsBytes bytes are returned, the last line refers to the build logs dir for the rest.
+func exportedErrors(errors []diagnostics.Diagnostic, logsDir string) string {
+	var lines []string
+	size := 0
+	for i, diagnostic := range errors {
+		line := diagnostic.Project + ": " + diagnostic.String()
+		if i == maxExportedErrors || size+len(line)+1 > maxExportedErrorsBytes {
+			lines = append(lines, fmt.Sprintf("... and %d more error(s), see the build logs in: %s", len(errors)-i, logsDir))
+			break
+		}
+		lines = append(lines, line)
+		size += len(line) + 1
+	}
+	return strings.Join(lines, "\n")
+}
+
+// exportDiagnostics exports the errors, one per line, and the number of errors and warnings.
+func exportDiagnostics(diags []diagnostics.Diagnostic, logsDir string) error {
+	errors := filterDiagnostics(diags, diagnostics.SeverityError)
+	warnings := filterDiagnostics(diags, diagnostics.SeverityWarning)
+
+	envs := []struct {
+		key   string
+		value string
+	}{
+		{buildErrorsEnvKey, exportedErrors(errors, logsDir)},
+		{buildErrorCountEnvKey, strconv.Itoa(len(errors))},
+		{buildWarningCountEnvKey, strconv.Itoa(len(warnings))},
+	}
+	for _, env := range envs {
+		if err := steputiltools.ExportEnvironmentWithEnvman(env.key, env.value); err != nil {
+			return fmt.Errorf("failed to export build diagnostics into (%s)", env.key)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/bitrise-steplib/steps-xamarin-archive/go-xamarin/tools/buildtools/diagnostics"
+)
+
+func TestExportedErrors(t *testing.T) {
+	newErrors := func(count, messageLength int) []diagnostics.Diagnostic {
+		var errors []diagnostics.Diagnostic
+		for i := 0; i < count; i++ {
+			errors = append(errors, diagnostics.Diagnostic{
+				Project:  "App",
+				File:     "Main.cs",
+				Line:     i + 1,
+				Severity: diagnostics.SeverityError,
+				Code:     "CS0103",
+				Message:  strings.Repeat("x", messageLength),
+			})
+		}
+		return errors
+	}
+
+	tests := []struct {
+		name      string
+		errors    []diagnostics.Diagnostic
+		wantLines int
+		wantLast  string
+	}{
+		{
+			name:      "no errors",
+			wantLines: 1,
+			wantLast:  "",
+		},
+		{
+			name:      "every error",
+			errors:    newErrors(3, 10),
+			wantLines: 3,
+			wantLast:  "App: " + newErrors(3, 10)[2].String(),
+		},
+		{
+			name:      "error count limit",
+			errors:    newErrors(maxExportedErrors+10, 10),
+			wantLines: maxExportedErrors + 1,
+			wantLast:  "... and 10 more error(s), see the build logs in: /deploy/logs",
+		},
+		{
+			name:      "length limit",
+			errors:    newErrors(20, 1000),
+			wantLines: 8,
+			wantLast:  "... and 13 more error(s), see the build logs in: /deploy/logs",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := exportedErrors(tt.errors, "/deploy/logs")
+			lines := strings.Split(got, "\n")
+
+			if len(lines) != tt.wantLines {
+				t.Errorf("exportedErrors() returned %d lines, want %d", len(lines), tt.wantLines)
+			}
+			if last := lines[len(lines)-1]; last != tt.wantLast {
+				t.Errorf("exportedErrors() last line = %q, want %q", last, tt.wantLast)
+			}
+			if len(got) > maxExportedErrorsBytes+len(fmt.Sprintf("\n... and %d more error(s), see the build logs in: /deploy/logs", len(tt.errors))) {
+				t.Errorf("exportedErrors() returned %d bytes", len(got))
+			}
+		})
+	}
+}
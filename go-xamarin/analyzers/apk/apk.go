@@ -0,0 +1,122 @@
+package apk
+
+import (
+	"archive/zip"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+
+	"github.com/bitrise-io/go-utils/log"
+)
+
+const manifestEntryName = "AndroidManifest.xml"
+
+// Model is the metadata of an APK, read from its compiled manifest and its native libraries.
+type Model struct {
+	PackageName      string   `json:"package_name"`
+	VersionCode      string   `json:"version_code"`
+	VersionName      string   `json:"version_name"`
+	MinSDKVersion    string   `json:"min_sdk_version"`
+	TargetSDKVersion string   `json:"target_sdk_version"`
+	Permissions      []string `json:"permissions"`
+	ABIs             []string `json:"abis"` // Empty if the APK has no native libraries
+}
+
+// Inspect reads the metadata of the APK at the given path.
+func Inspect(pth string) (Model, error) {
+	reader, err := zip.OpenReader(pth)
+	if err != nil {
+		return Model{}, fmt.Errorf("failed to open apk (%s), error: %s", pth, err)
+	}
+	defer func() {
+		if err := reader.Close(); err != nil {
+			log.Warnf("Failed to close apk (%s), error: %s", pth, err)
+		}
+	}()
+
+	var manifest []byte
+	abis := map[string]bool{}
+
+	for _, file := range reader.File {
+		if file.Name == manifestEntryName {
+			if manifest, err = readZipFile(file); err != nil {
+				return Model{}, fmt.Errorf("failed to read %s of apk (%s), error: %s", manifestEntryName, pth, err)
+			}
+			continue
+		}
+
+		// native libraries: lib/<abi>/<library>.so
+		if split := strings.Split(file.Name, "/"); len(split) == 3 && split[0] == "lib" && strings.HasSuffix(split[2], ".so") {
+			abis[split[1]] = true
+		}
+	}
+
+	if manifest == nil {
+		return Model{}, fmt.Errorf("no %s found in apk (%s)", manifestEntryName, pth)
+	}
+
+	model, err := ParseManifest(manifest)
+	if err != nil {
+		return Model{}, fmt.Errorf("failed to parse %s of apk (%s), error: %s", manifestEntryName, pth, err)
+	}
+
+	for abi := range abis {
+		model.ABIs = append(model.ABIs, abi)
+	}
+	sort.Strings(model.ABIs)
+
+	return model, nil
+}
+
+// ParseManifest returns the metadata defined by the compiled (binary XML) AndroidManifest.xml.
+func ParseManifest(content []byte) (Model, error) {
+	root, err := ParseAXML(content)
+	if err != nil {
+		return Model{}, err
+	}
+	if root.Name != "manifest" {
+		return Model{}, fmt.Errorf("root element is %s instead of manifest", root.Name)
+	}
+
+	model := Model{
+		Permissions: []string{},
+		ABIs:        []string{},
+	}
+	model.PackageName, _ = root.Attribute("package")
+	model.VersionCode, _ = root.Attribute("versionCode")
+	model.VersionName, _ = root.Attribute("versionName")
+
+	for _, usesSDK := range root.ChildrenNamed("uses-sdk") {
+		model.MinSDKVersion, _ = usesSDK.Attribute("minSdkVersion")
+		model.TargetSDKVersion, _ = usesSDK.Attribute("targetSdkVersion")
+	}
+	// the target SDK version defaults to the min SDK version
+	if model.TargetSDKVersion == "" {
+		model.TargetSDKVersion = model.MinSDKVersion
+	}
+
+	for _, elementName := range []string{"uses-permission", "uses-permission-sdk-23"} {
+		for _, permission := range root.ChildrenNamed(elementName) {
+			if name, ok := permission.Attribute("name"); ok && name != "" {
+				model.Permissions = append(model.Permissions, name)
+			}
+		}
+	}
+
+	return model, nil
+}
+
+func readZipFile(file *zip.File) ([]byte, error) {
+	reader, err := file.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := reader.Close(); err != nil {
+			log.Warnf("Failed to close %s, error: %s", file.Name, err)
+		}
+	}()
+
+	return ioutil.ReadAll(reader)
+}
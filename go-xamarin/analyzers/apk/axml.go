@@ -0,0 +1,397 @@
+package apk
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"unicode/utf16"
+)
+
+// Chunk types of the binary XML format, used by the compiled AndroidManifest.xml.
+const (
+	chunkStringPool   = 0x0001
+	chunkXML          = 0x0003
+	chunkStartElement = 0x0102
+	chunkEndElement   = 0x0103
+	chunkResourceMap  = 0x0180
+
+	stringPoolUTF8Flag = 1 << 8
+	noIndex            = 0xFFFFFFFF
+)
+
+// AndroidNamespace is the namespace of the android: attributes.
+const AndroidNamespace = "http://schemas.android.com/apk/res/android"
+
+// Data types of the typed attribute values.
+const (
+	typeReference = 0x01
+	typeString    = 0x03
+	typeFloat     = 0x04
+	typeIntDec    = 0x10
+	typeIntHex    = 0x11
+	typeIntBool   = 0x12
+)
+
+// attributeNamesByResourceID are the names of the android attributes, which are identified by their resource id
+// if the string pool has no name for them.
+var attributeNamesByResourceID = map[uint32]string{
+	0x01010003: "name",
+	0x0101020c: "minSdkVersion",
+	0x0101021b: "versionCode",
+	0x0101021c: "versionName",
+	0x01010270: "targetSdkVersion",
+	0x01010271: "maxSdkVersion",
+}
+
+// Element is an element of a binary XML document.
+type Element struct {
+	Name       string
+	Attributes []Attribute
+	Children   []*Element
+}
+
+// Attribute is an attribute of a binary XML element, its value is formatted as a string:
+// references are formatted like @0x7f0b0001, as the resource table is not resolved.
+type Attribute struct {
+	Namespace string
+	Name      string
+	Value     string
+}
+
+// Attribute returns the value of the attribute with the given local name. The attribute in the android namespace
+// is preferred (android:name over app:name), attributes without namespace (like the package of the manifest)
+// and of other namespaces are returned if the element has no such android attribute.
+func (element Element) Attribute(name string) (string, bool) {
+	for _, attribute := range element.Attributes {
+		if attribute.Name == name && attribute.Namespace == AndroidNamespace {
+			return attribute.Value, true
+		}
+	}
+	for _, attribute := range element.Attributes {
+		if attribute.Name == name {
+			return attribute.Value, true
+		}
+	}
+	return "", false
+}
+
+// ChildrenNamed returns the child elements with the given name.
+func (element Element) ChildrenNamed(name string) []*Element {
+	var children []*Element
+	for _, child := range element.Children {
+		if child.Name == name {
+			children = append(children, child)
+		}
+	}
+	return children
+}
+
+// axmlDecoder reads the chunks of a binary XML document.
+type axmlDecoder struct {
+	data        []byte
+	strings     []string
+	resourceIDs []uint32
+}
+
+// ParseAXML decodes a binary XML document and returns its root element.
+func ParseAXML(data []byte) (*Element, error) {
+	decoder := axmlDecoder{data: data}
+
+	chunkType, headerSize, size, err := decoder.chunkHeader(0)
+	if err != nil {
+		return nil, err
+	}
+	if chunkType != chunkXML {
+		return nil, fmt.Errorf("not a binary XML document, chunk type: 0x%04x", chunkType)
+	}
+	if int(size) > len(data) {
+		return nil, fmt.Errorf("truncated binary XML document")
+	}
+
+	var root *Element
+	var stack []*Element
+
+	for offset := int(headerSize); offset < int(size); {
+		chunkType, _, chunkSize, err := decoder.chunkHeader(offset)
+		if err != nil {
+			return nil, err
+		}
+		if chunkSize < 8 || offset+int(chunkSize) > int(size) {
+			return nil, fmt.Errorf("invalid chunk size (%d) at offset: %d", chunkSize, offset)
+		}
+
+		switch chunkType {
+		case chunkStringPool:
+			if err := decoder.readStringPool(offset); err != nil {
+				return nil, fmt.Errorf("invalid string pool, error: %s", err)
+			}
+		case chunkResourceMap:
+			decoder.readResourceMap(offset, int(chunkSize))
+		case chunkStartElement:
+			element, err := decoder.readStartElement(offset)
+			if err != nil {
+				return nil, err
+			}
+
+			if len(stack) > 0 {
+				parent := stack[len(stack)-1]
+				parent.Children = append(parent.Children, element)
+			} else if root == nil {
+				root = element
+			}
+			stack = append(stack, element)
+		case chunkEndElement:
+			if len(stack) > 0 {
+				stack = stack[:len(stack)-1]
+			}
+		}
+
+		offset += int(chunkSize)
+	}
+
+	if root == nil {
+		return nil, fmt.Errorf("no root element found")
+	}
+	return root, nil
+}
+
+func (decoder axmlDecoder) uint16(offset int) (uint16, error) {
+	if offset < 0 || offset+2 > len(decoder.data) {
+		return 0, fmt.Errorf("unexpected end of data at offset: %d", offset)
+	}
+	return binary.LittleEndian.Uint16(decoder.data[offset:]), nil
+}
+
+func (decoder axmlDecoder) uint32(offset int) (uint32, error) {
+	if offset < 0 || offset+4 > len(decoder.data) {
+		return 0, fmt.Errorf("unexpected end of data at offset: %d", offset)
+	}
+	return binary.LittleEndian.Uint32(decoder.data[offset:]), nil
+}
+
+func (decoder axmlDecoder) chunkHeader(offset int) (chunkType, headerSize uint16, size uint32, err error) {
+	if chunkType, err = decoder.uint16(offset); err != nil {
+		return
+	}
+	if headerSize, err = decoder.uint16(offset + 2); err != nil {
+		return
+	}
+	size, err = decoder.uint32(offset + 4)
+	return
+}
+
+func (decoder *axmlDecoder) readStringPool(offset int) error {
+	_, headerSize, _, err := decoder.chunkHeader(offset)
+	if err != nil {
+		return err
+	}
+
+	count, err := decoder.uint32(offset + 8)
+	if err != nil {
+		return err
+	}
+	flags, err := decoder.uint32(offset + 16)
+	if err != nil {
+		return err
+	}
+	stringsStart, err := decoder.uint32(offset + 20)
+	if err != nil {
+		return err
+	}
+	if int(count) > len(decoder.data)/4 {
+		return fmt.Errorf("invalid string count: %d", count)
+	}
+
+	decoder.strings = make([]string, count)
+	for i := 0; i < int(count); i++ {
+		stringOffset, err := decoder.uint32(offset + int(headerSize) + i*4)
+		if err != nil {
+			return err
+		}
+
+		start := offset + int(stringsStart) + int(stringOffset)
+		if flags&stringPoolUTF8Flag != 0 {
+			decoder.strings[i], err = decoder.utf8String(start)
+		} else {
+			decoder.strings[i], err = decoder.utf16String(start)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// utf8String reads a string of an UTF-8 string pool: the length in UTF-16 units, the length in bytes, then the bytes.
+func (decoder axmlDecoder) utf8String(offset int) (string, error) {
+	readLength := func(offset int) (int, int, error) {
+		if offset >= len(decoder.data) {
+			return 0, 0, fmt.Errorf("unexpected end of data at offset: %d", offset)
+		}
+		length := int(decoder.data[offset])
+		if length&0x80 == 0 {
+			return length, 1, nil
+		}
+		if offset+1 >= len(decoder.data) {
+			return 0, 0, fmt.Errorf("unexpected end of data at offset: %d", offset)
+		}
+		return (length&0x7f)<<8 | int(decoder.data[offset+1]), 2, nil
+	}
+
+	_, n, err := readLength(offset)
+	if err != nil {
+		return "", err
+	}
+	offset += n
+
+	length, n, err := readLength(offset)
+	if err != nil {
+		return "", err
+	}
+	offset += n
+
+	if offset+length > len(decoder.data) {
+		return "", fmt.Errorf("unexpected end of data at offset: %d", offset)
+	}
+	return string(decoder.data[offset : offset+length]), nil
+}
+
+// utf16String reads a string of an UTF-16 string pool: the length in UTF-16 units, then the units.
+func (decoder axmlDecoder) utf16String(offset int) (string, error) {
+	length16, err := decoder.uint16(offset)
+	if err != nil {
+		return "", err
+	}
+	length := int(length16)
+	offset += 2
+
+	if length&0x8000 != 0 {
+		low, err := decoder.uint16(offset)
+		if err != nil {
+			return "", err
+		}
+		length = (length&0x7fff)<<16 | int(low)
+		offset += 2
+	}
+
+	if offset+length*2 > len(decoder.data) {
+		return "", fmt.Errorf("unexpected end of data at offset: %d", offset)
+	}
+
+	units := make([]uint16, length)
+	for i := range units {
+		units[i] = binary.LittleEndian.Uint16(decoder.data[offset+i*2:])
+	}
+	return string(utf16.Decode(units)), nil
+}
+
+func (decoder *axmlDecoder) readResourceMap(offset, size int) {
+	_, headerSize, _, err := decoder.chunkHeader(offset)
+	if err != nil {
+		return
+	}
+
+	for pos := offset + int(headerSize); pos+4 <= offset+size; pos += 4 {
+		id, _ := decoder.uint32(pos)
+		decoder.resourceIDs = append(decoder.resourceIDs, id)
+	}
+}
+
+func (decoder axmlDecoder) string(index uint32) string {
+	if index == noIndex || int(index) >= len(decoder.strings) {
+		return ""
+	}
+	return decoder.strings[index]
+}
+
+// attributeName returns the name of the attribute, by its resource id if the string pool has no name for it.
+func (decoder axmlDecoder) attributeName(index uint32) string {
+	if name := decoder.string(index); name != "" {
+		return name
+	}
+	if int(index) < len(decoder.resourceIDs) {
+		return attributeNamesByResourceID[decoder.resourceIDs[index]]
+	}
+	return ""
+}
+
+func (decoder axmlDecoder) readStartElement(offset int) (*Element, error) {
+	_, headerSize, _, err := decoder.chunkHeader(offset)
+	if err != nil {
+		return nil, err
+	}
+
+	ext := offset + int(headerSize)
+	name, err := decoder.uint32(ext + 4)
+	if err != nil {
+		return nil, err
+	}
+	attributeStart, err := decoder.uint16(ext + 8)
+	if err != nil {
+		return nil, err
+	}
+	attributeSize, err := decoder.uint16(ext + 10)
+	if err != nil {
+		return nil, err
+	}
+	attributeCount, err := decoder.uint16(ext + 12)
+	if err != nil {
+		return nil, err
+	}
+
+	element := &Element{Name: decoder.string(name)}
+
+	for i := 0; i < int(attributeCount); i++ {
+		pos := ext + int(attributeStart) + i*int(attributeSize)
+
+		namespace, err := decoder.uint32(pos)
+		if err != nil {
+			return nil, err
+		}
+		attributeName, err := decoder.uint32(pos + 4)
+		if err != nil {
+			return nil, err
+		}
+		rawValue, err := decoder.uint32(pos + 8)
+		if err != nil {
+			return nil, err
+		}
+		if pos+20 > len(decoder.data) {
+			return nil, fmt.Errorf("unexpected end of data at offset: %d", pos)
+		}
+		dataType := decoder.data[pos+15]
+		data, _ := decoder.uint32(pos + 16)
+
+		element.Attributes = append(element.Attributes, Attribute{
+			Namespace: decoder.string(namespace),
+			Name:      decoder.attributeName(attributeName),
+			Value:     decoder.attributeValue(rawValue, dataType, data),
+		})
+	}
+
+	return element, nil
+}
+
+func (decoder axmlDecoder) attributeValue(rawValue uint32, dataType uint8, data uint32) string {
+	if rawValue != noIndex {
+		return decoder.string(rawValue)
+	}
+
+	switch dataType {
+	case typeString:
+		return decoder.string(data)
+	case typeReference:
+		return fmt.Sprintf("@0x%08x", data)
+	case typeIntDec, typeIntHex:
+		return fmt.Sprintf("%d", int32(data))
+	case typeIntBool:
+		if data != 0 {
+			return "true"
+		}
+		return "false"
+	case typeFloat:
+		return fmt.Sprintf("%g", math.Float32frombits(data))
+	default:
+		return fmt.Sprintf("0x%08x", data)
+	}
+}
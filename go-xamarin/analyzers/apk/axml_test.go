@@ -0,0 +1,136 @@
+package apk
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// The testdata manifests are compiled from:
+// <manifest xmlns:android="http://schemas.android.com/apk/res/android" xmlns:app="http://schemas.android.com/apk/res-auto"
+//     package="com.example.app" android:versionCode="42" android:versionName="1.4.2">
+//   <uses-sdk android:minSdkVersion="21" android:targetSdkVersion="34" />
+//   <uses-permission app:name="com.example.Other" android:name="android.permission.INTERNET" />
+//   <uses-permission android:name="android.permission.CAMERA" />
+//   <application android:name="Ünïcode App" />
+// </manifest>
+// AndroidManifest.xml has an UTF-16 string pool, AndroidManifest-utf8.xml has an UTF-8 string pool
+// without the android attribute names, which are resolved by their resource ids.
+
+func readTestManifest(t *testing.T, name string) []byte {
+	content, err := ioutil.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return content
+}
+
+func TestParseAXML(t *testing.T) {
+	for _, name := range []string{"AndroidManifest.xml", "AndroidManifest-utf8.xml"} {
+		t.Run(name, func(t *testing.T) {
+			root, err := ParseAXML(readTestManifest(t, name))
+			if err != nil {
+				t.Fatalf("ParseAXML() error = %v", err)
+			}
+
+			if root.Name != "manifest" {
+				t.Errorf("root element = %s, want manifest", root.Name)
+			}
+
+			var childNames []string
+			for _, child := range root.Children {
+				childNames = append(childNames, child.Name)
+			}
+			if want := []string{"uses-sdk", "uses-permission", "uses-permission", "application"}; !reflect.DeepEqual(childNames, want) {
+				t.Errorf("children = %v, want %v", childNames, want)
+			}
+
+			application := root.ChildrenNamed("application")
+			if len(application) != 1 {
+				t.Fatalf("application elements = %d, want 1", len(application))
+			}
+			if got, _ := application[0].Attribute("name"); got != "Ünïcode App" {
+				t.Errorf("application name = %q, want %q", got, "Ünïcode App")
+			}
+		})
+	}
+}
+
+func TestParseAXMLInvalid(t *testing.T) {
+	content := readTestManifest(t, "AndroidManifest.xml")
+
+	tests := []struct {
+		name    string
+		content []byte
+	}{
+		{name: "empty", content: []byte{}},
+		{name: "not binary xml", content: []byte("<manifest package=\"com.example.app\" />")},
+		{name: "truncated", content: content[:len(content)/2]},
+		{name: "header only", content: content[:8]},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseAXML(tt.content); err == nil {
+				t.Errorf("ParseAXML() error = nil, want error")
+			}
+		})
+	}
+}
+
+func TestElementAttribute(t *testing.T) {
+	element := Element{
+		Name: "uses-permission",
+		Attributes: []Attribute{
+			{Namespace: "http://schemas.android.com/apk/res-auto", Name: "name", Value: "com.example.Other"},
+			{Namespace: AndroidNamespace, Name: "name", Value: "android.permission.INTERNET"},
+			{Name: "package", Value: "com.example.app"},
+			{Namespace: "http://schemas.android.com/tools", Name: "node", Value: "remove"},
+		},
+	}
+
+	tests := []struct {
+		name   string
+		want   string
+		wantOk bool
+	}{
+		{name: "name", want: "android.permission.INTERNET", wantOk: true},
+		{name: "package", want: "com.example.app", wantOk: true},
+		{name: "node", want: "remove", wantOk: true},
+		{name: "missing", want: "", wantOk: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := element.Attribute(tt.name)
+			if got != tt.want || ok != tt.wantOk {
+				t.Errorf("Attribute(%q) = %q, %v, want %q, %v", tt.name, got, ok, tt.want, tt.wantOk)
+			}
+		})
+	}
+}
+
+func TestParseManifest(t *testing.T) {
+	want := Model{
+		PackageName:      "com.example.app",
+		VersionCode:      "42",
+		VersionName:      "1.4.2",
+		MinSDKVersion:    "21",
+		TargetSDKVersion: "34",
+		Permissions:      []string{"android.permission.INTERNET", "android.permission.CAMERA"},
+		ABIs:             []string{},
+	}
+
+	for _, name := range []string{"AndroidManifest.xml", "AndroidManifest-utf8.xml"} {
+		t.Run(name, func(t *testing.T) {
+			got, err := ParseManifest(readTestManifest(t, name))
+			if err != nil {
+				t.Fatalf("ParseManifest() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("ParseManifest() = %+v, want %+v", got, want)
+			}
+		})
+	}
+}
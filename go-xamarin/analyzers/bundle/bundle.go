@@ -0,0 +1,190 @@
+package bundle
+
+import (
+	"archive/zip"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"github.com/bitrise-io/go-utils/fileutil"
+	"github.com/bitrise-io/go-utils/log"
+	"github.com/bitrise-io/go-utils/pathutil"
+	"github.com/bitrise-steplib/steps-xamarin-archive/go-xamarin/plist"
+)
+
+// deviceFamilies are the names of the UIDeviceFamily values.
+var deviceFamilies = map[int64]string{
+	1: "iphone",
+	2: "ipad",
+	3: "tv",
+	4: "watch",
+	6: "mac",
+	7: "vision",
+}
+
+// Model is the metadata of an Apple application bundle, read from its Info.plist.
+type Model struct {
+	BundleID         string   `json:"bundle_id"`
+	Version          string   `json:"version"`      // CFBundleShortVersionString
+	BuildNumber      string   `json:"build_number"` // CFBundleVersion
+	MinimumOSVersion string   `json:"minimum_os_version"`
+	DeviceFamilies   []string `json:"device_families"`
+}
+
+// ParseInfoPlist returns the metadata defined by the application's Info.plist, in XML or binary format.
+func ParseInfoPlist(content []byte) (Model, error) {
+	root, err := plist.Parse(content)
+	if err != nil {
+		return Model{}, err
+	}
+
+	dict, ok := root.(*plist.Dict)
+	if !ok {
+		return Model{}, fmt.Errorf("root value is not a dict")
+	}
+
+	model := Model{DeviceFamilies: []string{}}
+	model.BundleID, _ = dict.GetString("CFBundleIdentifier")
+	model.Version, _ = dict.GetString("CFBundleShortVersionString")
+	model.BuildNumber, _ = dict.GetString("CFBundleVersion")
+
+	if version, ok := dict.GetString("MinimumOSVersion"); ok {
+		model.MinimumOSVersion = version
+	} else if version, ok := dict.GetString("LSMinimumSystemVersion"); ok {
+		// macOS applications
+		model.MinimumOSVersion = version
+		model.DeviceFamilies = append(model.DeviceFamilies, deviceFamilies[6])
+	}
+
+	if families, ok := dict.Get("UIDeviceFamily"); ok {
+		model.DeviceFamilies = []string{}
+
+		values, ok := families.([]plist.Value)
+		if !ok {
+			// a single family may be defined without an array
+			values = []plist.Value{families}
+		}
+		for _, value := range values {
+			model.DeviceFamilies = append(model.DeviceFamilies, deviceFamilyName(value))
+		}
+	}
+
+	return model, nil
+}
+
+func deviceFamilyName(value plist.Value) string {
+	var family int64
+	switch v := value.(type) {
+	case int64:
+		family = v
+	case string:
+		if _, err := fmt.Sscanf(v, "%d", &family); err != nil {
+			return v
+		}
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+
+	if name, ok := deviceFamilies[family]; ok {
+		return name
+	}
+	return fmt.Sprintf("%d", family)
+}
+
+// InspectIPA reads the metadata of the application in the IPA at the given path: Payload/*.app/Info.plist.
+func InspectIPA(pth string) (Model, error) {
+	reader, err := zip.OpenReader(pth)
+	if err != nil {
+		return Model{}, fmt.Errorf("failed to open ipa (%s), error: %s", pth, err)
+	}
+	defer func() {
+		if err := reader.Close(); err != nil {
+			log.Warnf("Failed to close ipa (%s), error: %s", pth, err)
+		}
+	}()
+
+	for _, file := range reader.File {
+		split := strings.Split(file.Name, "/")
+		if len(split) != 3 || split[0] != "Payload" || !strings.HasSuffix(split[1], ".app") || split[2] != "Info.plist" {
+			continue
+		}
+
+		content, err := readZipFile(file)
+		if err != nil {
+			return Model{}, fmt.Errorf("failed to read %s of ipa (%s), error: %s", file.Name, pth, err)
+		}
+
+		model, err := ParseInfoPlist(content)
+		if err != nil {
+			return Model{}, fmt.Errorf("failed to parse %s of ipa (%s), error: %s", file.Name, pth, err)
+		}
+		return model, nil
+	}
+
+	return Model{}, fmt.Errorf("no Payload/*.app/Info.plist found in ipa (%s)", pth)
+}
+
+// InspectXCArchive reads the metadata of the application in the xcarchive at the given path:
+// the Info.plist of the application in the Products dir, or the ApplicationProperties of the archive's Info.plist
+// if the application has no Info.plist.
+func InspectXCArchive(pth string) (Model, error) {
+	archiveInfo, err := plist.ReadFile(filepath.Join(pth, "Info.plist"))
+	if err != nil {
+		return Model{}, err
+	}
+
+	archiveDict, ok := archiveInfo.(*plist.Dict)
+	if !ok {
+		return Model{}, fmt.Errorf("invalid Info.plist of xcarchive (%s): root value is not a dict", pth)
+	}
+	properties, ok := archiveDict.GetDict("ApplicationProperties")
+	if !ok {
+		return Model{}, fmt.Errorf("no ApplicationProperties found in the Info.plist of xcarchive (%s)", pth)
+	}
+
+	if applicationPth, ok := properties.GetString("ApplicationPath"); ok {
+		appPth := filepath.Join(pth, "Products", applicationPth)
+		for _, infoPlistPth := range []string{
+			filepath.Join(appPth, "Info.plist"),
+			filepath.Join(appPth, "Contents", "Info.plist"), // macOS applications
+		} {
+			if exist, err := pathutil.IsPathExists(infoPlistPth); err != nil {
+				return Model{}, err
+			} else if !exist {
+				continue
+			}
+
+			content, err := fileutil.ReadBytesFromFile(infoPlistPth)
+			if err != nil {
+				return Model{}, fmt.Errorf("failed to read Info.plist (%s), error: %s", infoPlistPth, err)
+			}
+
+			model, err := ParseInfoPlist(content)
+			if err != nil {
+				return Model{}, fmt.Errorf("failed to parse Info.plist (%s), error: %s", infoPlistPth, err)
+			}
+			return model, nil
+		}
+	}
+
+	model := Model{DeviceFamilies: []string{}}
+	model.BundleID, _ = properties.GetString("CFBundleIdentifier")
+	model.Version, _ = properties.GetString("CFBundleShortVersionString")
+	model.BuildNumber, _ = properties.GetString("CFBundleVersion")
+	return model, nil
+}
+
+func readZipFile(file *zip.File) ([]byte, error) {
+	reader, err := file.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := reader.Close(); err != nil {
+			log.Warnf("Failed to close %s, error: %s", file.Name, err)
+		}
+	}()
+
+	return ioutil.ReadAll(reader)
+}
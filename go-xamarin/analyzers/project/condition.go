@@ -0,0 +1,520 @@
+package project
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/bitrise-io/go-utils/pathutil"
+	"github.com/bitrise-steplib/steps-xamarin-archive/go-xamarin/utility"
+)
+
+// Condition is a parsed MSBuild condition expression.
+//
+// Supported syntax: quoted strings ('...'), unquoted values (like $(Property) or true),
+// comparison operators (==, !=, <, >, <=, >=), logical operators (And, Or, !), parentheses
+// and the Exists() and HasTrailingSlash() functions. Property references may contain property functions,
+// see Evaluator.ExpandValue for the supported ones.
+type Condition struct {
+	raw  string
+	root conditionNode
+}
+
+// ConditionContext provides the property and path resolution for evaluating a condition.
+type ConditionContext interface {
+	ExpandValue(value string) (string, error)
+	Property(name string) string
+}
+
+type conditionNode interface{}
+
+type conditionValue struct {
+	value string
+}
+
+type conditionFunction struct {
+	name string
+	args []conditionNode
+}
+
+type conditionNot struct {
+	operand conditionNode
+}
+
+type conditionBinary struct {
+	operator    string
+	left, right conditionNode
+}
+
+// ParseCondition parses the given MSBuild condition expression.
+func ParseCondition(condition string) (Condition, error) {
+	tokens, err := tokenizeCondition(condition)
+	if err != nil {
+		return Condition{}, err
+	}
+
+	parser := conditionParser{tokens: tokens}
+	if len(tokens) == 0 {
+		return Condition{raw: condition}, nil
+	}
+
+	root, err := parser.parseOr()
+	if err != nil {
+		return Condition{}, fmt.Errorf("invalid condition (%s), error: %s", condition, err)
+	}
+	if !parser.done() {
+		return Condition{}, fmt.Errorf("invalid condition (%s), error: unexpected %s", condition, parser.peek().text)
+	}
+
+	return Condition{raw: condition, root: root}, nil
+}
+
+// String ...
+func (condition Condition) String() string {
+	return condition.raw
+}
+
+// Evaluate evaluates the condition, an empty condition is true.
+func (condition Condition) Evaluate(context ConditionContext) (bool, error) {
+	if condition.root == nil {
+		return true, nil
+	}
+	return evaluateBool(condition.root, context)
+}
+
+// Equalities returns the property values the condition requires through equality comparisons
+// of property references and literals, like '$(Configuration)|$(Platform)' == 'Debug|iPhone'.
+// Comparisons under negation or in Or branches are ignored. Property names are lower cased.
+func (condition Condition) Equalities() map[string]string {
+	equalities := map[string]string{}
+	collectEqualities(condition.root, equalities)
+	return equalities
+}
+
+func collectEqualities(node conditionNode, equalities map[string]string) {
+	binary, ok := node.(conditionBinary)
+	if !ok {
+		return
+	}
+
+	switch binary.operator {
+	case "and":
+		collectEqualities(binary.left, equalities)
+		collectEqualities(binary.right, equalities)
+	case "==":
+		left, leftOk := binary.left.(conditionValue)
+		right, rightOk := binary.right.(conditionValue)
+		if !leftOk || !rightOk {
+			return
+		}
+
+		if values, ok := matchPropertyTemplate(left.value, right.value); ok {
+			for name, value := range values {
+				equalities[name] = value
+			}
+		} else if values, ok := matchPropertyTemplate(right.value, left.value); ok {
+			for name, value := range values {
+				equalities[name] = value
+			}
+		}
+	}
+}
+
+// matchPropertyTemplate matches a value against a template of property references and literals,
+// like $(Configuration)|$(Platform) against Debug|iPhone.
+func matchPropertyTemplate(template, value string) (map[string]string, bool) {
+	references := propertyReferenceRegexp.FindAllStringSubmatchIndex(template, -1)
+	if len(references) == 0 || propertyReferenceRegexp.MatchString(value) {
+		return nil, false
+	}
+
+	pattern := "^"
+	names := []string{}
+	last := 0
+	for _, reference := range references {
+		pattern += regexp.QuoteMeta(template[last:reference[0]]) + "(.*?)"
+		names = append(names, strings.ToLower(template[reference[2]:reference[3]]))
+		last = reference[1]
+	}
+	pattern += regexp.QuoteMeta(template[last:]) + "$"
+
+	matches := regexp.MustCompile("(?i)" + pattern).FindStringSubmatch(value)
+	if len(matches) != len(names)+1 {
+		return nil, false
+	}
+
+	values := map[string]string{}
+	for i, name := range names {
+		values[name] = matches[i+1]
+	}
+	return values, true
+}
+
+func evaluateBool(node conditionNode, context ConditionContext) (bool, error) {
+	switch n := node.(type) {
+	case conditionBinary:
+		switch n.operator {
+		case "and":
+			left, err := evaluateBool(n.left, context)
+			if err != nil || !left {
+				return false, err
+			}
+			return evaluateBool(n.right, context)
+		case "or":
+			left, err := evaluateBool(n.left, context)
+			if err != nil || left {
+				return left, err
+			}
+			return evaluateBool(n.right, context)
+		default:
+			return evaluateComparison(n, context)
+		}
+	case conditionNot:
+		value, err := evaluateBool(n.operand, context)
+		return !value, err
+	case conditionFunction:
+		return evaluateFunction(n, context)
+	case conditionValue:
+		value, err := context.ExpandValue(n.value)
+		if err != nil {
+			return false, err
+		}
+		return parseConditionBool(value)
+	default:
+		return false, fmt.Errorf("unknown condition node: %v", node)
+	}
+}
+
+func evaluateString(node conditionNode, context ConditionContext) (string, error) {
+	switch n := node.(type) {
+	case conditionValue:
+		return context.ExpandValue(n.value)
+	case conditionFunction, conditionNot, conditionBinary:
+		value, err := evaluateBool(n, context)
+		return strconv.FormatBool(value), err
+	default:
+		return "", fmt.Errorf("unknown condition node: %v", node)
+	}
+}
+
+func evaluateComparison(comparison conditionBinary, context ConditionContext) (bool, error) {
+	left, err := evaluateString(comparison.left, context)
+	if err != nil {
+		return false, err
+	}
+	right, err := evaluateString(comparison.right, context)
+	if err != nil {
+		return false, err
+	}
+
+	leftNumber, leftErr := strconv.ParseFloat(left, 64)
+	rightNumber, rightErr := strconv.ParseFloat(right, 64)
+	isNumeric := leftErr == nil && rightErr == nil
+
+	switch comparison.operator {
+	case "==":
+		if isNumeric {
+			return leftNumber == rightNumber, nil
+		}
+		return strings.EqualFold(left, right), nil
+	case "!=":
+		if isNumeric {
+			return leftNumber != rightNumber, nil
+		}
+		return !strings.EqualFold(left, right), nil
+	}
+
+	if !isNumeric {
+		return false, fmt.Errorf("can not compare non numeric values: '%s' %s '%s'", left, comparison.operator, right)
+	}
+
+	switch comparison.operator {
+	case "<":
+		return leftNumber < rightNumber, nil
+	case ">":
+		return leftNumber > rightNumber, nil
+	case "<=":
+		return leftNumber <= rightNumber, nil
+	case ">=":
+		return leftNumber >= rightNumber, nil
+	default:
+		return false, fmt.Errorf("unknown operator: %s", comparison.operator)
+	}
+}
+
+func evaluateFunction(function conditionFunction, context ConditionContext) (bool, error) {
+	if len(function.args) != 1 {
+		return false, fmt.Errorf("%s() expects 1 argument, got: %d", function.name, len(function.args))
+	}
+
+	arg, err := evaluateString(function.args[0], context)
+	if err != nil {
+		return false, err
+	}
+
+	switch strings.ToLower(function.name) {
+	case "exists":
+		pth := strings.TrimSpace(utility.FixWindowsPath(arg))
+		if pth == "" {
+			return false, nil
+		}
+		if !filepath.IsAbs(pth) {
+			pth = filepath.Join(context.Property("MSBuildThisFileDirectory"), pth)
+		}
+		return pathutil.IsPathExists(pth)
+	case "hastrailingslash":
+		return strings.HasSuffix(arg, "/") || strings.HasSuffix(arg, `\`), nil
+	default:
+		return false, fmt.Errorf("unsupported function: %s", function.name)
+	}
+}
+
+func parseConditionBool(value string) (bool, error) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "true", "on", "yes", "!false", "!off", "!no":
+		return true, nil
+	case "false", "off", "no", "!true", "!on", "!yes":
+		return false, nil
+	default:
+		return false, fmt.Errorf("expected boolean value, got: '%s'", value)
+	}
+}
+
+//
+// Tokenizer
+
+type conditionTokenKind int
+
+const (
+	tokenString conditionTokenKind = iota
+	tokenValue
+	tokenOperator
+	tokenLeftParen
+	tokenRightParen
+	tokenComma
+)
+
+type conditionToken struct {
+	kind conditionTokenKind
+	text string
+}
+
+func tokenizeCondition(condition string) ([]conditionToken, error) {
+	var tokens []conditionToken
+
+	for i := 0; i < len(condition); {
+		c := condition[i]
+
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '\'':
+			// a quote inside a property reference, like '$([MSBuild]::GetTargetPlatformIdentifier('$(TargetFramework)'))',
+			// does not terminate the string
+			end, err := scanQuoted(condition, i)
+			if err != nil {
+				return nil, fmt.Errorf("%s in condition: %s", err, condition)
+			}
+			tokens = append(tokens, conditionToken{kind: tokenString, text: condition[i+1 : end-1]})
+			i = end
+		case c == '(':
+			tokens = append(tokens, conditionToken{kind: tokenLeftParen, text: "("})
+			i++
+		case c == ')':
+			tokens = append(tokens, conditionToken{kind: tokenRightParen, text: ")"})
+			i++
+		case c == ',':
+			tokens = append(tokens, conditionToken{kind: tokenComma, text: ","})
+			i++
+		case strings.HasPrefix(condition[i:], "==") || strings.HasPrefix(condition[i:], "!=") ||
+			strings.HasPrefix(condition[i:], "<=") || strings.HasPrefix(condition[i:], ">="):
+			tokens = append(tokens, conditionToken{kind: tokenOperator, text: condition[i : i+2]})
+			i += 2
+		case c == '<' || c == '>' || c == '!':
+			tokens = append(tokens, conditionToken{kind: tokenOperator, text: string(c)})
+			i++
+		case c == '$' || c == '@' || c == '%':
+			// unquoted property or item reference: $(Name) or $([MSBuild]::Function('$(Name)'))
+			end, err := scanReference(condition, i)
+			if err != nil {
+				return nil, fmt.Errorf("%s in condition: %s", err, condition)
+			}
+			tokens = append(tokens, conditionToken{kind: tokenValue, text: condition[i:end]})
+			i = end
+		default:
+			start := i
+			for i < len(condition) && strings.IndexByte(" \t\r\n'(),=!<>", condition[i]) < 0 {
+				i++
+			}
+			if start == i {
+				return nil, fmt.Errorf("unexpected character (%c) in condition: %s", c, condition)
+			}
+
+			word := condition[start:i]
+			switch strings.ToLower(word) {
+			case "and", "or":
+				tokens = append(tokens, conditionToken{kind: tokenOperator, text: strings.ToLower(word)})
+			default:
+				tokens = append(tokens, conditionToken{kind: tokenValue, text: word})
+			}
+		}
+	}
+
+	return tokens, nil
+}
+
+//
+// Parser
+
+type conditionParser struct {
+	tokens []conditionToken
+	pos    int
+}
+
+func (parser *conditionParser) done() bool {
+	return parser.pos >= len(parser.tokens)
+}
+
+func (parser *conditionParser) peek() conditionToken {
+	if parser.done() {
+		return conditionToken{kind: tokenOperator, text: "end of condition"}
+	}
+	return parser.tokens[parser.pos]
+}
+
+func (parser *conditionParser) next() conditionToken {
+	token := parser.peek()
+	parser.pos++
+	return token
+}
+
+func (parser *conditionParser) isOperator(operators ...string) bool {
+	token := parser.peek()
+	if parser.done() || token.kind != tokenOperator {
+		return false
+	}
+	for _, operator := range operators {
+		if token.text == operator {
+			return true
+		}
+	}
+	return false
+}
+
+// or := and { 'Or' and }
+func (parser *conditionParser) parseOr() (conditionNode, error) {
+	left, err := parser.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+
+	for parser.isOperator("or") {
+		parser.next()
+		right, err := parser.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = conditionBinary{operator: "or", left: left, right: right}
+	}
+	return left, nil
+}
+
+// and := comparison { 'And' comparison }
+func (parser *conditionParser) parseAnd() (conditionNode, error) {
+	left, err := parser.parseComparison()
+	if err != nil {
+		return nil, err
+	}
+
+	for parser.isOperator("and") {
+		parser.next()
+		right, err := parser.parseComparison()
+		if err != nil {
+			return nil, err
+		}
+		left = conditionBinary{operator: "and", left: left, right: right}
+	}
+	return left, nil
+}
+
+// comparison := unary [ operator unary ]
+func (parser *conditionParser) parseComparison() (conditionNode, error) {
+	left, err := parser.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+
+	if parser.isOperator("==", "!=", "<", ">", "<=", ">=") {
+		operator := parser.next().text
+		right, err := parser.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return conditionBinary{operator: operator, left: left, right: right}, nil
+	}
+	return left, nil
+}
+
+// unary := '!' unary | '(' or ')' | function | value
+func (parser *conditionParser) parseUnary() (conditionNode, error) {
+	token := parser.next()
+
+	switch token.kind {
+	case tokenOperator:
+		if token.text == "!" {
+			operand, err := parser.parseUnary()
+			if err != nil {
+				return nil, err
+			}
+			return conditionNot{operand: operand}, nil
+		}
+		return nil, fmt.Errorf("unexpected %s", token.text)
+	case tokenLeftParen:
+		node, err := parser.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if closing := parser.next(); closing.kind != tokenRightParen {
+			return nil, fmt.Errorf("expected ), got: %s", closing.text)
+		}
+		return node, nil
+	case tokenString:
+		return conditionValue{value: token.text}, nil
+	case tokenValue:
+		if !parser.done() && parser.peek().kind == tokenLeftParen {
+			return parser.parseFunction(token.text)
+		}
+		return conditionValue{value: token.text}, nil
+	default:
+		return nil, fmt.Errorf("unexpected %s", token.text)
+	}
+}
+
+// function := name '(' [ or { ',' or } ] ')'
+func (parser *conditionParser) parseFunction(name string) (conditionNode, error) {
+	parser.next() // (
+
+	function := conditionFunction{name: name}
+	if !parser.done() && parser.peek().kind == tokenRightParen {
+		parser.next()
+		return function, nil
+	}
+
+	for {
+		arg, err := parser.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		function.args = append(function.args, arg)
+
+		token := parser.next()
+		if token.kind == tokenRightParen {
+			return function, nil
+		}
+		if token.kind != tokenComma {
+			return nil, fmt.Errorf("expected , or ) in %s(), got: %s", name, token.text)
+		}
+	}
+}
@@ -0,0 +1,264 @@
+package project
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseCondition(t *testing.T) {
+	tests := []struct {
+		name      string
+		condition string
+		wantErr   bool
+	}{
+		{name: "empty", condition: ""},
+		{name: "configuration platform", condition: ` '$(Configuration)|$(Platform)' == 'Debug|iPhone' `},
+		{name: "logical operators", condition: `'$(A)' == '' And ('$(B)' != 'x' Or !Exists('$(C)'))`},
+		{name: "quoted property function", condition: `'$([MSBuild]::GetTargetPlatformIdentifier('$(TargetFramework)'))' == 'ios'`},
+		{name: "unquoted property function", condition: `$([MSBuild]::GetTargetPlatformIdentifier('$(TargetFramework)')) == 'android'`},
+		{name: "string method", condition: `$(TargetFramework.Contains('-windows')) != true`},
+		{name: "nested property functions", condition: `$([MSBuild]::VersionGreaterThanOrEquals($([MSBuild]::GetTargetPlatformVersion('$(TargetFramework)')), '17.0'))`},
+		{name: "unterminated string", condition: `'$(Configuration) == 'Debug`, wantErr: true},
+		{name: "unterminated reference", condition: `'$(Configuration' == 'Debug'`, wantErr: true},
+		{name: "unbalanced property function", condition: `$([MSBuild]::GetTargetPlatformIdentifier('$(TargetFramework)') == 'ios'`, wantErr: true},
+		{name: "missing operand", condition: `'$(Configuration)' ==`, wantErr: true},
+		{name: "missing closing paren", condition: `('$(Configuration)' == 'Debug'`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseCondition(tt.condition)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseCondition(%q) error = %v, wantErr %v", tt.condition, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestConditionEvaluate(t *testing.T) {
+	tests := []struct {
+		name       string
+		condition  string
+		properties map[string]string
+		want       bool
+		wantErr    bool
+	}{
+		{
+			name:      "empty condition is true",
+			condition: "",
+			want:      true,
+		},
+		{
+			name:       "configuration platform",
+			condition:  ` '$(Configuration)|$(Platform)' == 'Release|iPhone' `,
+			properties: map[string]string{"Configuration": "Release", "Platform": "iPhone"},
+			want:       true,
+		},
+		{
+			name:       "comparison is case insensitive",
+			condition:  `'$(Configuration)' == 'release'`,
+			properties: map[string]string{"Configuration": "Release"},
+			want:       true,
+		},
+		{
+			name:      "undefined property is empty",
+			condition: `'$(Configuration)' == ''`,
+			want:      true,
+		},
+		{
+			name:       "and or not",
+			condition:  `'$(A)' == 'a' And !('$(B)' == 'b' Or '$(C)' == 'c')`,
+			properties: map[string]string{"A": "a", "C": "x"},
+			want:       true,
+		},
+		{
+			name:       "numeric comparison",
+			condition:  `'$(Version)' >= '4.5'`,
+			properties: map[string]string{"Version": "10.0"},
+			want:       true,
+		},
+		{
+			name:      "boolean value",
+			condition: `'$(Flag)' != 'true' and !false`,
+			want:      true,
+		},
+		{
+			name:      "non existing path",
+			condition: `Exists('/non/existing/path')`,
+			want:      false,
+		},
+		{
+			name:       "has trailing slash",
+			condition:  `HasTrailingSlash('$(OutputPath)')`,
+			properties: map[string]string{"OutputPath": `bin\Release\`},
+			want:       true,
+		},
+		{
+			name:       "maui platform identifier, quoted",
+			condition:  `'$([MSBuild]::GetTargetPlatformIdentifier('$(TargetFramework)'))' == 'ios'`,
+			properties: map[string]string{"TargetFramework": "net8.0-ios"},
+			want:       true,
+		},
+		{
+			name:       "maui platform identifier, other platform",
+			condition:  `'$([MSBuild]::GetTargetPlatformIdentifier('$(TargetFramework)'))' == 'ios'`,
+			properties: map[string]string{"TargetFramework": "net8.0-android"},
+			want:       false,
+		},
+		{
+			name:       "maui platform identifier, unquoted",
+			condition:  `$([MSBuild]::GetTargetPlatformIdentifier('$(TargetFramework)')) == 'android'`,
+			properties: map[string]string{"TargetFramework": "net8.0-android34.0"},
+			want:       true,
+		},
+		{
+			name:       "maui windows exclusion",
+			condition:  `$(TargetFramework.Contains('-windows')) != true`,
+			properties: map[string]string{"TargetFramework": "net8.0-maccatalyst"},
+			want:       true,
+		},
+		{
+			name:       "platform version comparison",
+			condition:  `$([MSBuild]::VersionGreaterThanOrEquals($([MSBuild]::GetTargetPlatformVersion('$(TargetFramework)')), '17.0'))`,
+			properties: map[string]string{"TargetFramework": "net8.0-ios17.2"},
+			want:       true,
+		},
+		{
+			name:       "target framework identifier",
+			condition:  `'$([MSBuild]::GetTargetFrameworkIdentifier('$(TargetFramework)'))' == '.NETCoreApp'`,
+			properties: map[string]string{"TargetFramework": "net8.0-ios"},
+			want:       true,
+		},
+		{
+			name:       "unsupported property function",
+			condition:  `'$([System.DateTime]::Now.Year)' == '2020'`,
+			properties: map[string]string{},
+			wantErr:    true,
+		},
+		{
+			name:       "non numeric comparison",
+			condition:  `'$(Configuration)' > 'Debug'`,
+			properties: map[string]string{"Configuration": "Release"},
+			wantErr:    true,
+		},
+		{
+			name:      "non boolean value",
+			condition: `'yes please'`,
+			wantErr:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			condition, err := ParseCondition(tt.condition)
+			if err != nil {
+				t.Fatalf("ParseCondition(%q) error = %v", tt.condition, err)
+			}
+
+			got, err := condition.Evaluate(NewEvaluator(tt.properties))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Evaluate(%q) error = %v, wantErr %v", tt.condition, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Evaluate(%q) = %v, want %v", tt.condition, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConditionEqualities(t *testing.T) {
+	tests := []struct {
+		name      string
+		condition string
+		want      map[string]string
+	}{
+		{
+			name:      "configuration and platform",
+			condition: ` '$(Configuration)|$(Platform)' == 'Debug|iPhoneSimulator' `,
+			want:      map[string]string{"configuration": "Debug", "platform": "iPhoneSimulator"},
+		},
+		{
+			name:      "reversed operands",
+			condition: `'Release' == '$(Configuration)'`,
+			want:      map[string]string{"configuration": "Release"},
+		},
+		{
+			name:      "and branches",
+			condition: `'$(Configuration)' == 'Release' And '$(Platform)' == 'AnyCPU'`,
+			want:      map[string]string{"configuration": "Release", "platform": "AnyCPU"},
+		},
+		{
+			name:      "or branches are ignored",
+			condition: `'$(Configuration)' == 'Release' Or '$(Platform)' == 'AnyCPU'`,
+			want:      map[string]string{},
+		},
+		{
+			name:      "inequality is ignored",
+			condition: `'$(Configuration)' != 'Release'`,
+			want:      map[string]string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			condition, err := ParseCondition(tt.condition)
+			if err != nil {
+				t.Fatalf("ParseCondition(%q) error = %v", tt.condition, err)
+			}
+
+			if got := condition.Equalities(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Equalities() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetResolvedConfigurationPlatform(t *testing.T) {
+	tests := []struct {
+		name              string
+		condition         string
+		wantConfiguration string
+		wantPlatform      string
+	}{
+		{
+			name:              "configuration and platform",
+			condition:         ` '$(Configuration)|$(Platform)' == 'Debug|iPhone' `,
+			wantConfiguration: "Debug",
+			wantPlatform:      "iPhone",
+		},
+		{
+			name:              "configuration only",
+			condition:         ` '$(Configuration)' == 'Release' `,
+			wantConfiguration: "Release",
+			wantPlatform:      ` '$(Configuration)' == 'Release' `,
+		},
+		{
+			name:              "unparsable condition",
+			condition:         `'$(Configuration`,
+			wantConfiguration: `'$(Configuration`,
+			wantPlatform:      `'$(Configuration`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			propertyGroup := PropertyGroup{Condition: tt.condition}
+
+			configuration, err := GetResolvedConfiguration(propertyGroup)
+			if err != nil {
+				t.Fatalf("GetResolvedConfiguration() error = %v", err)
+			}
+			if configuration != tt.wantConfiguration {
+				t.Errorf("GetResolvedConfiguration() = %q, want %q", configuration, tt.wantConfiguration)
+			}
+
+			platform, err := GetResolvedPlatform(propertyGroup)
+			if err != nil {
+				t.Fatalf("GetResolvedPlatform() error = %v", err)
+			}
+			if platform != tt.wantPlatform {
+				t.Errorf("GetResolvedPlatform() = %q, want %q", platform, tt.wantPlatform)
+			}
+		})
+	}
+}
@@ -0,0 +1,517 @@
+package project
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/bitrise-io/go-utils/fileutil"
+	"github.com/bitrise-io/go-utils/log"
+	"github.com/bitrise-steplib/steps-xamarin-archive/go-xamarin/utility"
+)
+
+var (
+	propertyReferenceRegexp = regexp.MustCompile(`\$\(([A-Za-z_][A-Za-z0-9_\-]*)\)`)
+	itemReferenceRegexp     = regexp.MustCompile(`@\(([A-Za-z_][A-Za-z0-9_\-]*)\)`)
+)
+
+// Item is an evaluated MSBuild item, like <Compile Include="Main.cs" />.
+type Item struct {
+	Type     string
+	Include  string
+	Metadata map[string]string
+}
+
+// element is a generic node of an MSBuild project file.
+type element struct {
+	Name     string
+	Attrs    map[string]string
+	Text     string
+	Children []*element
+}
+
+// Attr returns the value of the given attribute of the element.
+func (e element) Attr(name string) string {
+	return e.Attrs[name]
+}
+
+func parseElements(content []byte) (*element, error) {
+	decoder := xml.NewDecoder(strings.NewReader(string(content)))
+
+	var root *element
+	var stack []*element
+
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			if err == io.EOF {
+				break
+			}
+			return nil, err
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			e := &element{Name: t.Name.Local, Attrs: map[string]string{}}
+			for _, attr := range t.Attr {
+				e.Attrs[attr.Name.Local] = attr.Value
+			}
+
+			if len(stack) > 0 {
+				parent := stack[len(stack)-1]
+				parent.Children = append(parent.Children, e)
+			} else if root == nil {
+				root = e
+			}
+			stack = append(stack, e)
+		case xml.EndElement:
+			if len(stack) > 0 {
+				stack = stack[:len(stack)-1]
+			}
+		case xml.CharData:
+			if len(stack) > 0 {
+				stack[len(stack)-1].Text += string(t)
+			}
+		}
+	}
+
+	if root == nil {
+		return nil, fmt.Errorf("no root element found")
+	}
+	return root, nil
+}
+
+// Evaluator evaluates the properties and items of an MSBuild project file and the files it imports:
+// property groups, item groups and imports are processed in order,
+// and $(Name) property references are expanded with the values known at that point.
+// Unlike MSBuild, which evaluates every property before the items, it evaluates both in a single pass,
+// so an item only sees the properties defined before it. Wildcards of item includes (like **/*.cs) are not expanded.
+type Evaluator struct {
+	properties       map[string]string // lower cased property name - value
+	globalProperties map[string]bool   // lower cased property name
+
+	items []Item
+
+	importedPths map[string]bool
+	conditions   map[string]Condition
+
+	configurationPlatforms map[string]bool
+
+	sdkStyle bool
+}
+
+// NewEvaluator creates an evaluator with the given global properties,
+// global properties can not be overridden by the evaluated files (like /p: options of msbuild).
+func NewEvaluator(globalProperties map[string]string) *Evaluator {
+	evaluator := &Evaluator{
+		properties:             map[string]string{},
+		globalProperties:       map[string]bool{},
+		importedPths:           map[string]bool{},
+		conditions:             map[string]Condition{},
+		configurationPlatforms: map[string]bool{},
+	}
+
+	for name, value := range globalProperties {
+		evaluator.properties[strings.ToLower(name)] = value
+		evaluator.globalProperties[strings.ToLower(name)] = true
+	}
+
+	return evaluator
+}
+
+// Property returns the value of the given property, property names are case insensitive.
+func (evaluator *Evaluator) Property(name string) string {
+	return evaluator.properties[strings.ToLower(name)]
+}
+
+// SetProperty sets the given property, unless it is a global property.
+func (evaluator *Evaluator) SetProperty(name, value string) {
+	key := strings.ToLower(name)
+	if evaluator.globalProperties[key] {
+		return
+	}
+	evaluator.properties[key] = value
+}
+
+// Expand replaces the $(Name) property references of the given value with the property values
+// and the @(Type) item references with the ; separated item includes, undefined properties expand to empty string.
+// Property functions are expanded by ExpandValue, the unsupported ones are kept as is.
+func (evaluator *Evaluator) Expand(value string) string {
+	expanded, err := evaluator.ExpandValue(value)
+	if err != nil {
+		log.Debugf("%s", err)
+	}
+	return expanded
+}
+
+// Items returns the evaluated items of the given type, item types are case insensitive.
+func (evaluator *Evaluator) Items(itemType string) []Item {
+	items := []Item{}
+	for _, item := range evaluator.items {
+		if strings.EqualFold(item.Type, itemType) {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// EvaluateCondition evaluates the given MSBuild condition, an empty condition is true.
+// A condition which can not be parsed or evaluated is false, a warning is logged about it once.
+func (evaluator *Evaluator) EvaluateCondition(condition string) bool {
+	parsed, err := evaluator.parseCondition(condition)
+	if err != nil {
+		warnUnsupportedCondition(condition, err)
+		return false
+	}
+
+	result, err := parsed.Evaluate(evaluator)
+	if err != nil {
+		warnUnsupportedCondition(condition, fmt.Errorf("failed to evaluate condition (%s), error: %s", condition, err))
+		return false
+	}
+	return result
+}
+
+var (
+	warnedConditions      = map[string]bool{}
+	warnedConditionsMutex sync.Mutex
+)
+
+// warnUnsupportedCondition logs a warning about the condition which is evaluated as false,
+// the project analysis evaluates the same files many times, the warning is logged only once per condition.
+func warnUnsupportedCondition(condition string, err error) {
+	warnedConditionsMutex.Lock()
+	defer warnedConditionsMutex.Unlock()
+
+	if warnedConditions[condition] {
+		return
+	}
+	warnedConditions[condition] = true
+
+	log.Warnf("Unsupported MSBuild condition is evaluated as false: %s", err)
+}
+
+func (evaluator *Evaluator) parseCondition(condition string) (Condition, error) {
+	if parsed, ok := evaluator.conditions[condition]; ok {
+		return parsed, nil
+	}
+
+	parsed, err := ParseCondition(condition)
+	if err != nil {
+		return Condition{}, err
+	}
+	evaluator.conditions[condition] = parsed
+	return parsed, nil
+}
+
+// ConfigurationPlatforms returns the Configuration|Platform pairs the evaluated property groups are conditioned on.
+func (evaluator *Evaluator) ConfigurationPlatforms() []string {
+	configurationPlatforms := []string{}
+	for configurationPlatform := range evaluator.configurationPlatforms {
+		configurationPlatforms = append(configurationPlatforms, configurationPlatform)
+	}
+	sort.Strings(configurationPlatforms)
+	return configurationPlatforms
+}
+
+// ImportedPths returns the files imported by the evaluated project, directly or transitively, like Directory.Build.props
+// or the .projitems of a shared project.
+func (evaluator *Evaluator) ImportedPths() []string {
+	projectPth := evaluator.Property("MSBuildProjectFullPath")
+
+	importedPths := []string{}
+	for pth := range evaluator.importedPths {
+		if pth != projectPth {
+			importedPths = append(importedPths, pth)
+		}
+	}
+	sort.Strings(importedPths)
+	return importedPths
+}
+
+// EvaluateProject evaluates the project file at the given path.
+// The solution dir is optional, it is used to define the SolutionDir property.
+func (evaluator *Evaluator) EvaluateProject(pth, solutionDir string) error {
+	absPth, err := filepath.Abs(pth)
+	if err != nil {
+		return fmt.Errorf("failed to expand path (%s), error: %s", pth, err)
+	}
+
+	projectDir := filepath.Dir(absPth)
+	projectFile := filepath.Base(absPth)
+
+	evaluator.SetProperty("MSBuildProjectDirectory", projectDir)
+	evaluator.SetProperty("MSBuildProjectFullPath", absPth)
+	evaluator.SetProperty("MSBuildProjectFile", projectFile)
+	evaluator.SetProperty("MSBuildProjectName", strings.TrimSuffix(projectFile, filepath.Ext(projectFile)))
+	evaluator.SetProperty("MSBuildProjectExtension", filepath.Ext(projectFile))
+	if solutionDir != "" {
+		evaluator.SetProperty("SolutionDir", withTrailingSeparator(solutionDir))
+	}
+
+	root, err := evaluator.loadFile(absPth)
+	if err != nil || root == nil {
+		return err
+	}
+
+	evaluator.sdkStyle = isSDKStyleProject(root)
+	if evaluator.sdkStyle {
+		if err := evaluator.evaluateSDKProps(projectDir); err != nil {
+			return err
+		}
+	}
+
+	if err := evaluator.evaluateRoot(absPth, root); err != nil {
+		return err
+	}
+
+	if evaluator.sdkStyle {
+		return evaluator.evaluateSDKTargets(projectDir)
+	}
+	return nil
+}
+
+// IsSDKStyle returns true if the evaluated project is an SDK-style project, like <Project Sdk="Microsoft.NET.Sdk">.
+func (evaluator *Evaluator) IsSDKStyle() bool {
+	return evaluator.sdkStyle
+}
+
+func (evaluator *Evaluator) evaluateFile(pth string) error {
+	root, err := evaluator.loadFile(pth)
+	if err != nil || root == nil {
+		return err
+	}
+	return evaluator.evaluateRoot(pth, root)
+}
+
+// loadFile parses the given file, it returns nil if the file is already imported.
+func (evaluator *Evaluator) loadFile(pth string) (*element, error) {
+	if evaluator.importedPths[pth] {
+		log.Debugf("%s is already imported, skipping...", pth)
+		return nil, nil
+	}
+	evaluator.importedPths[pth] = true
+
+	content, err := fileutil.ReadBytesFromFile(pth)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read (%s), error: %s", pth, err)
+	}
+
+	root, err := parseElements(content)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse (%s), error: %s", pth, err)
+	}
+	return root, nil
+}
+
+func (evaluator *Evaluator) evaluateRoot(pth string, root *element) error {
+	previousThisFile := evaluator.thisFileProperties()
+	evaluator.setThisFileProperties(pth)
+	defer evaluator.restoreThisFileProperties(previousThisFile)
+
+	return evaluator.evaluateElements(root.Children)
+}
+
+func (evaluator *Evaluator) evaluateElements(elements []*element) error {
+	for _, e := range elements {
+		condition := e.Attr("Condition")
+		evaluator.collectConfigurationPlatforms(condition)
+
+		switch e.Name {
+		case "PropertyGroup":
+			if !evaluator.EvaluateCondition(condition) {
+				continue
+			}
+
+			for _, property := range e.Children {
+				evaluator.collectConfigurationPlatforms(property.Attr("Condition"))
+
+				if !evaluator.EvaluateCondition(property.Attr("Condition")) {
+					continue
+				}
+				evaluator.SetProperty(property.Name, evaluator.Expand(strings.TrimSpace(property.Text)))
+			}
+		case "ItemGroup":
+			if !evaluator.EvaluateCondition(condition) {
+				continue
+			}
+
+			for _, item := range e.Children {
+				if !evaluator.EvaluateCondition(item.Attr("Condition")) {
+					continue
+				}
+				evaluator.evaluateItem(item)
+			}
+		case "Import":
+			if !evaluator.EvaluateCondition(condition) {
+				continue
+			}
+
+			if err := evaluator.evaluateImport(e.Attr("Project")); err != nil {
+				return err
+			}
+		case "Choose":
+			if err := evaluator.evaluateChoose(e); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// evaluateChoose evaluates the first When element whose condition is true, or the Otherwise element.
+func (evaluator *Evaluator) evaluateChoose(choose *element) error {
+	for _, e := range choose.Children {
+		evaluator.collectConfigurationPlatforms(e.Attr("Condition"))
+	}
+
+	for _, e := range choose.Children {
+		switch e.Name {
+		case "When":
+			if evaluator.EvaluateCondition(e.Attr("Condition")) {
+				return evaluator.evaluateElements(e.Children)
+			}
+		case "Otherwise":
+			return evaluator.evaluateElements(e.Children)
+		}
+	}
+	return nil
+}
+
+func (evaluator *Evaluator) evaluateItem(e *element) {
+	if remove := e.Attr("Remove"); remove != "" {
+		removed := map[string]bool{}
+		for _, include := range splitItemSpec(evaluator.Expand(remove)) {
+			removed[strings.ToLower(include)] = true
+		}
+
+		items := []Item{}
+		for _, item := range evaluator.items {
+			if strings.EqualFold(item.Type, e.Name) && removed[strings.ToLower(item.Include)] {
+				continue
+			}
+			items = append(items, item)
+		}
+		evaluator.items = items
+	}
+
+	include := e.Attr("Include")
+	if include == "" {
+		return
+	}
+
+	metadata := map[string]string{}
+	for name, value := range e.Attrs {
+		switch name {
+		case "Include", "Exclude", "Remove", "Update", "Condition":
+		default:
+			metadata[name] = evaluator.Expand(value)
+		}
+	}
+	for _, child := range e.Children {
+		if !evaluator.EvaluateCondition(child.Attr("Condition")) {
+			continue
+		}
+		metadata[child.Name] = evaluator.Expand(strings.TrimSpace(child.Text))
+	}
+
+	for _, itemSpec := range splitItemSpec(evaluator.Expand(include)) {
+		evaluator.items = append(evaluator.items, Item{
+			Type:     e.Name,
+			Include:  itemSpec,
+			Metadata: metadata,
+		})
+	}
+}
+
+func splitItemSpec(itemSpec string) []string {
+	itemSpecs := []string{}
+	for _, spec := range strings.Split(itemSpec, ";") {
+		if spec = strings.TrimSpace(spec); spec != "" {
+			itemSpecs = append(itemSpecs, spec)
+		}
+	}
+	return itemSpecs
+}
+
+func (evaluator *Evaluator) evaluateImport(project string) error {
+	importPth := utility.FixWindowsPath(evaluator.Expand(project))
+	if importPth == "" {
+		return nil
+	}
+	if !filepath.IsAbs(importPth) {
+		importPth = filepath.Join(evaluator.Property("MSBuildThisFileDirectory"), importPth)
+	}
+
+	pths, err := filepath.Glob(importPth)
+	if err != nil {
+		return fmt.Errorf("invalid import (%s), error: %s", project, err)
+	}
+	if len(pths) == 0 {
+		log.Debugf("imported project (%s) not found at: %s", project, importPth)
+		return nil
+	}
+
+	for _, pth := range pths {
+		if err := evaluator.evaluateFile(pth); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// collectConfigurationPlatforms records the Configuration and Platform values the given condition requires.
+func (evaluator *Evaluator) collectConfigurationPlatforms(condition string) {
+	if strings.TrimSpace(condition) == "" {
+		return
+	}
+
+	parsed, err := evaluator.parseCondition(condition)
+	if err != nil {
+		return
+	}
+
+	equalities := parsed.Equalities()
+	configuration := equalities["configuration"]
+	if configuration == "" {
+		return
+	}
+	evaluator.configurationPlatforms[utility.ToConfig(configuration, equalities["platform"])] = true
+}
+
+var thisFilePropertyNames = []string{"MSBuildThisFile", "MSBuildThisFileDirectory", "MSBuildThisFileFullPath", "MSBuildThisFileName", "MSBuildThisFileExtension"}
+
+func (evaluator *Evaluator) thisFileProperties() map[string]string {
+	properties := map[string]string{}
+	for _, name := range thisFilePropertyNames {
+		properties[name] = evaluator.Property(name)
+	}
+	return properties
+}
+
+func (evaluator *Evaluator) setThisFileProperties(pth string) {
+	fileName := filepath.Base(pth)
+	evaluator.SetProperty("MSBuildThisFile", fileName)
+	evaluator.SetProperty("MSBuildThisFileDirectory", withTrailingSeparator(filepath.Dir(pth)))
+	evaluator.SetProperty("MSBuildThisFileFullPath", pth)
+	evaluator.SetProperty("MSBuildThisFileName", strings.TrimSuffix(fileName, filepath.Ext(fileName)))
+	evaluator.SetProperty("MSBuildThisFileExtension", filepath.Ext(fileName))
+}
+
+func (evaluator *Evaluator) restoreThisFileProperties(properties map[string]string) {
+	for name, value := range properties {
+		evaluator.SetProperty(name, value)
+	}
+}
+
+func withTrailingSeparator(dir string) string {
+	if strings.HasSuffix(dir, string(filepath.Separator)) {
+		return dir
+	}
+	return dir + string(filepath.Separator)
+}
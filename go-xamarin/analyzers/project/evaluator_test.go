@@ -0,0 +1,244 @@
+package project
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestEvaluateProject(t *testing.T) {
+	tests := []struct {
+		name             string
+		files            map[string]string // path relative to the test dir - content, the project is App/App.csproj
+		globalProperties map[string]string
+		wantProperties   map[string]string
+		wantItems        []string // the includes of the Compile items
+		wantImported     []string // paths relative to the test dir
+		wantErr          bool
+	}{
+		{
+			name: "properties expand in order",
+			files: map[string]string{
+				"App/App.csproj": `<Project>
+  <PropertyGroup>
+    <A>one</A>
+    <B>$(A)-two</B>
+    <A>three</A>
+    <C>$(a)|$(B)</C>
+    <D>$(Later)</D>
+    <Later>later</Later>
+  </PropertyGroup>
+  <ItemGroup>
+    <Compile Include="$(A).cs;$(Later).cs" />
+  </ItemGroup>
+  <PropertyGroup>
+    <A>four</A>
+  </PropertyGroup>
+</Project>`,
+			},
+			wantProperties: map[string]string{"A": "four", "B": "one-two", "C": "three|one-two", "D": "", "MSBuildProjectName": "App"},
+			wantItems:      []string{"three.cs", "later.cs"},
+			wantImported:   []string{},
+		},
+		{
+			name: "global properties are not overridden",
+			files: map[string]string{
+				"App/App.csproj": `<Project>
+  <PropertyGroup>
+    <Configuration Condition=" '$(Configuration)' == '' ">Debug</Configuration>
+    <Configuration>Debug</Configuration>
+    <OutputPath>bin/$(Configuration)</OutputPath>
+  </PropertyGroup>
+</Project>`,
+			},
+			globalProperties: map[string]string{"Configuration": "Release"},
+			wantProperties:   map[string]string{"Configuration": "Release", "OutputPath": "bin/Release"},
+			wantItems:        []string{},
+			wantImported:     []string{},
+		},
+		{
+			name: "conditions on property groups and properties",
+			files: map[string]string{
+				"App/App.csproj": `<Project>
+  <PropertyGroup>
+    <Configuration Condition=" '$(Configuration)' == '' ">Debug</Configuration>
+    <Platform Condition=" '$(Platform)' == '' ">iPhone</Platform>
+  </PropertyGroup>
+  <PropertyGroup Condition=" '$(Configuration)|$(Platform)' == 'Debug|iPhone' ">
+    <DebugSymbols>true</DebugSymbols>
+  </PropertyGroup>
+  <PropertyGroup Condition=" '$(Configuration)|$(Platform)' == 'Release|iPhone' ">
+    <Optimize>true</Optimize>
+    <BuildIpa Condition=" '$(Optimize)' == 'true' ">true</BuildIpa>
+    <Archive Condition=" '$(Optimize)' != 'true' ">true</Archive>
+  </PropertyGroup>
+</Project>`,
+			},
+			globalProperties: map[string]string{"Configuration": "Release"},
+			wantProperties:   map[string]string{"Platform": "iPhone", "DebugSymbols": "", "Optimize": "true", "BuildIpa": "true", "Archive": ""},
+			wantItems:        []string{},
+			wantImported:     []string{},
+		},
+		{
+			name: "conditions on item groups and items",
+			files: map[string]string{
+				"App/App.csproj": `<Project>
+  <PropertyGroup>
+    <UseShared>true</UseShared>
+  </PropertyGroup>
+  <ItemGroup>
+    <Compile Include="Main.cs" />
+    <Compile Include="Shared.cs" Condition=" '$(UseShared)' == 'true' " />
+    <Compile Include="Legacy.cs" Condition=" '$(UseShared)' != 'true' " />
+  </ItemGroup>
+  <ItemGroup Condition=" '$(Configuration)' == 'Debug' ">
+    <Compile Include="Debug.cs" />
+  </ItemGroup>
+  <ItemGroup Condition=" '$(Configuration)' == 'Release' ">
+    <Compile Include="Release.cs" />
+    <Compile Remove="Main.cs" />
+  </ItemGroup>
+</Project>`,
+			},
+			globalProperties: map[string]string{"Configuration": "Release"},
+			wantProperties:   map[string]string{"UseShared": "true"},
+			wantItems:        []string{"Shared.cs", "Release.cs"},
+			wantImported:     []string{},
+		},
+		{
+			name: "imports are resolved relative to the importing file",
+			files: map[string]string{
+				"App/App.csproj": `<Project>
+  <Import Project="../build/Common.props" />
+  <PropertyGroup>
+    <AfterImport>$(Common)-$(Nested)</AfterImport>
+    <ThisFile>$(MSBuildThisFile)</ThisFile>
+  </PropertyGroup>
+</Project>`,
+				"build/Common.props": `<Project>
+  <PropertyGroup>
+    <Common>common</Common>
+    <CommonDir>$(MSBuildThisFileDirectory)</CommonDir>
+  </PropertyGroup>
+  <Import Project="$(MSBuildThisFileDirectory)nested/Nested.props" />
+  <ItemGroup>
+    <Compile Include="$(Nested).cs" />
+  </ItemGroup>
+</Project>`,
+				"build/nested/Nested.props": `<Project>
+  <PropertyGroup>
+    <Nested>nested</Nested>
+  </PropertyGroup>
+</Project>`,
+			},
+			wantProperties: map[string]string{"AfterImport": "common-nested", "ThisFile": "App.csproj"},
+			wantItems:      []string{"nested.cs"},
+			wantImported:   []string{"build/Common.props", "build/nested/Nested.props"},
+		},
+		{
+			name: "missing and false conditioned imports are skipped",
+			files: map[string]string{
+				"App/App.csproj": `<Project>
+  <Import Project="Missing.props" />
+  <Import Project="$(UndefinedDir)Missing.targets" />
+  <Import Project="Other.props" Condition="Exists('Other.props') And '$(UseOther)' == 'true'" />
+  <PropertyGroup>
+    <AfterImport>evaluated</AfterImport>
+  </PropertyGroup>
+</Project>`,
+				"App/Other.props": `<Project>
+  <PropertyGroup>
+    <Other>other</Other>
+  </PropertyGroup>
+</Project>`,
+			},
+			wantProperties: map[string]string{"AfterImport": "evaluated", "Other": ""},
+			wantItems:      []string{},
+			wantImported:   []string{},
+		},
+		{
+			name: "wildcard imports",
+			files: map[string]string{
+				"App/App.csproj": `<Project>
+  <Import Project="props/*.props" />
+</Project>`,
+				"App/props/A.props": `<Project><PropertyGroup><A>a</A></PropertyGroup></Project>`,
+				"App/props/B.props": `<Project><PropertyGroup><B>b</B></PropertyGroup></Project>`,
+			},
+			wantProperties: map[string]string{"A": "a", "B": "b"},
+			wantItems:      []string{},
+			wantImported:   []string{"App/props/A.props", "App/props/B.props"},
+		},
+		{
+			name: "invalid import",
+			files: map[string]string{
+				"App/App.csproj": `<Project>
+  <Import Project="Broken.props" />
+</Project>`,
+				"App/Broken.props": `<Project><PropertyGroup>`,
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpDir, err := ioutil.TempDir("", "evaluator")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer func() {
+				if err := os.RemoveAll(tmpDir); err != nil {
+					t.Log(err)
+				}
+			}()
+
+			for name, content := range tt.files {
+				pth := filepath.Join(tmpDir, name)
+				if err := os.MkdirAll(filepath.Dir(pth), 0755); err != nil {
+					t.Fatal(err)
+				}
+				if err := ioutil.WriteFile(pth, []byte(content), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			evaluator := NewEvaluator(tt.globalProperties)
+			err = evaluator.EvaluateProject(filepath.Join(tmpDir, "App", "App.csproj"), tmpDir)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("EvaluateProject() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			for name, want := range tt.wantProperties {
+				if got := evaluator.Property(name); got != want {
+					t.Errorf("Property(%s) = %q, want %q", name, got, want)
+				}
+			}
+
+			items := []string{}
+			for _, item := range evaluator.Items("Compile") {
+				items = append(items, item.Include)
+			}
+			if !reflect.DeepEqual(items, tt.wantItems) {
+				t.Errorf("Items(Compile) = %v, want %v", items, tt.wantItems)
+			}
+
+			imported := []string{}
+			for _, pth := range evaluator.ImportedPths() {
+				rel, err := filepath.Rel(tmpDir, pth)
+				if err != nil {
+					t.Fatal(err)
+				}
+				imported = append(imported, filepath.ToSlash(rel))
+			}
+			if !reflect.DeepEqual(imported, tt.wantImported) {
+				t.Errorf("ImportedPths() = %v, want %v", imported, tt.wantImported)
+			}
+		})
+	}
+}
@@ -0,0 +1,319 @@
+package project
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/bitrise-io/go-utils/log"
+	"github.com/bitrise-io/go-utils/pathutil"
+	"github.com/bitrise-steplib/steps-xamarin-archive/go-xamarin/constants"
+	"github.com/bitrise-steplib/steps-xamarin-archive/go-xamarin/utility"
+)
+
+// ConfigurationPlatformModel ...
+type ConfigurationPlatformModel struct {
+	Configuration string
+	Platform      string
+	OutputDir     string
+
+	MtouchArchs       []string
+	BuildIpa          bool
+	RuntimeIdentifier string
+
+	ArchivePath    string
+	IpaPackageDir  string
+	IpaPackageName string
+
+	SignAndroid bool
+}
+
+// Model ...
+type Model struct {
+	Pth  string
+	Name string // Set by solution analyze or set its path's filename without extension
+
+	// Solution Configuration|Platform - Project Configuration|Platform map
+	// !!! only set by solution analyze
+	ConfigMap map[string]string
+	// Project type GUID of the project's entry in the solution
+	// !!! only set by solution analyze
+	TypeID string
+	// Slash separated path of the solution folders containing the project, empty if the project is at the solution root
+	// !!! only set by solution analyze
+	SolutionFolder string
+
+	ID            string
+	SDK           constants.SDK
+	TestFramework constants.TestFramework
+	OutputType    string
+	AssemblyName  string
+
+	ReferredProjectIDs  []string
+	ReferredProjectPths []string
+	CompilePths         []string // Explicitly included source files, wildcard includes are not expanded
+	ImportedPths        []string // Imported files, like Directory.Build.props or the .projitems of the shared projects
+
+	ManifestPth        string
+	AndroidApplication bool
+
+	InfoPlistPth string
+
+	SDKStyle         bool
+	TargetFrameworks []string
+	TargetFramework  string // The target framework the SDK is resolved from
+	ApplicationID    string
+
+	IgnoredTargetFrameworks []string // The other platform target frameworks of a multi-platform project, they are not built
+
+	Configs map[string]ConfigurationPlatformModel // Project Configuration|Platform - ConfigurationPlatformModel map
+}
+
+// New ...
+func New(pth string) (Model, error) {
+	return analyzeProject(pth, "")
+}
+
+// NewInSolution analyzes the project as part of the solution in the given dir, which defines the SolutionDir property.
+func NewInSolution(pth, solutionDir string) (Model, error) {
+	return analyzeProject(pth, solutionDir)
+}
+
+func debugLog(err error, pth string) {
+	log.Debugf("%v for project at %s", err, pth)
+}
+
+func analyzeTargetDefinition(projectModel Model, pth string) (Model, error) {
+	projectDir := filepath.Dir(pth)
+	var err error
+
+	parsedProject, err := ParseProject(pth)
+	if err != nil {
+		return Model{}, err
+	}
+
+	for _, importedProject := range GetImportedProjects(parsedProject) {
+		if !strings.Contains(importedProject, "$(MSBuild") {
+			targetDefinitionPth := filepath.Join(projectDir, importedProject)
+
+			if exist, err := pathutil.IsPathExists(targetDefinitionPth); err != nil {
+				return Model{}, err
+			} else if exist {
+				projectFromTargetDefinition, err := analyzeTargetDefinition(projectModel, targetDefinitionPth)
+				if err != nil {
+					return Model{}, err
+				}
+
+				// Set properties became from solution analyze
+				projectFromTargetDefinition.Name = projectModel.Name
+				projectFromTargetDefinition.Pth = projectModel.Pth
+				projectFromTargetDefinition.ConfigMap = projectModel.ConfigMap
+				// ---
+
+				projectModel = projectFromTargetDefinition
+			}
+		}
+	}
+
+	projectModel.ID, err = GetProjectGUID(parsedProject)
+	if err != nil {
+		debugLog(err, pth)
+	}
+
+	projectModel.OutputType, err = GetOutputType(parsedProject)
+	if err != nil {
+		debugLog(err, pth)
+	}
+
+	projectModel.AssemblyName, err = GetAssemblyName(parsedProject)
+	if err != nil {
+		debugLog(err, pth)
+	}
+
+	projectModel.TestFramework, err = GetTestFramework(parsedProject)
+	if err != nil {
+		debugLog(err, pth)
+	}
+
+	projectModel.SDK, err = GetResolvedProjectTypeGUIDs(parsedProject)
+	if err != nil {
+		debugLog(err, pth)
+	}
+
+	if projectModel.SDK == constants.SDKAndroid {
+		projectModel.ManifestPth, err = GetResolvedAndroidManifestPath(parsedProject, projectDir)
+		if err != nil {
+			debugLog(err, pth)
+		}
+
+		projectModel.AndroidApplication, err = GetIsAndroidApplication(parsedProject)
+		if err != nil {
+			debugLog(err, pth)
+		}
+	}
+
+	projectModel.ReferredProjectIDs = GetReferencedProjectIds(parsedProject)
+
+	return projectModel, nil
+}
+
+func analyzeProject(pth, solutionDir string) (Model, error) {
+	absPth, err := pathutil.AbsPath(pth)
+	if err != nil {
+		return Model{}, fmt.Errorf("failed to expand path (%s), error: %s", pth, err)
+	}
+
+	fileName := filepath.Base(absPth)
+	ext := filepath.Ext(absPth)
+	fileName = strings.TrimSuffix(fileName, ext)
+
+	project := Model{
+		Pth:           absPth,
+		Name:          fileName,
+		ConfigMap:     map[string]string{},
+		Configs:       map[string]ConfigurationPlatformModel{},
+		SDK:           constants.SDKUnknown,
+		TestFramework: constants.TestFrameworkUnknown,
+	}
+
+	project, err = analyzeTargetDefinition(project, absPth)
+	if err != nil {
+		return Model{}, err
+	}
+
+	return evaluateProject(project, solutionDir)
+}
+
+// evaluateProject sets the evaluated property values of the project,
+// and the project configurations evaluated for each Configuration|Platform the project defines.
+func evaluateProject(project Model, solutionDir string) (Model, error) {
+	projectDir := filepath.Dir(project.Pth)
+
+	evaluator := NewEvaluator(nil)
+	if err := evaluator.EvaluateProject(project.Pth, solutionDir); err != nil {
+		return Model{}, err
+	}
+
+	project.SDKStyle = evaluator.IsSDKStyle()
+	project.TargetFrameworks = targetFrameworks(evaluator)
+	if project.SDK == constants.SDKUnknown {
+		project.SDK, project.TargetFramework, project.IgnoredTargetFrameworks = resolveTargetFrameworkSDK(project.TargetFrameworks)
+	}
+	project.ApplicationID = evaluator.Property("ApplicationId")
+
+	project.ReferredProjectPths = []string{}
+	for _, reference := range evaluator.Items("ProjectReference") {
+		project.ReferredProjectPths = append(project.ReferredProjectPths, resolvePath(projectDir, reference.Include))
+	}
+
+	project.CompilePths = []string{}
+	for _, compile := range evaluator.Items("Compile") {
+		if strings.ContainsAny(compile.Include, "*?") {
+			continue
+		}
+		project.CompilePths = append(project.CompilePths, resolvePath(projectDir, compile.Include))
+	}
+
+	project.ImportedPths = evaluator.ImportedPths()
+
+	if assemblyName := evaluator.Property("AssemblyName"); assemblyName != "" {
+		project.AssemblyName = assemblyName
+	}
+	if outputType := evaluator.Property("OutputType"); outputType != "" {
+		project.OutputType = strings.ToLower(outputType)
+	}
+	if project.SDK == constants.SDKAndroid {
+		if manifest := evaluator.Property("AndroidManifest"); manifest != "" {
+			project.ManifestPth = resolvePath(projectDir, manifest)
+		} else if project.SDKStyle {
+			manifestPth, err := defaultAndroidManifestPth(projectDir)
+			if err != nil {
+				return Model{}, err
+			}
+			project.ManifestPth = manifestPth
+		}
+
+		if androidApplication := evaluator.Property("AndroidApplication"); androidApplication != "" {
+			project.AndroidApplication = boolParse(androidApplication)
+		} else if project.SDKStyle {
+			project.AndroidApplication = project.OutputType == "exe"
+		}
+	}
+
+	if project.SDK == constants.SDKIOS || project.SDK == constants.SDKTvOS || project.SDK == constants.SDKMacOS {
+		plistPth, err := infoPlistPth(evaluator, projectDir, project.TargetFramework)
+		if err != nil {
+			return Model{}, err
+		}
+		project.InfoPlistPth = plistPth
+	}
+
+	defaultPlatform := evaluator.Property("Platform")
+
+	for _, configPlatform := range evaluator.ConfigurationPlatforms() {
+		split := strings.SplitN(configPlatform, "|", 2)
+		configuration, platform := split[0], split[1]
+		if platform == "" {
+			platform = defaultPlatform
+		}
+
+		globalProperties := map[string]string{
+			"Configuration": configuration,
+			"Platform":      platform,
+		}
+		if project.TargetFramework != "" && evaluator.Property("TargetFramework") == "" {
+			// multi-targeting project, evaluate the inner build of the analyzed target framework
+			globalProperties["TargetFramework"] = project.TargetFramework
+		}
+
+		configEvaluator := NewEvaluator(globalProperties)
+		if err := configEvaluator.EvaluateProject(project.Pth, solutionDir); err != nil {
+			return Model{}, err
+		}
+
+		project.Configs[utility.ToConfig(configuration, platform)] = newConfigurationPlatformModel(configEvaluator, projectDir, project.SDK)
+	}
+
+	return project, nil
+}
+
+// newConfigurationPlatformModel creates the project configuration from the evaluated properties.
+func newConfigurationPlatformModel(evaluator *Evaluator, projectDir string, sdk constants.SDK) ConfigurationPlatformModel {
+	configModel := ConfigurationPlatformModel{
+		Configuration: evaluator.Property("Configuration"),
+		Platform:      evaluator.Property("Platform"),
+		MtouchArchs:   []string{},
+	}
+
+	configModel.RuntimeIdentifier = evaluator.Property("RuntimeIdentifier")
+
+	if outputPath := evaluator.Property("OutputPath"); outputPath != "" {
+		configModel.OutputDir = resolvePath(projectDir, outputPath)
+	}
+
+	if sdk == constants.SDKIOS || sdk == constants.SDKMacOS || sdk == constants.SDKTvOS {
+		if mtouchArch := evaluator.Property("MtouchArch"); mtouchArch != "" {
+			configModel.MtouchArchs = utility.SplitAndStripList(mtouchArch, ",")
+		}
+		configModel.BuildIpa = boolParse(evaluator.Property("BuildIpa"))
+
+		if archivePth := evaluator.Property("ArchivePath"); archivePth != "" {
+			configModel.ArchivePath = resolvePath(projectDir, archivePth)
+		}
+		if ipaPackageDir := evaluator.Property("IpaPackageDir"); ipaPackageDir != "" {
+			configModel.IpaPackageDir = resolvePath(projectDir, ipaPackageDir)
+		}
+		configModel.IpaPackageName = evaluator.Property("IpaPackageName")
+
+		if configModel.IpaPackageDir == "" && evaluator.IsSDKStyle() && configModel.OutputDir != "" {
+			// .NET for iOS creates the ipa in the publish dir
+			configModel.IpaPackageDir = filepath.Join(configModel.OutputDir, "publish")
+		}
+	}
+
+	if sdk == constants.SDKAndroid {
+		configModel.SignAndroid = boolParse(evaluator.Property("AndroidKeyStore"))
+	}
+
+	return configModel
+}
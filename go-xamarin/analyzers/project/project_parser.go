@@ -4,13 +4,12 @@ import (
 	"encoding/xml"
 	"fmt"
 	"path/filepath"
-	"regexp"
 	"strings"
 
 	"github.com/bitrise-io/go-utils/fileutil"
 	"github.com/bitrise-io/go-utils/log"
-	"github.com/toggl/go-xamarin/constants"
-	"github.com/toggl/go-xamarin/utility"
+	"github.com/bitrise-steplib/steps-xamarin-archive/go-xamarin/constants"
+	"github.com/bitrise-steplib/steps-xamarin-archive/go-xamarin/utility"
 )
 
 // Project is the struct for the csproj file.
@@ -215,35 +214,30 @@ func GetPropertyGroupCondition(propertyGroup PropertyGroup) (string, error) {
 
 // GetResolvedConfiguration gets the resolved configuration from the given property group.
 func GetResolvedConfiguration(propertyGroup PropertyGroup) (string, error) {
-	conditionText, err := GetPropertyGroupCondition(propertyGroup)
-	if err != nil {
-		return "", err
-	}
-	if matches := regexp.MustCompile(`'\$\(Configuration\)\|\$\(Platform\)'\s*==\s*'(?P<config>.*)\|(?P<platform>.*)'`).FindStringSubmatch(conditionText); len(matches) == 3 {
-		return matches[1], nil
-	}
-
-	if matches := regexp.MustCompile(`'\$\(Configuration\)'\s*==\s*'(?P<config>.*)'`).FindStringSubmatch(conditionText); len(matches) == 2 {
-		return matches[1], nil
-	}
-
-	return conditionText, nil
+	return getConditionEquality(propertyGroup, "configuration")
 }
 
 // GetResolvedPlatform gets the resolved platform from the given property group.
 func GetResolvedPlatform(propertyGroup PropertyGroup) (string, error) {
+	return getConditionEquality(propertyGroup, "platform")
+}
+
+// getConditionEquality gets the value the property group's condition requires for the given property,
+// or the condition itself if it does not specify the property.
+func getConditionEquality(propertyGroup PropertyGroup, property string) (string, error) {
 	conditionText, err := GetPropertyGroupCondition(propertyGroup)
 	if err != nil {
 		return "", err
 	}
-	if matches := regexp.MustCompile(`'\$\(Configuration\)\|\$\(Platform\)'\s*==\s*'(?P<config>.*)\|(?P<platform>.*)'`).FindStringSubmatch(conditionText); len(matches) == 3 {
-		return matches[2], nil
-	}
 
-	if matches := regexp.MustCompile(`'\$\(Platform\)'\s*==\s*'(?P<platform>.*)'`).FindStringSubmatch(conditionText); len(matches) == 2 {
-		return matches[1], nil
+	condition, err := ParseCondition(conditionText)
+	if err != nil {
+		return conditionText, nil
 	}
 
+	if value, ok := condition.Equalities()[property]; ok {
+		return value, nil
+	}
 	return conditionText, nil
 }
 
@@ -273,8 +267,8 @@ func GetOutputDir(propertyGroup PropertyGroup, projectDir, configuration, platfo
 		return "", err
 	}
 	relativePth = utility.FixWindowsPath(relativePth)
-	strings.Replace(relativePth, "$(Configuration)", configuration, -1)
-	strings.Replace(relativePth, "$(Platform)", platform, -1)
+	relativePth = strings.Replace(relativePth, "$(Configuration)", configuration, -1)
+	relativePth = strings.Replace(relativePth, "$(Platform)", platform, -1)
 	return filepath.Join(projectDir, relativePth), nil
 }
 
@@ -314,6 +308,15 @@ func GetAndroidKeyStore(propertyGroup PropertyGroup) (bool, error) {
 	return false, fmt.Errorf(getterErrorMsg, "Android keystore")
 }
 
+// resolvePath returns the given project relative path as an absolute path.
+func resolvePath(projectDir, pth string) string {
+	pth = utility.FixWindowsPath(pth)
+	if filepath.IsAbs(pth) {
+		return pth
+	}
+	return filepath.Join(projectDir, pth)
+}
+
 // GetProjectTypeGUIDs gets the project type GUIDs from the given project.
 func GetProjectTypeGUIDs(project Project) (string, error) {
 	for _, propertyGroup := range project.PropertyGroups {
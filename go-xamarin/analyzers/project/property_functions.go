@@ -0,0 +1,396 @@
+package project
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"github.com/bitrise-steplib/steps-xamarin-archive/go-xamarin/utility"
+)
+
+var (
+	propertyNameRegexp    = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_\-]*$`)
+	propertyMethodRegexp  = regexp.MustCompile(`^([A-Za-z_][A-Za-z0-9_\-]*)\.([A-Za-z]+)\((.*)\)$`)
+	staticFunctionRegexp  = regexp.MustCompile(`^\[([A-Za-z.]+)\]::([A-Za-z]+)\((.*)\)$`)
+	targetFrameworkRegexp = regexp.MustCompile(`^([a-z]+)([0-9.]*)(?:-([a-z]+)([0-9.]*))?$`)
+)
+
+// scanReference returns the end of the $(...), @(...) or %(...) reference starting at the given index.
+// Parentheses are balanced and quoted strings are skipped, so property functions like
+// $([MSBuild]::GetTargetPlatformIdentifier('$(TargetFramework)')) are scanned as a single reference.
+func scanReference(value string, start int) (int, error) {
+	if start+1 >= len(value) || value[start+1] != '(' {
+		return 0, fmt.Errorf("invalid reference at: %s", value[start:])
+	}
+
+	depth := 0
+	for i := start + 1; i < len(value); {
+		switch value[i] {
+		case '(':
+			depth++
+			i++
+		case ')':
+			depth--
+			i++
+			if depth == 0 {
+				return i, nil
+			}
+		case '\'', '"', '`':
+			end, err := scanQuoted(value, i)
+			if err != nil {
+				return 0, err
+			}
+			i = end
+		default:
+			i++
+		}
+	}
+	return 0, fmt.Errorf("unterminated reference: %s", value[start:])
+}
+
+// scanQuoted returns the end of the quoted string starting at the given index,
+// the references inside the string may contain the quote character.
+func scanQuoted(value string, start int) (int, error) {
+	quote := value[start]
+	for i := start + 1; i < len(value); {
+		switch {
+		case value[i] == quote:
+			return i + 1, nil
+		case strings.IndexByte("$@%", value[i]) >= 0 && i+1 < len(value) && value[i+1] == '(':
+			end, err := scanReference(value, i)
+			if err != nil {
+				return 0, err
+			}
+			i = end
+		default:
+			i++
+		}
+	}
+	return 0, fmt.Errorf("unterminated string: %s", value[start:])
+}
+
+// ExpandValue replaces the property references of the given value like Expand does, and returns an error
+// if the value uses a property function which is not supported. Supported property functions:
+// - string methods of properties: Contains, StartsWith, EndsWith, ToLower(Invariant), ToUpper(Invariant), Trim, Replace
+// - [MSBuild]::GetTargetPlatformIdentifier, GetTargetPlatformVersion, GetTargetFrameworkIdentifier,
+// GetTargetFrameworkVersion, IsOSPlatform, EnsureTrailingSlash and the Version comparison functions
+// - [System.String]::IsNullOrEmpty, IsNullOrWhiteSpace and [System.IO.Path]::Combine
+func (evaluator *Evaluator) ExpandValue(value string) (string, error) {
+	var expanded strings.Builder
+	var unsupported error
+
+	for i := 0; i < len(value); {
+		if value[i] != '$' || i+1 >= len(value) || value[i+1] != '(' {
+			expanded.WriteByte(value[i])
+			i++
+			continue
+		}
+
+		end, err := scanReference(value, i)
+		if err != nil {
+			// unbalanced reference, kept as is
+			expanded.WriteString(value[i:])
+			break
+		}
+
+		reference := value[i:end]
+		propertyValue, err := evaluator.expandPropertyReference(strings.TrimSpace(reference[2 : len(reference)-1]))
+		if err != nil {
+			if unsupported == nil {
+				unsupported = fmt.Errorf("%s: %s", err, reference)
+			}
+			propertyValue = reference
+		}
+		expanded.WriteString(propertyValue)
+		i = end
+	}
+
+	result := itemReferenceRegexp.ReplaceAllStringFunc(expanded.String(), func(reference string) string {
+		itemType := itemReferenceRegexp.FindStringSubmatch(reference)[1]
+		includes := []string{}
+		for _, item := range evaluator.Items(itemType) {
+			includes = append(includes, item.Include)
+		}
+		return strings.Join(includes, ";")
+	})
+	return result, unsupported
+}
+
+// expandPropertyReference returns the value of the content of a $(...) reference.
+func (evaluator *Evaluator) expandPropertyReference(reference string) (string, error) {
+	if propertyNameRegexp.MatchString(reference) {
+		return evaluator.Property(reference), nil
+	}
+
+	if match := propertyMethodRegexp.FindStringSubmatch(reference); match != nil {
+		args, err := evaluator.functionArgs(match[3])
+		if err != nil {
+			return "", err
+		}
+		return stringMethod(evaluator.Property(match[1]), match[2], args)
+	}
+
+	if match := staticFunctionRegexp.FindStringSubmatch(reference); match != nil {
+		args, err := evaluator.functionArgs(match[3])
+		if err != nil {
+			return "", err
+		}
+		return staticFunction(match[1], match[2], args)
+	}
+
+	return "", fmt.Errorf("unsupported property function")
+}
+
+// functionArgs splits the comma separated arguments of a property function and returns their expanded values.
+func (evaluator *Evaluator) functionArgs(argList string) ([]string, error) {
+	var rawArgs []string
+	start := 0
+	for i := 0; i < len(argList); {
+		switch c := argList[i]; {
+		case c == ',':
+			rawArgs = append(rawArgs, argList[start:i])
+			i++
+			start = i
+		case c == '\'' || c == '"' || c == '`':
+			end, err := scanQuoted(argList, i)
+			if err != nil {
+				return nil, err
+			}
+			i = end
+		case strings.IndexByte("$@%", c) >= 0 && i+1 < len(argList) && argList[i+1] == '(':
+			end, err := scanReference(argList, i)
+			if err != nil {
+				return nil, err
+			}
+			i = end
+		default:
+			i++
+		}
+	}
+	if strings.TrimSpace(argList) != "" {
+		rawArgs = append(rawArgs, argList[start:])
+	}
+
+	args := make([]string, 0, len(rawArgs))
+	for _, arg := range rawArgs {
+		arg = strings.TrimSpace(arg)
+		if len(arg) >= 2 && strings.IndexByte(`'"`+"`", arg[0]) >= 0 && arg[len(arg)-1] == arg[0] {
+			arg = arg[1 : len(arg)-1]
+		}
+
+		expanded, err := evaluator.ExpandValue(arg)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, expanded)
+	}
+	return args, nil
+}
+
+func expectArgs(name string, args []string, count int) error {
+	if len(args) != count {
+		return fmt.Errorf("%s() expects %d argument(s), got: %d", name, count, len(args))
+	}
+	return nil
+}
+
+// stringMethod calls the given method of the property's string value.
+func stringMethod(value, method string, args []string) (string, error) {
+	switch strings.ToLower(method) {
+	case "contains", "startswith", "endswith":
+		if err := expectArgs(method, args, 1); err != nil {
+			return "", err
+		}
+
+		var result bool
+		switch strings.ToLower(method) {
+		case "contains":
+			result = strings.Contains(value, args[0])
+		case "startswith":
+			result = strings.HasPrefix(value, args[0])
+		default:
+			result = strings.HasSuffix(value, args[0])
+		}
+		return formatBool(result), nil
+	case "tolower", "tolowerinvariant":
+		return strings.ToLower(value), expectArgs(method, args, 0)
+	case "toupper", "toupperinvariant":
+		return strings.ToUpper(value), expectArgs(method, args, 0)
+	case "trim":
+		return strings.TrimSpace(value), expectArgs(method, args, 0)
+	case "replace":
+		if err := expectArgs(method, args, 2); err != nil {
+			return "", err
+		}
+		return strings.Replace(value, args[0], args[1], -1), nil
+	default:
+		return "", fmt.Errorf("unsupported string method: %s", method)
+	}
+}
+
+// staticFunction calls the given static property function.
+func staticFunction(typeName, function string, args []string) (string, error) {
+	switch strings.ToLower(typeName) {
+	case "msbuild":
+		return msbuildFunction(function, args)
+	case "system.string", "string":
+		switch strings.ToLower(function) {
+		case "isnullorempty":
+			if err := expectArgs(function, args, 1); err != nil {
+				return "", err
+			}
+			return formatBool(args[0] == ""), nil
+		case "isnullorwhitespace":
+			if err := expectArgs(function, args, 1); err != nil {
+				return "", err
+			}
+			return formatBool(strings.TrimSpace(args[0]) == ""), nil
+		}
+	case "system.io.path":
+		if strings.EqualFold(function, "Combine") {
+			pths := make([]string, len(args))
+			for i, arg := range args {
+				pths[i] = utility.FixWindowsPath(arg)
+			}
+			return filepath.Join(pths...), nil
+		}
+	}
+	return "", fmt.Errorf("unsupported property function: [%s]::%s", typeName, function)
+}
+
+// msbuildFunction calls the given MSBuild property function.
+func msbuildFunction(function string, args []string) (string, error) {
+	switch strings.ToLower(function) {
+	case "gettargetplatformidentifier", "gettargetplatformversion", "gettargetframeworkidentifier", "gettargetframeworkversion":
+		if len(args) < 1 || len(args) > 2 {
+			return "", fmt.Errorf("%s() expects 1 or 2 arguments, got: %d", function, len(args))
+		}
+		framework := parseTargetFramework(args[0])
+
+		switch strings.ToLower(function) {
+		case "gettargetplatformidentifier":
+			return framework.platform, nil
+		case "gettargetplatformversion":
+			return framework.platformVersion, nil
+		case "gettargetframeworkidentifier":
+			return framework.identifier, nil
+		default:
+			return framework.version, nil
+		}
+	case "isosplatform":
+		if err := expectArgs(function, args, 1); err != nil {
+			return "", err
+		}
+		platforms := map[string]string{"darwin": "osx", "windows": "windows", "linux": "linux"}
+		return formatBool(strings.EqualFold(args[0], platforms[runtime.GOOS])), nil
+	case "ensuretrailingslash":
+		if err := expectArgs(function, args, 1); err != nil {
+			return "", err
+		}
+		return withTrailingSeparator(args[0]), nil
+	case "versionequals", "versionnotequals", "versiongreaterthan", "versiongreaterthanorequals", "versionlessthan", "versionlessthanorequals":
+		if err := expectArgs(function, args, 2); err != nil {
+			return "", err
+		}
+		comparison, err := compareVersions(args[0], args[1])
+		if err != nil {
+			return "", err
+		}
+
+		results := map[string]bool{
+			"versionequals":              comparison == 0,
+			"versionnotequals":           comparison != 0,
+			"versiongreaterthan":         comparison > 0,
+			"versiongreaterthanorequals": comparison >= 0,
+			"versionlessthan":            comparison < 0,
+			"versionlessthanorequals":    comparison <= 0,
+		}
+		return formatBool(results[strings.ToLower(function)]), nil
+	default:
+		return "", fmt.Errorf("unsupported property function: [MSBuild]::%s", function)
+	}
+}
+
+func formatBool(value bool) string {
+	if value {
+		return "True"
+	}
+	return "False"
+}
+
+// targetFrameworkModel is a parsed target framework moniker, like net8.0-ios17.0.
+type targetFrameworkModel struct {
+	identifier      string
+	version         string
+	platform        string
+	platformVersion string
+}
+
+// parseTargetFramework parses the short form of a target framework moniker,
+// the platform identifier and version are empty if the target framework does not target a platform.
+func parseTargetFramework(targetFramework string) targetFrameworkModel {
+	match := targetFrameworkRegexp.FindStringSubmatch(strings.ToLower(strings.TrimSpace(targetFramework)))
+	if match == nil {
+		return targetFrameworkModel{}
+	}
+
+	framework := targetFrameworkModel{
+		version:         match[2],
+		platform:        match[3],
+		platformVersion: match[4],
+	}
+
+	switch match[1] {
+	case "netstandard":
+		framework.identifier = ".NETStandard"
+	case "netcoreapp":
+		framework.identifier = ".NETCoreApp"
+	case "net":
+		// net5.0 and later are .NET (Core), net472 like versions without a dot are .NET Framework
+		framework.identifier = ".NETCoreApp"
+		if !strings.Contains(framework.version, ".") {
+			framework.identifier = ".NETFramework"
+			framework.version = strings.Join(strings.Split(framework.version, ""), ".")
+		}
+	default:
+		framework.identifier = match[1]
+	}
+
+	if framework.version != "" && !strings.Contains(framework.version, ".") {
+		framework.version += ".0"
+	}
+	return framework
+}
+
+// compareVersions compares the dot separated numeric versions, missing components are zero.
+func compareVersions(a, b string) (int, error) {
+	aParts, bParts := strings.Split(strings.TrimSpace(a), "."), strings.Split(strings.TrimSpace(b), ".")
+	for len(aParts) < len(bParts) {
+		aParts = append(aParts, "0")
+	}
+	for len(bParts) < len(aParts) {
+		bParts = append(bParts, "0")
+	}
+
+	for i := range aParts {
+		aNumber, err := strconv.Atoi(aParts[i])
+		if err != nil {
+			return 0, fmt.Errorf("invalid version: %s", a)
+		}
+		bNumber, err := strconv.Atoi(bParts[i])
+		if err != nil {
+			return 0, fmt.Errorf("invalid version: %s", b)
+		}
+
+		if aNumber != bNumber {
+			if aNumber < bNumber {
+				return -1, nil
+			}
+			return 1, nil
+		}
+	}
+	return 0, nil
+}
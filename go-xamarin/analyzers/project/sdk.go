@@ -0,0 +1,201 @@
+package project
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/bitrise-io/go-utils/pathutil"
+	"github.com/bitrise-steplib/steps-xamarin-archive/go-xamarin/constants"
+	"github.com/bitrise-steplib/steps-xamarin-archive/go-xamarin/utility"
+)
+
+const (
+	directoryBuildPropsFileName   = "Directory.Build.props"
+	directoryBuildTargetsFileName = "Directory.Build.targets"
+)
+
+// isSDKStyleProject returns true if the project root references an MSBuild SDK,
+// like <Project Sdk="Microsoft.NET.Sdk">, <Sdk Name="..." /> or <Import Project="Sdk.props" Sdk="..." />.
+func isSDKStyleProject(root *element) bool {
+	if root.Attr("Sdk") != "" {
+		return true
+	}
+	for _, e := range root.Children {
+		if e.Name == "Sdk" || (e.Name == "Import" && e.Attr("Sdk") != "") {
+			return true
+		}
+	}
+	return false
+}
+
+// evaluateSDKProps evaluates the implicit imports preceding the body of an SDK-style project.
+// The SDK files themselves are not available, only the defaults the analyzer relies on are set.
+func (evaluator *Evaluator) evaluateSDKProps(projectDir string) error {
+	if evaluator.Property("Configuration") == "" {
+		evaluator.SetProperty("Configuration", "Debug")
+	}
+	if evaluator.Property("Platform") == "" {
+		evaluator.SetProperty("Platform", "AnyCPU")
+	}
+
+	if evaluator.Property("ImportDirectoryBuildProps") == "false" {
+		return nil
+	}
+	return evaluator.evaluateDirectoryBuildFile(projectDir, directoryBuildPropsFileName)
+}
+
+// evaluateSDKTargets evaluates the implicit imports following the body of an SDK-style project,
+// and sets the default values of the properties the project did not define.
+func (evaluator *Evaluator) evaluateSDKTargets(projectDir string) error {
+	if evaluator.Property("ImportDirectoryBuildTargets") != "false" {
+		if err := evaluator.evaluateDirectoryBuildFile(projectDir, directoryBuildTargetsFileName); err != nil {
+			return err
+		}
+	}
+
+	defaults := map[string]string{
+		"AssemblyName":   evaluator.Property("MSBuildProjectName"),
+		"OutputType":     "Library",
+		"Configurations": "Debug;Release",
+		"Platforms":      "AnyCPU",
+		"BaseOutputPath": `bin\`,
+	}
+	for name, value := range defaults {
+		if evaluator.Property(name) == "" {
+			evaluator.SetProperty(name, value)
+		}
+	}
+
+	for _, configuration := range splitItemSpec(evaluator.Property("Configurations")) {
+		for _, platform := range splitItemSpec(evaluator.Property("Platforms")) {
+			evaluator.configurationPlatforms[utility.ToConfig(configuration, platform)] = true
+		}
+	}
+
+	outputPath := evaluator.Property("OutputPath")
+	if outputPath == "" {
+		outputPath = withTrailingBackslash(evaluator.Property("BaseOutputPath"))
+		if platform := evaluator.Property("Platform"); platform != "" && !strings.EqualFold(platform, "AnyCPU") {
+			outputPath += platform + `\`
+		}
+		outputPath += evaluator.Property("Configuration") + `\`
+	}
+	outputPath = withTrailingBackslash(outputPath)
+
+	if targetFramework := evaluator.Property("TargetFramework"); targetFramework != "" && evaluator.Property("AppendTargetFrameworkToOutputPath") != "false" {
+		outputPath += strings.ToLower(targetFramework) + `\`
+	}
+	if runtimeIdentifier := evaluator.Property("RuntimeIdentifier"); runtimeIdentifier != "" && evaluator.Property("AppendRuntimeIdentifierToOutputPath") != "false" {
+		outputPath += runtimeIdentifier + `\`
+	}
+	evaluator.SetProperty("OutputPath", outputPath)
+
+	return nil
+}
+
+// evaluateDirectoryBuildFile evaluates the given file found in the project dir or in its closest parent dir.
+func (evaluator *Evaluator) evaluateDirectoryBuildFile(projectDir, fileName string) error {
+	dir := projectDir
+	for {
+		pth := filepath.Join(dir, fileName)
+		if exist, err := pathutil.IsPathExists(pth); err != nil {
+			return err
+		} else if exist {
+			return evaluator.evaluateFile(pth)
+		}
+
+		parentDir := filepath.Dir(dir)
+		if parentDir == dir {
+			return nil
+		}
+		dir = parentDir
+	}
+}
+
+func withTrailingBackslash(pth string) string {
+	if pth == "" || strings.HasSuffix(pth, `\`) || strings.HasSuffix(pth, "/") {
+		return pth
+	}
+	return pth + `\`
+}
+
+// targetFrameworks returns the target frameworks of the evaluated project,
+// defined by either the TargetFramework or the TargetFrameworks property.
+func targetFrameworks(evaluator *Evaluator) []string {
+	if targetFramework := evaluator.Property("TargetFramework"); targetFramework != "" {
+		return []string{targetFramework}
+	}
+	return splitItemSpec(evaluator.Property("TargetFrameworks"))
+}
+
+// resolveTargetFrameworkSDK returns the first target framework with a known SDK and its SDK,
+// along with the other platform target frameworks. Projects targeting multiple platforms are analyzed
+// for their first platform, the rest of the platforms are returned to let the caller report them.
+func resolveTargetFrameworkSDK(targetFrameworks []string) (constants.SDK, string, []string) {
+	sdk, resolved := constants.SDKUnknown, ""
+	var ignored []string
+	for _, targetFramework := range targetFrameworks {
+		targetFrameworkSDK, err := constants.ParseTargetFramework(targetFramework)
+		if err != nil {
+			continue
+		}
+
+		if resolved == "" {
+			sdk, resolved = targetFrameworkSDK, targetFramework
+		} else {
+			ignored = append(ignored, targetFramework)
+		}
+	}
+	return sdk, resolved, ignored
+}
+
+// defaultAndroidManifestPth returns the manifest of an SDK-style Android project which does not define
+// the AndroidManifest property: Platforms/Android/AndroidManifest.xml of a MAUI project, or the one in the project dir.
+func defaultAndroidManifestPth(projectDir string) (string, error) {
+	for _, pth := range []string{
+		filepath.Join(projectDir, "Platforms", "Android", "AndroidManifest.xml"),
+		filepath.Join(projectDir, "AndroidManifest.xml"),
+	} {
+		if exist, err := pathutil.IsPathExists(pth); err != nil {
+			return "", err
+		} else if exist {
+			return pth, nil
+		}
+	}
+	return "", nil
+}
+
+// infoPlistPth returns the Info.plist of an Apple project: the AppBundleManifest property of SDK-style projects,
+// the Info.plist included by the project, or the conventional Info.plist of the target platform.
+func infoPlistPth(evaluator *Evaluator, projectDir, targetFramework string) (string, error) {
+	if manifest := evaluator.Property("AppBundleManifest"); manifest != "" {
+		return resolvePath(projectDir, manifest), nil
+	}
+
+	for _, itemType := range []string{"None", "BundleResource", "Content"} {
+		for _, item := range evaluator.Items(itemType) {
+			if strings.EqualFold(filepath.Base(utility.FixWindowsPath(item.Include)), "Info.plist") {
+				return resolvePath(projectDir, item.Include), nil
+			}
+		}
+	}
+
+	candidates := []string{}
+	platformDirs := map[string]string{"ios": "iOS", "tvos": "tvOS", "maccatalyst": "MacCatalyst", "macos": "MacOS"}
+	if split := strings.SplitN(targetFramework, "-", 2); len(split) == 2 {
+		platform := strings.TrimRight(strings.ToLower(split[1]), "0123456789.")
+		if dir, ok := platformDirs[platform]; ok {
+			candidates = append(candidates, filepath.Join(projectDir, "Platforms", dir, "Info.plist"))
+		}
+	}
+	candidates = append(candidates, filepath.Join(projectDir, "Info.plist"))
+
+	for _, pth := range candidates {
+		if exist, err := pathutil.IsPathExists(pth); err != nil {
+			return "", err
+		} else if exist {
+			return pth, nil
+		}
+	}
+	return "", nil
+}
@@ -0,0 +1,102 @@
+package project
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/bitrise-steplib/steps-xamarin-archive/go-xamarin/constants"
+)
+
+func TestNewMauiProject(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "maui")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := os.RemoveAll(tmpDir); err != nil {
+			t.Log(err)
+		}
+	}()
+
+	pth := filepath.Join(tmpDir, "MauiApp", "MauiApp.csproj")
+	if err := os.MkdirAll(filepath.Dir(pth), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(pth, []byte(mauiTestProjectContent), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	proj, err := New(pth)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	if !proj.SDKStyle {
+		t.Errorf("SDKStyle = false, want true")
+	}
+	if proj.SDK != constants.SDKAndroid {
+		t.Errorf("SDK = %s, want %s", proj.SDK, constants.SDKAndroid)
+	}
+	if proj.TargetFramework != "net8.0-android" {
+		t.Errorf("TargetFramework = %s, want net8.0-android", proj.TargetFramework)
+	}
+	if want := []string{"net8.0-ios", "net8.0-maccatalyst"}; !reflect.DeepEqual(proj.IgnoredTargetFrameworks, want) {
+		t.Errorf("IgnoredTargetFrameworks = %v, want %v", proj.IgnoredTargetFrameworks, want)
+	}
+	if proj.ApplicationID != "com.companyname.mauiapp" {
+		t.Errorf("ApplicationID = %s, want com.companyname.mauiapp", proj.ApplicationID)
+	}
+	if proj.AssemblyName != "MauiApp" {
+		t.Errorf("AssemblyName = %s, want MauiApp", proj.AssemblyName)
+	}
+	if !proj.AndroidApplication {
+		t.Errorf("AndroidApplication = false, want true")
+	}
+
+	release, ok := proj.Configs["Release|AnyCPU"]
+	if !ok {
+		t.Fatalf("Release|AnyCPU config not found, configs: %v", proj.Configs)
+	}
+	if release.RuntimeIdentifier != "android-arm64" {
+		t.Errorf("Release RuntimeIdentifier = %s, want android-arm64", release.RuntimeIdentifier)
+	}
+	if want := filepath.Join(tmpDir, "MauiApp", "bin", "Release", "net8.0-android", "android-arm64"); release.OutputDir != want {
+		t.Errorf("Release OutputDir = %s, want %s", release.OutputDir, want)
+	}
+
+	debug, ok := proj.Configs["Debug|AnyCPU"]
+	if !ok {
+		t.Fatalf("Debug|AnyCPU config not found, configs: %v", proj.Configs)
+	}
+	if debug.RuntimeIdentifier != "" {
+		t.Errorf("Debug RuntimeIdentifier = %s, want empty", debug.RuntimeIdentifier)
+	}
+	if want := filepath.Join(tmpDir, "MauiApp", "bin", "Debug", "net8.0-android"); debug.OutputDir != want {
+		t.Errorf("Debug OutputDir = %s, want %s", debug.OutputDir, want)
+	}
+}
+
+func TestParseTargetFramework(t *testing.T) {
+	tests := []struct {
+		targetFramework string
+		want            targetFrameworkModel
+	}{
+		{"net8.0-ios17.0", targetFrameworkModel{identifier: ".NETCoreApp", version: "8.0", platform: "ios", platformVersion: "17.0"}},
+		{"net8.0-android", targetFrameworkModel{identifier: ".NETCoreApp", version: "8.0", platform: "android"}},
+		{"net6.0", targetFrameworkModel{identifier: ".NETCoreApp", version: "6.0"}},
+		{"netstandard2.0", targetFrameworkModel{identifier: ".NETStandard", version: "2.0"}},
+		{"net472", targetFrameworkModel{identifier: ".NETFramework", version: "4.7.2"}},
+		{"not a framework", targetFrameworkModel{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.targetFramework, func(t *testing.T) {
+			if got := parseTargetFramework(tt.targetFramework); got != tt.want {
+				t.Errorf("parseTargetFramework(%q) = %+v, want %+v", tt.targetFramework, got, tt.want)
+			}
+		})
+	}
+}
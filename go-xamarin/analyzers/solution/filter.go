@@ -0,0 +1,94 @@
+package solution
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/bitrise-io/go-utils/fileutil"
+	"github.com/bitrise-io/go-utils/log"
+	"github.com/bitrise-io/go-utils/pathutil"
+	"github.com/bitrise-steplib/steps-xamarin-archive/go-xamarin/analyzers/project"
+	"github.com/bitrise-steplib/steps-xamarin-archive/go-xamarin/utility"
+)
+
+// filterModel is the content of a solution filter (.slnf) file.
+type filterModel struct {
+	Solution struct {
+		Path     string   `json:"path"`     // Relative to the solution filter
+		Projects []string `json:"projects"` // Relative to the solution
+	} `json:"solution"`
+}
+
+// analyzeSolutionFilter analyzes the solution referenced by the solution filter,
+// restricted to the projects listed in the filter.
+func analyzeSolutionFilter(pth string, analyzeProjects bool) (Model, error) {
+	absPth, err := pathutil.AbsPath(pth)
+	if err != nil {
+		return Model{}, fmt.Errorf("Failed to expand path (%s), error: %s", pth, err)
+	}
+
+	content, err := fileutil.ReadBytesFromFile(absPth)
+	if err != nil {
+		return Model{}, fmt.Errorf("failed to read solution filter (%s), error: %s", absPth, err)
+	}
+
+	// Visual Studio writes the file with byte order mark
+	content = []byte(strings.TrimPrefix(string(content), "\uFEFF"))
+
+	var filter filterModel
+	if err := json.Unmarshal(content, &filter); err != nil {
+		return Model{}, fmt.Errorf("failed to parse solution filter (%s), error: %s", absPth, err)
+	}
+
+	if filter.Solution.Path == "" {
+		return Model{}, fmt.Errorf("solution filter (%s) does not reference a solution", absPth)
+	}
+
+	solutionPth := filepath.Join(filepath.Dir(absPth), utility.FixWindowsPath(filter.Solution.Path))
+	if exist, err := pathutil.IsPathExists(solutionPth); err != nil {
+		return Model{}, err
+	} else if !exist {
+		return Model{}, fmt.Errorf("solution (%s) of solution filter (%s) not exist", solutionPth, absPth)
+	}
+
+	solution, err := analyzeSolution(solutionPth, false)
+	if err != nil {
+		return Model{}, err
+	}
+
+	solution.FilterPth = absPth
+	solution.ProjectMap = filterProjects(solution.ProjectMap, filepath.Dir(solutionPth), filter.Solution.Projects)
+
+	if analyzeProjects {
+		return analyzeSolutionProjects(solution)
+	}
+
+	return solution, nil
+}
+
+// filterProjects returns the projects listed by their solution relative path.
+func filterProjects(projectMap map[string]project.Model, solutionDir string, projectPths []string) map[string]project.Model {
+	filtered := map[string]project.Model{}
+
+	for _, projectPth := range projectPths {
+		pth := filepath.Join(solutionDir, utility.FixWindowsPath(projectPth))
+
+		found := false
+		for projectID, proj := range projectMap {
+			// project paths are case insensitive on Windows, where the filter is usually created
+			if strings.EqualFold(proj.Pth, pth) {
+				filtered[projectID] = proj
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			log.Warnf("Project (%s) of the solution filter is not part of the solution", projectPth)
+		}
+	}
+
+	return filtered
+}
@@ -0,0 +1,275 @@
+package solution
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// GraphModel is the project reference graph of the solution, its nodes are the project IDs of the solution.
+type GraphModel struct {
+	names map[string]string // Project ID - Project name map
+	pths  map[string]string // Project ID - Project path map
+
+	dependencies map[string][]string // Project ID - referred project IDs
+	dependents   map[string][]string // Project ID - referring project IDs
+	missing      map[string][]string // Project ID - referred project paths, which are not part of the solution
+
+	Warnings []string
+}
+
+// ReferenceGraph returns the project reference graph of the solution's projects.
+// References are resolved by project path, or by project ID if the project does not define reference paths.
+func (solution Model) ReferenceGraph() GraphModel {
+	graph := GraphModel{
+		names:        map[string]string{},
+		pths:         map[string]string{},
+		dependencies: map[string][]string{},
+		dependents:   map[string][]string{},
+		missing:      map[string][]string{},
+	}
+
+	for projectID, proj := range solution.ProjectMap {
+		graph.names[projectID] = proj.Name
+		graph.pths[projectID] = proj.Pth
+	}
+
+	projectIDByPth := func(pth string) (string, bool) {
+		for projectID, proj := range solution.ProjectMap {
+			if strings.EqualFold(proj.Pth, pth) {
+				return projectID, true
+			}
+		}
+		return "", false
+	}
+
+	for _, projectID := range graph.sortedIDs(graph.allIDs()) {
+		proj := solution.ProjectMap[projectID]
+
+		referredIDs := map[string]bool{}
+		addDependency := func(referredID string) {
+			if referredIDs[referredID] {
+				return
+			}
+			referredIDs[referredID] = true
+			graph.dependencies[projectID] = append(graph.dependencies[projectID], referredID)
+			graph.dependents[referredID] = append(graph.dependents[referredID], projectID)
+		}
+
+		if len(proj.ReferredProjectPths) > 0 {
+			for _, pth := range proj.ReferredProjectPths {
+				if referredID, ok := projectIDByPth(pth); ok {
+					addDependency(referredID)
+					continue
+				}
+
+				graph.missing[projectID] = append(graph.missing[projectID], pth)
+				graph.Warnings = append(graph.Warnings, fmt.Sprintf("Project (%s) references project (%s), which is not part of the solution", proj.Name, pth))
+			}
+		} else {
+			for _, referredID := range proj.ReferredProjectIDs {
+				if referredID == "" {
+					continue
+				}
+				if _, ok := solution.ProjectMap[referredID]; ok {
+					addDependency(referredID)
+					continue
+				}
+
+				graph.Warnings = append(graph.Warnings, fmt.Sprintf("Project (%s) references project with id (%s), which is not part of the solution", proj.Name, referredID))
+			}
+		}
+	}
+
+	for projectID := range graph.dependencies {
+		graph.dependencies[projectID] = graph.sortedIDs(graph.dependencies[projectID])
+	}
+	for projectID := range graph.dependents {
+		graph.dependents[projectID] = graph.sortedIDs(graph.dependents[projectID])
+	}
+
+	return graph
+}
+
+// Dependencies returns the IDs of the projects directly referred by the given project.
+func (graph GraphModel) Dependencies(projectID string) []string {
+	return append([]string{}, graph.dependencies[projectID]...)
+}
+
+// TransitiveDependencies returns the IDs of the projects referred by the given project, directly or transitively.
+func (graph GraphModel) TransitiveDependencies(projectID string) []string {
+	return graph.reachable(projectID, graph.dependencies)
+}
+
+// Dependents returns the IDs of the projects directly referring the given project.
+func (graph GraphModel) Dependents(projectID string) []string {
+	return append([]string{}, graph.dependents[projectID]...)
+}
+
+// TransitiveDependents returns the IDs of the projects referring the given project, directly or transitively.
+func (graph GraphModel) TransitiveDependents(projectID string) []string {
+	return graph.reachable(projectID, graph.dependents)
+}
+
+// MissingReferences returns the paths of the projects referred by the given project, which are not part of the solution.
+func (graph GraphModel) MissingReferences(projectID string) []string {
+	return append([]string{}, graph.missing[projectID]...)
+}
+
+// TopologicalOrder returns the project IDs ordered so, that every project comes after the projects it refers,
+// independent projects are ordered by name. Returns an error if the references contain a cycle.
+func (graph GraphModel) TopologicalOrder() ([]string, error) {
+	if cycles := graph.Cycles(); len(cycles) > 0 {
+		return nil, fmt.Errorf("project references contain a cycle: %s", graph.cycleString(cycles[0]))
+	}
+
+	remaining := map[string]int{} // Project ID - number of referred projects not yet ordered
+	for _, projectID := range graph.allIDs() {
+		remaining[projectID] = len(graph.dependencies[projectID])
+	}
+
+	order := []string{}
+	for len(order) < len(remaining) {
+		var ready []string
+		for projectID, count := range remaining {
+			if count == 0 {
+				ready = append(ready, projectID)
+			}
+		}
+		ready = graph.sortedIDs(ready)
+
+		// the first ready project is ordered next, to keep the order independent of the map iteration
+		next := ready[0]
+		order = append(order, next)
+		remaining[next] = -1
+		for _, dependentID := range graph.dependents[next] {
+			remaining[dependentID]--
+		}
+	}
+
+	return order, nil
+}
+
+// Cycles returns the reference cycles of the graph, each cycle is a list of project IDs.
+func (graph GraphModel) Cycles() [][]string {
+	// Tarjan's strongly connected components algorithm
+	index := 0
+	indexes := map[string]int{}
+	lowLinks := map[string]int{}
+	onStack := map[string]bool{}
+	stack := []string{}
+	cycles := [][]string{}
+
+	var connect func(projectID string)
+	connect = func(projectID string) {
+		indexes[projectID] = index
+		lowLinks[projectID] = index
+		index++
+		stack = append(stack, projectID)
+		onStack[projectID] = true
+
+		for _, referredID := range graph.dependencies[projectID] {
+			if _, visited := indexes[referredID]; !visited {
+				connect(referredID)
+				if lowLinks[referredID] < lowLinks[projectID] {
+					lowLinks[projectID] = lowLinks[referredID]
+				}
+			} else if onStack[referredID] && indexes[referredID] < lowLinks[projectID] {
+				lowLinks[projectID] = indexes[referredID]
+			}
+		}
+
+		if lowLinks[projectID] != indexes[projectID] {
+			return
+		}
+
+		component := []string{}
+		for {
+			last := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			onStack[last] = false
+			component = append(component, last)
+			if last == projectID {
+				break
+			}
+		}
+
+		if len(component) > 1 || graph.refers(projectID, projectID) {
+			cycles = append(cycles, graph.sortedIDs(component))
+		}
+	}
+
+	for _, projectID := range graph.sortedIDs(graph.allIDs()) {
+		if _, visited := indexes[projectID]; !visited {
+			connect(projectID)
+		}
+	}
+
+	return cycles
+}
+
+// Name returns the name of the given project.
+func (graph GraphModel) Name(projectID string) string {
+	return graph.names[projectID]
+}
+
+// Pth returns the path of the given project.
+func (graph GraphModel) Pth(projectID string) string {
+	return graph.pths[projectID]
+}
+
+func (graph GraphModel) refers(projectID, referredID string) bool {
+	for _, id := range graph.dependencies[projectID] {
+		if id == referredID {
+			return true
+		}
+	}
+	return false
+}
+
+func (graph GraphModel) reachable(projectID string, edges map[string][]string) []string {
+	visited := map[string]bool{projectID: true}
+	reached := []string{}
+
+	var visit func(id string)
+	visit = func(id string) {
+		for _, next := range edges[id] {
+			if visited[next] {
+				continue
+			}
+			visited[next] = true
+			reached = append(reached, next)
+			visit(next)
+		}
+	}
+	visit(projectID)
+
+	return graph.sortedIDs(reached)
+}
+
+func (graph GraphModel) allIDs() []string {
+	projectIDs := []string{}
+	for projectID := range graph.names {
+		projectIDs = append(projectIDs, projectID)
+	}
+	return projectIDs
+}
+
+// sortedIDs sorts the project IDs by project name, then by ID.
+func (graph GraphModel) sortedIDs(projectIDs []string) []string {
+	sort.Slice(projectIDs, func(i, j int) bool {
+		if graph.names[projectIDs[i]] != graph.names[projectIDs[j]] {
+			return graph.names[projectIDs[i]] < graph.names[projectIDs[j]]
+		}
+		return projectIDs[i] < projectIDs[j]
+	})
+	return projectIDs
+}
+
+func (graph GraphModel) cycleString(cycle []string) string {
+	names := []string{}
+	for _, projectID := range cycle {
+		names = append(names, graph.names[projectID])
+	}
+	return strings.Join(names, ", ")
+}
@@ -0,0 +1,119 @@
+package solution
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/bitrise-steplib/steps-xamarin-archive/go-xamarin/analyzers/project"
+)
+
+// testGraph returns the reference graph of a solution with the given projects and their references by project name,
+// the project names are used as project IDs.
+func testGraph(references map[string][]string) GraphModel {
+	projectPth := func(name string) string {
+		return "/src/" + name + "/" + name + ".csproj"
+	}
+
+	solution := Model{ProjectMap: map[string]project.Model{}}
+	for name, referredNames := range references {
+		proj := project.Model{Name: name, Pth: projectPth(name), ReferredProjectPths: []string{}}
+		for _, referredName := range referredNames {
+			proj.ReferredProjectPths = append(proj.ReferredProjectPths, projectPth(referredName))
+		}
+		solution.ProjectMap[name] = proj
+	}
+	return solution.ReferenceGraph()
+}
+
+func TestGraphCycles(t *testing.T) {
+	tests := []struct {
+		name       string
+		references map[string][]string
+		want       [][]string
+	}{
+		{
+			name:       "no references",
+			references: map[string][]string{"A": nil, "B": nil},
+			want:       [][]string{},
+		},
+		{
+			name:       "diamond",
+			references: map[string][]string{"App": {"Core", "UI"}, "UI": {"Core"}, "Core": nil},
+			want:       [][]string{},
+		},
+		{
+			name:       "self reference",
+			references: map[string][]string{"A": {"A"}, "B": {"A"}},
+			want:       [][]string{{"A"}},
+		},
+		{
+			name:       "two project cycle",
+			references: map[string][]string{"A": {"B"}, "B": {"A"}, "C": {"A"}},
+			want:       [][]string{{"A", "B"}},
+		},
+		{
+			name:       "long cycle",
+			references: map[string][]string{"A": {"B"}, "B": {"C"}, "C": {"D"}, "D": {"A"}},
+			want:       [][]string{{"A", "B", "C", "D"}},
+		},
+		{
+			name: "separate cycles",
+			references: map[string][]string{
+				"A": {"B"}, "B": {"A"},
+				"C": {"D"}, "D": {"E"}, "E": {"C"},
+				"F": {"A", "C"},
+			},
+			want: [][]string{{"A", "B"}, {"C", "D", "E"}},
+		},
+		{
+			name:       "missing reference",
+			references: map[string][]string{"A": {"Missing"}},
+			want:       [][]string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := testGraph(tt.references).Cycles(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Cycles() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGraphTopologicalOrder(t *testing.T) {
+	tests := []struct {
+		name       string
+		references map[string][]string
+		want       []string
+		wantErr    bool
+	}{
+		{
+			name:       "independent projects by name",
+			references: map[string][]string{"C": nil, "A": nil, "B": nil},
+			want:       []string{"A", "B", "C"},
+		},
+		{
+			name:       "referred projects first",
+			references: map[string][]string{"App": {"Core", "UI"}, "UI": {"Core"}, "Core": nil},
+			want:       []string{"Core", "UI", "App"},
+		},
+		{
+			name:       "cycle",
+			references: map[string][]string{"A": {"B"}, "B": {"A"}},
+			wantErr:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := testGraph(tt.references).TopologicalOrder()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("TopologicalOrder() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("TopologicalOrder() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
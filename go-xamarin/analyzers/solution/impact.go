@@ -0,0 +1,183 @@
+package solution
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/bitrise-steplib/steps-xamarin-archive/go-xamarin/analyzers/project"
+)
+
+// buildFileNames are the files, which affect the build of every project in their dir, when changed.
+var buildFileNames = []string{
+	"Directory.Build.props",
+	"Directory.Build.targets",
+	"Directory.Packages.props",
+	"global.json",
+	"NuGet.config",
+}
+
+// ChangeImpactModel describes which projects are affected by a set of changed files.
+type ChangeImpactModel struct {
+	ChangedProjectIDs  []string // Projects owning a changed file
+	AffectedProjectIDs []string // The changed projects and the projects referring them, directly or transitively
+
+	BuildFilePths []string // Changed files affecting every project in their dir, like the solution or Directory.Build.props
+	UnownedPths   []string // Changed files not owned by any project
+	BuildAll      bool     // A changed file in the solution dir is not owned by any project, every project is affected
+}
+
+// ChangeImpact maps the changed files to the projects owning them and returns the projects affected by the changes.
+// A file is owned by the project in the closest parent dir of the file, by the projects explicitly compiling it
+// and by the projects importing it or the .projitems of the shared project in its dir.
+// A changed file in the solution dir, which is not owned by any project (like a build file imported from an unknown place),
+// affects every project.
+func (solution Model) ChangeImpact(graph GraphModel, changedPths []string) ChangeImpactModel {
+	impact := ChangeImpactModel{
+		ChangedProjectIDs:  []string{},
+		AffectedProjectIDs: []string{},
+		BuildFilePths:      []string{},
+		UnownedPths:        []string{},
+	}
+
+	changed := map[string]bool{}
+	for _, pth := range changedPths {
+		pth = filepath.Clean(pth)
+
+		if solution.isBuildFile(pth) {
+			impact.BuildFilePths = append(impact.BuildFilePths, pth)
+			isSolution := pathsEqual(pth, solution.Pth) || pathsEqual(pth, solution.FilterPth)
+			for projectID, proj := range solution.ProjectMap {
+				if isSolution || isInDir(proj.Pth, filepath.Dir(pth)) {
+					changed[projectID] = true
+				}
+			}
+			continue
+		}
+
+		owners := solution.owningProjectIDs(pth)
+		if len(owners) == 0 {
+			impact.UnownedPths = append(impact.UnownedPths, pth)
+			if isInDir(pth, filepath.Dir(solution.Pth)) {
+				impact.BuildAll = true
+			}
+			continue
+		}
+
+		for _, projectID := range owners {
+			changed[projectID] = true
+		}
+	}
+
+	if impact.BuildAll {
+		for projectID := range solution.ProjectMap {
+			changed[projectID] = true
+		}
+	}
+
+	affected := map[string]bool{}
+	for projectID := range changed {
+		impact.ChangedProjectIDs = append(impact.ChangedProjectIDs, projectID)
+
+		affected[projectID] = true
+		for _, dependentID := range graph.TransitiveDependents(projectID) {
+			affected[dependentID] = true
+		}
+	}
+
+	for projectID := range affected {
+		impact.AffectedProjectIDs = append(impact.AffectedProjectIDs, projectID)
+	}
+
+	impact.ChangedProjectIDs = graph.sortedIDs(impact.ChangedProjectIDs)
+	impact.AffectedProjectIDs = graph.sortedIDs(impact.AffectedProjectIDs)
+
+	return impact
+}
+
+// isBuildFile returns if the file is the solution, the solution filter or a build file like Directory.Build.props.
+func (solution Model) isBuildFile(pth string) bool {
+	if pathsEqual(pth, solution.Pth) || pathsEqual(pth, solution.FilterPth) {
+		return true
+	}
+
+	for _, name := range buildFileNames {
+		if strings.EqualFold(filepath.Base(pth), name) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// owningProjectIDs returns the project in the closest parent dir of the file, the projects compiling the file
+// and the projects importing the file or the .projitems of the shared project in the file's dir.
+func (solution Model) owningProjectIDs(pth string) []string {
+	owners := []string{}
+
+	closestID := ""
+	closestDirLen := -1
+	for projectID, proj := range solution.ProjectMap {
+		projectDir := filepath.Dir(proj.Pth)
+		if !isInDir(pth, projectDir) {
+			continue
+		}
+
+		if len(projectDir) > closestDirLen {
+			closestID = projectID
+			closestDirLen = len(projectDir)
+		}
+	}
+	if closestID != "" {
+		owners = append(owners, closestID)
+	}
+
+	for projectID, proj := range solution.ProjectMap {
+		if projectID == closestID {
+			continue
+		}
+
+		if isCompiled(pth, proj) || isImported(pth, proj) {
+			owners = append(owners, projectID)
+		}
+	}
+
+	return owners
+}
+
+func isCompiled(pth string, proj project.Model) bool {
+	for _, compilePth := range proj.CompilePths {
+		if pathsEqual(pth, compilePth) {
+			return true
+		}
+	}
+	return false
+}
+
+// isImported returns if the project imports the file, or the file is in the dir of a shared project (.projitems)
+// imported by the project.
+func isImported(pth string, proj project.Model) bool {
+	for _, importedPth := range proj.ImportedPths {
+		if pathsEqual(pth, importedPth) {
+			return true
+		}
+		if strings.EqualFold(filepath.Ext(importedPth), ".projitems") && isInDir(pth, filepath.Dir(importedPth)) {
+			return true
+		}
+	}
+	return false
+}
+
+func isInDir(pth, dir string) bool {
+	rel, err := filepath.Rel(dir, pth)
+	if err != nil {
+		return false
+	}
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+func pathsEqual(pth, other string) bool {
+	if pth == "" || other == "" {
+		return false
+	}
+	return filepath.Clean(pth) == filepath.Clean(other)
+}
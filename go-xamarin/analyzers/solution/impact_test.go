@@ -0,0 +1,146 @@
+package solution
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/bitrise-steplib/steps-xamarin-archive/go-xamarin/analyzers/project"
+)
+
+func TestChangeImpact(t *testing.T) {
+	// Droid and iOS refer Core and import the Shared project, Droid.Tests refers Droid, Web is independent.
+	solution := Model{
+		Pth: "/src/App.sln",
+		ProjectMap: map[string]project.Model{
+			"Core": {Name: "Core", Pth: "/src/Core/Core.csproj", CompilePths: []string{"/lib/Linked.cs"}},
+			"Droid": {
+				Name: "Droid", Pth: "/src/Droid/Droid.csproj",
+				ReferredProjectPths: []string{"/src/Core/Core.csproj"},
+				ImportedPths:        []string{"/src/Shared/Shared.projitems", "/build/Signing.targets"},
+			},
+			"Droid.Tests": {
+				Name: "Droid.Tests", Pth: "/src/Droid/Tests/Droid.Tests.csproj",
+				ReferredProjectPths: []string{"/src/Droid/Droid.csproj"},
+			},
+			"iOS": {
+				Name: "iOS", Pth: "/src/iOS/iOS.csproj",
+				ReferredProjectPths: []string{"/src/Core/Core.csproj"},
+				ImportedPths:        []string{"/src/Shared/Shared.projitems"},
+			},
+			"Web": {Name: "Web", Pth: "/src/Web/Web.csproj"},
+		},
+	}
+	graph := solution.ReferenceGraph()
+
+	all := []string{"Core", "Droid", "Droid.Tests", "Web", "iOS"}
+
+	tests := []struct {
+		name         string
+		changedPths  []string
+		wantChanged  []string
+		wantAffected []string
+		wantBuild    []string
+		wantUnowned  []string
+		wantBuildAll bool
+	}{
+		{
+			name:         "no changes",
+			wantChanged:  []string{},
+			wantAffected: []string{},
+		},
+		{
+			name:         "file in the project dir",
+			changedPths:  []string{"/src/Web/Pages/Index.cs"},
+			wantChanged:  []string{"Web"},
+			wantAffected: []string{"Web"},
+		},
+		{
+			name:         "referring projects are affected transitively",
+			changedPths:  []string{"/src/Core/Models/Item.cs"},
+			wantChanged:  []string{"Core"},
+			wantAffected: []string{"Core", "Droid", "Droid.Tests", "iOS"},
+		},
+		{
+			name:         "the closest project dir owns the file",
+			changedPths:  []string{"/src/Droid/Tests/MainTests.cs"},
+			wantChanged:  []string{"Droid.Tests"},
+			wantAffected: []string{"Droid.Tests"},
+		},
+		{
+			name:         "compiled file outside of the solution dir",
+			changedPths:  []string{"/lib/Linked.cs"},
+			wantChanged:  []string{"Core"},
+			wantAffected: []string{"Core", "Droid", "Droid.Tests", "iOS"},
+		},
+		{
+			name:         "file of an imported shared project",
+			changedPths:  []string{"/src/Shared/Views/MainView.cs"},
+			wantChanged:  []string{"Droid", "iOS"},
+			wantAffected: []string{"Droid", "Droid.Tests", "iOS"},
+		},
+		{
+			name:         "imported file outside of the solution dir",
+			changedPths:  []string{"/build/Signing.targets"},
+			wantChanged:  []string{"Droid"},
+			wantAffected: []string{"Droid", "Droid.Tests"},
+		},
+		{
+			name:         "build file affects the projects in its dir",
+			changedPths:  []string{"/src/Droid/Directory.Build.props"},
+			wantChanged:  []string{"Droid", "Droid.Tests"},
+			wantAffected: []string{"Droid", "Droid.Tests"},
+			wantBuild:    []string{"/src/Droid/Directory.Build.props"},
+		},
+		{
+			name:         "changed solution affects every project",
+			changedPths:  []string{"/src/App.sln"},
+			wantChanged:  all,
+			wantAffected: all,
+			wantBuild:    []string{"/src/App.sln"},
+		},
+		{
+			name:         "unowned file in the solution dir affects every project",
+			changedPths:  []string{"/src/Web/Pages/Index.cs", "/src/build/Common.targets"},
+			wantChanged:  all,
+			wantAffected: all,
+			wantUnowned:  []string{"/src/build/Common.targets"},
+			wantBuildAll: true,
+		},
+		{
+			name:         "unowned file outside of the solution dir",
+			changedPths:  []string{"/docs/README.md", "/src/Web/../Web/Web.csproj"},
+			wantChanged:  []string{"Web"},
+			wantAffected: []string{"Web"},
+			wantUnowned:  []string{"/docs/README.md"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			impact := solution.ChangeImpact(graph, tt.changedPths)
+
+			if tt.wantBuild == nil {
+				tt.wantBuild = []string{}
+			}
+			if tt.wantUnowned == nil {
+				tt.wantUnowned = []string{}
+			}
+
+			if !reflect.DeepEqual(impact.ChangedProjectIDs, tt.wantChanged) {
+				t.Errorf("ChangedProjectIDs = %v, want %v", impact.ChangedProjectIDs, tt.wantChanged)
+			}
+			if !reflect.DeepEqual(impact.AffectedProjectIDs, tt.wantAffected) {
+				t.Errorf("AffectedProjectIDs = %v, want %v", impact.AffectedProjectIDs, tt.wantAffected)
+			}
+			if !reflect.DeepEqual(impact.BuildFilePths, tt.wantBuild) {
+				t.Errorf("BuildFilePths = %v, want %v", impact.BuildFilePths, tt.wantBuild)
+			}
+			if !reflect.DeepEqual(impact.UnownedPths, tt.wantUnowned) {
+				t.Errorf("UnownedPths = %v, want %v", impact.UnownedPths, tt.wantUnowned)
+			}
+			if impact.BuildAll != tt.wantBuildAll {
+				t.Errorf("BuildAll = %v, want %v", impact.BuildAll, tt.wantBuildAll)
+			}
+		})
+	}
+}
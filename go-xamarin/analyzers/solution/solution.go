@@ -9,9 +9,9 @@ import (
 
 	"github.com/bitrise-io/go-utils/fileutil"
 	"github.com/bitrise-io/go-utils/pathutil"
-	"github.com/toggl/go-xamarin/analyzers/project"
-	"github.com/toggl/go-xamarin/constants"
-	"github.com/toggl/go-xamarin/utility"
+	"github.com/bitrise-steplib/steps-xamarin-archive/go-xamarin/analyzers/project"
+	"github.com/bitrise-steplib/steps-xamarin-archive/go-xamarin/constants"
+	"github.com/bitrise-steplib/steps-xamarin-archive/go-xamarin/utility"
 )
 
 const (
@@ -24,24 +24,68 @@ const (
 	projectConfigurationPlatformsSectionStartPattern = `GlobalSection\(ProjectConfigurationPlatforms\) = postSolution`
 	projectConfigurationPlatformsSectionEndPattern   = `EndGlobalSection`
 	projectConfigurationPlatformPattern              = `{(?P<project_id>.*)}.(?P<config>.*)\|(?P<platform>.*)\.Build.* = (?P<mapped_config>.*)\|(?P<mapped_platform>.*)`
+
+	nestedProjectsSectionStartPattern = `GlobalSection\(NestedProjects\) = preSolution`
+	nestedProjectsSectionEndPattern   = `EndGlobalSection`
+	nestedProjectPattern              = `{(?P<child_id>[^}]*)} = {(?P<parent_id>[^}]*)}`
+
+	solutionGUIDPattern = `SolutionGuid = {(?P<solution_id>[^}]*)}`
 )
 
+// FolderModel is a solution folder.
+type FolderModel struct {
+	ID       string
+	Name     string
+	ParentID string // ID of the containing solution folder, empty if the folder is at the solution root
+}
+
 // Model ...
 type Model struct {
 	Pth  string
 	Name string
-	ID   string
+	ID   string // SolutionGuid of the solution, empty if the solution does not define it
+
+	FilterPth string // Path of the solution filter (.slnf) restricting the projects, empty if no filter is used
 
 	ConfigMap map[string]string // Internal Configuartion|Platform - External Configuartion|Platform map
 
 	ProjectMap map[string]project.Model // Project ID - Project Model map
+	FolderMap  map[string]FolderModel   // Solution folder ID - Solution folder Model map
 }
 
-// New ...
+// New analyzes the given solution (.sln) or solution filter (.slnf).
 func New(pth string, loadProjects bool) (Model, error) {
+	if filepath.Ext(pth) == constants.SolutionFilterExt {
+		return analyzeSolutionFilter(pth, loadProjects)
+	}
 	return analyzeSolution(pth, loadProjects)
 }
 
+// BuildPth returns the path to build the solution with: the solution filter, if used, otherwise the solution.
+func (solution Model) BuildPth() string {
+	if solution.FilterPth != "" {
+		return solution.FilterPth
+	}
+	return solution.Pth
+}
+
+// FolderPth returns the slash separated path of the given solution folder, like: Apps/Mobile.
+func (solution Model) FolderPth(folderID string) string {
+	var names []string
+	visited := map[string]bool{}
+	for folderID != "" && !visited[folderID] {
+		visited[folderID] = true
+
+		folder, ok := solution.FolderMap[folderID]
+		if !ok {
+			break
+		}
+		names = append([]string{folder.Name}, names...)
+		folderID = folder.ParentID
+	}
+	return strings.Join(names, "/")
+}
+
 // ConfigList ...
 func (solution Model) ConfigList() []string {
 	configList := []string{}
@@ -66,10 +110,14 @@ func analyzeSolution(pth string, analyzeProjects bool) (Model, error) {
 		Name:       fileName,
 		ConfigMap:  map[string]string{},
 		ProjectMap: map[string]project.Model{},
+		FolderMap:  map[string]FolderModel{},
 	}
 
 	isSolutionConfigurationPlatformsSection := false
 	isProjectConfigurationPlatformsSection := false
+	isNestedProjectsSection := false
+
+	parentIDs := map[string]string{} // Project or solution folder ID - Solution folder ID map
 
 	solutionDir := filepath.Dir(absPth)
 
@@ -84,20 +132,26 @@ func analyzeSolution(pth string, analyzeProjects bool) (Model, error) {
 
 		// Projects
 		if matches := regexp.MustCompile(solutionProjectsPattern).FindStringSubmatch(line); len(matches) == 5 {
-			ID := strings.ToUpper(matches[1])
+			typeID := strings.ToUpper(matches[1])
 			projectName := matches[2]
 			projectID := strings.ToUpper(matches[4])
 			projectRelativePth := utility.FixWindowsPath(matches[3])
 			projectPth := filepath.Join(solutionDir, projectRelativePth)
 
-			if strings.HasSuffix(projectPth, constants.CSProjExt) ||
+			if typeID == constants.SolutionFolderTypeGUID {
+				solution.FolderMap[projectID] = FolderModel{
+					ID:   projectID,
+					Name: projectName,
+				}
+			} else if strings.HasSuffix(projectPth, constants.CSProjExt) ||
 				strings.HasSuffix(projectPth, constants.SHProjExt) ||
 				strings.HasSuffix(projectPth, constants.FSProjExt) {
 
 				project := project.Model{
-					ID:   projectID,
-					Name: projectName,
-					Pth:  projectPth,
+					ID:     projectID,
+					Name:   projectName,
+					Pth:    projectPth,
+					TypeID: typeID,
 
 					ConfigMap: map[string]string{},
 					Configs:   map[string]project.ConfigurationPlatformModel{},
@@ -105,8 +159,29 @@ func analyzeSolution(pth string, analyzeProjects bool) (Model, error) {
 				solution.ProjectMap[projectID] = project
 			}
 
-			solution.ID = ID
+			continue
+		}
 
+		if matches := regexp.MustCompile(solutionGUIDPattern).FindStringSubmatch(line); len(matches) == 2 {
+			solution.ID = strings.ToUpper(matches[1])
+			continue
+		}
+
+		// GlobalSection(NestedProjects) = preSolution
+		if isNestedProjectsSection {
+			if match := regexp.MustCompile(nestedProjectsSectionEndPattern).FindString(line); match != "" {
+				isNestedProjectsSection = false
+				continue
+			}
+
+			if matches := regexp.MustCompile(nestedProjectPattern).FindStringSubmatch(line); len(matches) == 3 {
+				parentIDs[strings.ToUpper(matches[1])] = strings.ToUpper(matches[2])
+				continue
+			}
+		}
+
+		if match := regexp.MustCompile(nestedProjectsSectionStartPattern).FindString(line); match != "" {
+			isNestedProjectsSection = true
 			continue
 		}
 
@@ -179,24 +254,48 @@ func analyzeSolution(pth string, analyzeProjects bool) (Model, error) {
 		return Model{}, err
 	}
 
+	for folderID, folder := range solution.FolderMap {
+		folder.ParentID = parentIDs[folderID]
+		solution.FolderMap[folderID] = folder
+	}
+
+	for projectID, proj := range solution.ProjectMap {
+		proj.SolutionFolder = solution.FolderPth(parentIDs[projectID])
+		solution.ProjectMap[projectID] = proj
+	}
+
 	if analyzeProjects {
-		projectMap := map[string]project.Model{}
+		return analyzeSolutionProjects(solution)
+	}
 
-		for projectID, proj := range solution.ProjectMap {
-			projectDefinition, err := project.New(proj.Pth)
-			if err != nil {
-				return Model{}, fmt.Errorf("failed to analyze project (%s), error: %s", proj.Pth, err)
-			}
+	return solution, nil
+}
 
-			projectDefinition.Name = proj.Name
-			projectDefinition.Pth = proj.Pth
-			projectDefinition.ConfigMap = proj.ConfigMap
+// analyzeSolutionProjects analyzes the project files of the solution's projects.
+func analyzeSolutionProjects(solution Model) (Model, error) {
+	solutionDir := filepath.Dir(solution.Pth)
+	projectMap := map[string]project.Model{}
 
-			projectMap[projectID] = projectDefinition
+	for projectID, proj := range solution.ProjectMap {
+		projectDefinition, err := project.NewInSolution(proj.Pth, solutionDir)
+		if err != nil {
+			return Model{}, fmt.Errorf("failed to analyze project (%s), error: %s", proj.Pth, err)
 		}
 
-		solution.ProjectMap = projectMap
+		if projectDefinition.ID == "" {
+			// SDK-style projects do not define ProjectGuid
+			projectDefinition.ID = projectID
+		}
+		projectDefinition.Name = proj.Name
+		projectDefinition.Pth = proj.Pth
+		projectDefinition.ConfigMap = proj.ConfigMap
+		projectDefinition.TypeID = proj.TypeID
+		projectDefinition.SolutionFolder = proj.SolutionFolder
+
+		projectMap[projectID] = projectDefinition
 	}
 
+	solution.ProjectMap = projectMap
+
 	return solution, nil
 }
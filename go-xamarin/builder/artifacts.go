@@ -0,0 +1,196 @@
+package builder
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/bitrise-io/go-utils/log"
+	"github.com/bitrise-io/go-utils/pathutil"
+	"github.com/bitrise-steplib/steps-xamarin-archive/go-xamarin/analyzers/project"
+	"github.com/bitrise-steplib/steps-xamarin-archive/go-xamarin/constants"
+)
+
+// ExpectedArtifactPaths returns the locations where the given output type of the project is expected to be created,
+// based on the project model. The most specific location comes first, locations may contain glob patterns.
+func ExpectedArtifactPaths(proj project.Model, projectConfig project.ConfigurationPlatformModel, outputType constants.OutputType) ([]string, error) {
+	outputDir := projectConfig.OutputDir
+
+	switch outputType {
+	case constants.OutputTypeAPK, constants.OutputTypeAAB:
+		packageName, err := projectAndroidPackageName(proj)
+		if err != nil {
+			return nil, err
+		}
+
+		ext := "." + string(outputType)
+		return []string{
+			filepath.Join(outputDir, packageName+"-Signed"+ext),
+			filepath.Join(outputDir, packageName+ext),
+		}, nil
+	case constants.OutputTypeAPP:
+		return []string{
+			filepath.Join(outputDir, proj.AssemblyName+".app"),
+		}, nil
+	case constants.OutputTypeDSYM:
+		return []string{
+			filepath.Join(outputDir, proj.AssemblyName+".app.dSYM"),
+			filepath.Join(outputDir, proj.AssemblyName+".appex.dSYM"),
+		}, nil
+	case constants.OutputTypeIPA:
+		ipaPackageDir := projectConfig.IpaPackageDir
+		if ipaPackageDir == "" {
+			ipaPackageDir = outputDir
+		}
+
+		ipaPackageName := projectConfig.IpaPackageName
+		if ipaPackageName == "" {
+			ipaPackageName = proj.AssemblyName + ".ipa"
+		}
+
+		return []string{
+			filepath.Join(ipaPackageDir, ipaPackageName),
+			// older Xamarin.iOS versions create the ipa in a timestamped sub directory
+			filepath.Join(ipaPackageDir, escapeGlob(proj.AssemblyName)+" *", escapeGlob(ipaPackageName)),
+		}, nil
+	case constants.OutputTypeXCArchive:
+		archivePth := projectConfig.ArchivePath
+		if strings.HasSuffix(archivePth, ".xcarchive") {
+			return []string{archivePth}, nil
+		}
+
+		archivesDir := archivePth
+		if archivesDir == "" {
+			dir, err := xcodeArchivesDir()
+			if err != nil {
+				return nil, err
+			}
+			archivesDir = filepath.Join(dir, "*")
+		}
+
+		return []string{
+			filepath.Join(archivesDir, escapeGlob(proj.AssemblyName)+" *.xcarchive"),
+		}, nil
+	case constants.OutputTypePKG:
+		return []string{
+			filepath.Join(outputDir, proj.AssemblyName+".pkg"),
+			filepath.Join(outputDir, escapeGlob(proj.AssemblyName)+"-*.pkg"),
+		}, nil
+	case constants.OutputTypeDLL:
+		return []string{
+			filepath.Join(outputDir, proj.AssemblyName+".dll"),
+		}, nil
+	default:
+		return nil, fmt.Errorf("unsupported output type: %s", outputType)
+	}
+}
+
+// resolveArtifactPath returns the first expected path that exists and was modified at or after the start time.
+// If a glob pattern matches more than one such path, the most recently modified one is returned.
+// Paths modified before the start time are left from an earlier build (e.g. restored from a cache), so they are skipped.
+func resolveArtifactPath(startTime time.Time, expectedPths ...string) (string, error) {
+	for _, pattern := range expectedPths {
+		matches := []string{pattern}
+		if exist, err := pathutil.IsPathExists(pattern); err != nil {
+			return "", err
+		} else if !exist {
+			matches, err = filepath.Glob(pattern)
+			if err != nil {
+				return "", fmt.Errorf("invalid artifact path pattern (%s), error: %s", pattern, err)
+			}
+		}
+
+		var lastModifiedPth string
+		var lastModTime time.Time
+		for _, pth := range matches {
+			info, err := os.Stat(pth)
+			if err != nil {
+				return "", err
+			}
+
+			if info.ModTime().Before(startTime) {
+				log.Debugf("%s was modified before the build (%v), skipping", pth, info.ModTime())
+				continue
+			}
+
+			if lastModifiedPth == "" || info.ModTime().After(lastModTime) {
+				lastModifiedPth = pth
+				lastModTime = info.ModTime()
+			}
+		}
+
+		if lastModifiedPth != "" {
+			return lastModifiedPth, nil
+		}
+	}
+
+	return "", nil
+}
+
+// resolveOutput returns the path of the given output type of the project. The artifact is looked up at the expected
+// locations first, the latest artifact created in the build time window is searched for only if none of them
+// exist with a modification time at or after the build start.
+func resolveOutput(proj project.Model, projectConfig project.ConfigurationPlatformModel, outputType constants.OutputType, startTime, endTime time.Time) (string, error) {
+	expectedPths, err := ExpectedArtifactPaths(proj, projectConfig, outputType)
+	if err != nil {
+		return "", err
+	}
+
+	pth, err := resolveArtifactPath(startTime, expectedPths...)
+	if err != nil {
+		return "", err
+	}
+	if pth != "" {
+		log.Debugf("%s found at expected location: %s", outputType, pth)
+		return pth, nil
+	}
+
+	log.Debugf("No %s created by the build found at the expected locations: %s, searching for the latest one modified during the build", outputType, strings.Join(expectedPths, ", "))
+
+	pth, err = findOutputInTimeWindow(proj, projectConfig, outputType, startTime, endTime)
+	if err != nil {
+		return "", err
+	}
+	if pth != "" {
+		log.Warnf("The %s of project (%s) is not at any of the expected locations: %s", outputType, proj.Name, strings.Join(expectedPths, ", "))
+		log.Warnf("Using the latest %s modified during the build: %s", outputType, pth)
+	}
+
+	return pth, nil
+}
+
+func findOutputInTimeWindow(proj project.Model, projectConfig project.ConfigurationPlatformModel, outputType constants.OutputType, startTime, endTime time.Time) (string, error) {
+	switch outputType {
+	case constants.OutputTypeAPK, constants.OutputTypeAAB:
+		packageName, err := projectAndroidPackageName(proj)
+		if err != nil {
+			return "", err
+		}
+
+		if outputType == constants.OutputTypeAAB {
+			return exportAab(projectConfig.OutputDir, packageName, startTime, endTime)
+		}
+		return exportApk(projectConfig.OutputDir, packageName, startTime, endTime)
+	case constants.OutputTypeAPP:
+		return exportApp(projectConfig.OutputDir, proj.AssemblyName, startTime, endTime)
+	case constants.OutputTypeDSYM:
+		return exportAppDSYM(projectConfig.OutputDir, proj.AssemblyName, startTime, endTime)
+	case constants.OutputTypeIPA:
+		return exportIpa(projectConfig.OutputDir, proj.AssemblyName, startTime, endTime)
+	case constants.OutputTypeXCArchive:
+		return exportLatestXCArchiveFromXcodeArchives(proj.AssemblyName, startTime, endTime)
+	case constants.OutputTypePKG:
+		return exportPKG(projectConfig.OutputDir, proj.AssemblyName, startTime, endTime)
+	case constants.OutputTypeDLL:
+		return exportDLL(projectConfig.OutputDir, proj.AssemblyName, startTime, endTime)
+	default:
+		return "", fmt.Errorf("unsupported output type: %s", outputType)
+	}
+}
+
+func escapeGlob(pth string) string {
+	replacer := strings.NewReplacer(`*`, `\*`, `?`, `\?`, `[`, `\[`)
+	return replacer.Replace(pth)
+}
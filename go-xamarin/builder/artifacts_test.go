@@ -0,0 +1,107 @@
+package builder
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestResolveArtifactPath(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "artifacts")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := os.RemoveAll(tmpDir); err != nil {
+			t.Log(err)
+		}
+	}()
+
+	startTime := time.Now().Add(-time.Minute)
+	stale := startTime.Add(-time.Hour)
+	fresh := startTime.Add(time.Second)
+
+	files := map[string]time.Time{
+		"stale/com.app-Signed.aab": stale,
+		"stale/com.app-Signed.apk": stale,
+		"stale/com.app.apk":        fresh,
+		"glob/App 1/App.ipa":       stale,
+		"glob/App 2/App.ipa":       fresh,
+		"glob/App 3/App.ipa":       fresh.Add(time.Second),
+		"fresh/App.ipa":            fresh,
+	}
+	for name, modTime := range files {
+		pth := filepath.Join(tmpDir, name)
+		if err := os.MkdirAll(filepath.Dir(pth), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(pth, []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(pth, modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name    string
+		pths    []string
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "stale expected path",
+			pths: []string{"stale/com.app-Signed.aab"},
+			want: "",
+		},
+		{
+			name: "stale most specific path falls through",
+			pths: []string{"stale/com.app-Signed.apk", "stale/com.app.apk"},
+			want: "stale/com.app.apk",
+		},
+		{
+			name: "fresh path",
+			pths: []string{"fresh/App.ipa"},
+			want: "fresh/App.ipa",
+		},
+		{
+			name: "latest fresh glob match",
+			pths: []string{"glob/App.ipa", "glob/App */App.ipa"},
+			want: "glob/App 3/App.ipa",
+		},
+		{
+			name: "missing path",
+			pths: []string{"missing/App.ipa"},
+			want: "",
+		},
+		{
+			name:    "invalid pattern",
+			pths:    []string{"glob/["},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var pths []string
+			for _, pth := range tt.pths {
+				pths = append(pths, filepath.Join(tmpDir, pth))
+			}
+
+			got, err := resolveArtifactPath(startTime, pths...)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("resolveArtifactPath() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			want := tt.want
+			if want != "" {
+				want = filepath.Join(tmpDir, want)
+			}
+			if got != want {
+				t.Errorf("resolveArtifactPath() = %q, want %q", got, want)
+			}
+		})
+	}
+}
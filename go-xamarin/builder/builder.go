@@ -1,32 +1,51 @@
 package builder
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
+	"sort"
 	"time"
 
 	"github.com/bitrise-io/go-utils/log"
 	"github.com/bitrise-io/go-utils/pathutil"
-	"github.com/toggl/go-xamarin/analyzers/project"
-	"github.com/toggl/go-xamarin/analyzers/solution"
-	"github.com/toggl/go-xamarin/constants"
-	"github.com/toggl/go-xamarin/tools"
-	"github.com/toggl/go-xamarin/tools/buildtools"
-	"github.com/toggl/go-xamarin/tools/nunit"
-	"github.com/toggl/go-xamarin/utility"
+	"github.com/bitrise-steplib/steps-xamarin-archive/go-xamarin/analyzers/project"
+	"github.com/bitrise-steplib/steps-xamarin-archive/go-xamarin/analyzers/solution"
+	"github.com/bitrise-steplib/steps-xamarin-archive/go-xamarin/constants"
+	"github.com/bitrise-steplib/steps-xamarin-archive/go-xamarin/tools"
+	"github.com/bitrise-steplib/steps-xamarin-archive/go-xamarin/tools/buildtools"
+	"github.com/bitrise-steplib/steps-xamarin-archive/go-xamarin/tools/buildtools/diagnostics"
+	"github.com/bitrise-steplib/steps-xamarin-archive/go-xamarin/tools/nunit"
+	"github.com/bitrise-steplib/steps-xamarin-archive/go-xamarin/utility"
 )
 
 // Model ...
 type Model struct {
-	solution solution.Model
+	solution       solution.Model
+	referenceGraph solution.GraphModel
 
 	projectTypeWhitelist []constants.SDK
 	buildTool            buildtools.BuildTool
 
 	outWriter io.Writer
 	errWriter io.Writer
+
+	commandResultCallback CommandResultCallback
+
+	parallelism int
+
+	ctx            context.Context
+	commandTimeout time.Duration
+
+	logs        *commandLogs
+	diagnostics *diagnostics.Collector
+
+	projectFilter      projectFilter
+	affectedProjectIDs map[string]bool // Projects affected by the changed files, nil if the build is not restricted
+
+	androidSigning *AndroidSigningModel // Overrides the signing configuration of the Android projects, if set
 }
 
 // SetOutputs ...
@@ -35,6 +54,42 @@ func (builder *Model) SetOutputs(outWriter, errWriter io.Writer) {
 	builder.errWriter = errWriter
 }
 
+// SetCommandResultCallback sets the callback to notify the caller about each finished command.
+func (builder *Model) SetCommandResultCallback(callback CommandResultCallback) {
+	builder.commandResultCallback = callback
+}
+
+// SetParallelism sets the number of build commands BuildAllProjects may run at the same time, 1 means serial build.
+// Commands building the whole solution never run at the same time with other commands,
+// commands building the same project run one after another.
+// When building in parallel, the output of each command is buffered and written once the command finished,
+// right after the build callback of the command is called.
+func (builder *Model) SetParallelism(workers int) {
+	builder.parallelism = workers
+}
+
+// SetContext sets the context of the build commands, when it is done the running command is killed.
+func (builder *Model) SetContext(ctx context.Context) {
+	builder.ctx = ctx
+}
+
+// SetCommandTimeout sets the time limit of each build command, 0 means no limit.
+// The process tree of a command running longer is killed.
+func (builder *Model) SetCommandTimeout(timeout time.Duration) {
+	builder.commandTimeout = timeout
+}
+
+// Solution returns the analyzed solution.
+func (builder Model) Solution() solution.Model {
+	return builder.solution
+}
+
+// SkippedProjectModel ...
+type SkippedProjectModel struct {
+	ProjectName string
+	Reason      string
+}
+
 // OutputModel ...
 type OutputModel struct {
 	Pth        string
@@ -50,6 +105,16 @@ type ProjectOutputModel struct {
 // ProjectOutputMap ...
 type ProjectOutputMap map[string]ProjectOutputModel // Project Name - ProjectOutputModel
 
+// ProjectNames returns the project names of the output map in ascending order.
+func (outputMap ProjectOutputMap) ProjectNames() []string {
+	projectNames := []string{}
+	for projectName := range outputMap {
+		projectNames = append(projectNames, projectName)
+	}
+	sort.Strings(projectNames)
+	return projectNames
+}
+
 // TestProjectOutputModel ...
 type TestProjectOutputModel struct {
 	TestFramwork         constants.TestFramework
@@ -66,6 +131,9 @@ type PrepareCommandCallback func(solutionName string, projectName string, sdk co
 // BuildCommandCallback ...
 type BuildCommandCallback func(solutionName string, projectName string, sdk constants.SDK, testFramework constants.TestFramework, commandStr string, alreadyPerformed bool)
 
+// CommandResultCallback ...
+type CommandResultCallback func(solutionName string, projectName string, sdk constants.SDK, testFramework constants.TestFramework, commandStr string, duration time.Duration, err error)
+
 // ClearCommandCallback ...
 type ClearCommandCallback func(project project.Model, dir string)
 
@@ -85,10 +153,13 @@ func New(solutionPth string, projectTypeWhitelist []constants.SDK, buildTool bui
 	}
 
 	return Model{
-		solution: solution,
+		solution:       solution,
+		referenceGraph: solution.ReferenceGraph(),
 
 		projectTypeWhitelist: projectTypeWhitelist,
 		buildTool:            buildTool,
+
+		diagnostics: diagnostics.NewCollector(),
 	}, nil
 }
 
@@ -150,7 +221,7 @@ func (builder Model) BuildSolution(configuration, platform string, callback Buil
 		callback(builder.solution.Name, "", constants.SDKUnknown, constants.TestFrameworkUnknown, buildCommand.String(), false)
 	}
 
-	return buildCommand.Run(builder.outWriter, builder.errWriter)
+	return builder.runCommand(buildCommand, "", constants.SDKUnknown, constants.TestFrameworkUnknown)
 }
 
 // BuildAllProjects ...
@@ -161,9 +232,17 @@ func (builder Model) BuildAllProjects(configuration, platform string, buildIpa b
 		return warnings, err
 	}
 
-	buildableProjects, warns := builder.buildableProjects(configuration, platform)
+	buildableProjects, skippedProjects := builder.buildableProjects(configuration, platform)
+	for _, skippedProject := range skippedProjects {
+		warnings = append(warnings, skippedProject.Reason)
+	}
 	if len(buildableProjects) == 0 {
-		return warns, fmt.Errorf("No project to build found")
+		return warnings, fmt.Errorf("No project to build found")
+	}
+
+	if builder.parallelism > 1 {
+		warns, err := builder.buildProjectsInParallel(configuration, platform, buildableProjects, buildIpa, prepareCallback, callback)
+		return append(warnings, warns...), err
 	}
 
 	perfomedCommands := []tools.Printable{}
@@ -194,7 +273,7 @@ func (builder Model) BuildAllProjects(configuration, platform string, buildIpa b
 			}
 
 			if !alreadyPerformed {
-				if err := buildCommand.Run(builder.outWriter, builder.errWriter); err != nil {
+				if err := builder.runCommand(buildCommand, proj.Name, proj.SDK, proj.TestFramework); err != nil {
 					return warnings, err
 				}
 				perfomedCommands = append(perfomedCommands, buildCommand)
@@ -250,7 +329,7 @@ func (builder Model) BuildAllUITestableXamarinProjects(configuration, platform s
 			}
 
 			if !alreadyPerformed {
-				if err := buildCommand.Run(builder.outWriter, builder.errWriter); err != nil {
+				if err := builder.runCommand(buildCommand, proj.Name, proj.SDK, proj.TestFramework); err != nil {
 					return warnings, err
 				}
 				perfomedCommands = append(perfomedCommands, buildCommand)
@@ -301,7 +380,7 @@ func (builder Model) RunAllXamarinUITests(configuration, platform string, prepar
 		}
 
 		if !alreadyPerformed {
-			if err := buildCommand.Run(builder.outWriter, builder.errWriter); err != nil {
+			if err := builder.runCommand(buildCommand, testProj.Name, testProj.SDK, testProj.TestFramework); err != nil {
 				return warnings, err
 			}
 			perfomedCommands = append(perfomedCommands, buildCommand)
@@ -374,7 +453,7 @@ func (builder Model) RunAllNunitTestProjects(configuration, platform string, cal
 		}
 
 		if !alreadyPerformed {
-			if err := buildCommand.Run(builder.outWriter, builder.errWriter); err != nil {
+			if err := builder.runCommand(buildCommand, testProj.Name, constants.SDKUnknown, constants.TestFrameworkNunitTest); err != nil {
 				return warnings, err
 			}
 			perfomedCommands = append(perfomedCommands, buildCommand)
@@ -393,6 +472,23 @@ func (builder Model) BuildAndRunAllNunitTestProjects(configuration, platform str
 	return builder.RunAllNunitTestProjects(configuration, platform, callback, prepareCallback)
 }
 
+// ReferenceGraph returns the project reference graph of the solution.
+func (builder Model) ReferenceGraph() solution.GraphModel {
+	return builder.referenceGraph
+}
+
+// BuildableProjects returns the projects which are built for the given configuration and platform.
+func (builder Model) BuildableProjects(configuration, platform string) []project.Model {
+	projects, _ := builder.buildableProjects(configuration, platform)
+	return projects
+}
+
+// SkippedProjects returns the projects which are not built for the given configuration and platform, with the reason of skipping.
+func (builder Model) SkippedProjects(configuration, platform string) []SkippedProjectModel {
+	_, skippedProjects := builder.buildableProjects(configuration, platform)
+	return skippedProjects
+}
+
 // CollectProjectOutputs ...
 func (builder Model) CollectProjectOutputs(configuration, platform string, startTime, endTime time.Time) (ProjectOutputMap, error) {
 	projectOutputMap := ProjectOutputMap{}
@@ -411,6 +507,7 @@ func (builder Model) CollectProjectOutputs(configuration, platform string, start
 		if !ok {
 			continue
 		}
+		projectConfig = builder.buildConfig(proj, projectConfig)
 
 		projectOutputs, ok := projectOutputMap[proj.Name]
 		if !ok {
@@ -420,102 +517,21 @@ func (builder Model) CollectProjectOutputs(configuration, platform string, start
 			}
 		}
 
-		switch proj.SDK {
-		case constants.SDKIOS, constants.SDKTvOS:
-			if IsDeviceArch(projectConfig.MtouchArchs...) {
-				if xcarchivePth, err := exportLatestXCArchiveFromXcodeArchives(proj.AssemblyName, startTime, endTime); err != nil {
-					return ProjectOutputMap{}, err
-				} else if xcarchivePth != "" {
-					projectOutputs.Outputs = append(projectOutputs.Outputs, OutputModel{
-						Pth:        xcarchivePth,
-						OutputType: constants.OutputTypeXCArchive,
-					})
-				} else {
-					log.Debugf("No valid xcarchive path found.")
-				}
-
-				if ipaPth, err := exportIpa(projectConfig.OutputDir, proj.AssemblyName, startTime, endTime); err != nil {
-					return ProjectOutputMap{}, err
-				} else if ipaPth != "" {
-					projectOutputs.Outputs = append(projectOutputs.Outputs, OutputModel{
-						Pth:        ipaPth,
-						OutputType: constants.OutputTypeIPA,
-					})
-				} else {
-					log.Debugf("No valid IPA path found.")
-				}
-
-				if dsymPth, err := exportAppDSYM(projectConfig.OutputDir, proj.AssemblyName, startTime, endTime); err != nil {
-					return ProjectOutputMap{}, err
-				} else if dsymPth != "" {
-					projectOutputs.Outputs = append(projectOutputs.Outputs, OutputModel{
-						Pth:        dsymPth,
-						OutputType: constants.OutputTypeDSYM,
-					})
-				} else {
-					log.Debugf("No valid dsym path found.")
-				}
-			}
-
-			if appPth, err := exportApp(projectConfig.OutputDir, proj.AssemblyName, startTime, endTime); err != nil {
-				return ProjectOutputMap{}, err
-			} else if appPth != "" {
-				projectOutputs.Outputs = append(projectOutputs.Outputs, OutputModel{
-					Pth:        appPth,
-					OutputType: constants.OutputTypeAPP,
-				})
-			} else {
-				log.Debugf("No valid app path found.")
-			}
-		case constants.SDKMacOS:
-			if appPth, err := exportApp(projectConfig.OutputDir, proj.AssemblyName, startTime, endTime); err != nil {
-				return ProjectOutputMap{}, err
-			} else if appPth != "" {
-				projectOutputs.Outputs = append(projectOutputs.Outputs, OutputModel{
-					Pth:        appPth,
-					OutputType: constants.OutputTypeAPP,
-				})
-			} else {
-				log.Debugf("No valid app path found.")
-			}
-
-			if pkgPth, err := exportPKG(projectConfig.OutputDir, proj.AssemblyName, startTime, endTime); err != nil {
-				return ProjectOutputMap{}, err
-			} else if pkgPth != "" {
-				projectOutputs.Outputs = append(projectOutputs.Outputs, OutputModel{
-					Pth:        pkgPth,
-					OutputType: constants.OutputTypePKG,
-				})
-			} else {
-				log.Debugf("No valid pkg path found.")
-			}
-		case constants.SDKAndroid:
-			packageName, err := androidPackageName(proj.ManifestPth)
+		for _, outputType := range expectedOutputTypes(proj, projectConfig) {
+			pth, err := resolveOutput(proj, projectConfig, outputType, startTime, endTime)
 			if err != nil {
-				return ProjectOutputMap{}, fmt.Errorf("could get package name from manifest file at %v. Error: %v", proj.ManifestPth, err)
+				return ProjectOutputMap{}, fmt.Errorf("could not export %s. Error: %v", outputType, err)
 			}
 
-			if apkPth, err := exportApk(projectConfig.OutputDir, packageName, startTime, endTime); err != nil {
-				return ProjectOutputMap{}, fmt.Errorf("could not export apk. Error: %v", err)
-			} else if apkPth != "" {
-				projectOutputs.Outputs = append(projectOutputs.Outputs, OutputModel{
-					Pth:        apkPth,
-					OutputType: constants.OutputTypeAPK,
-				})
-			} else {
-				log.Debugf("No valid apk path found.")
+			if pth == "" {
+				log.Debugf("No valid %s path found.", outputType)
+				continue
 			}
 
-			if aabPth, err := exportAab(projectConfig.OutputDir, packageName, startTime, endTime); err != nil {
-				return ProjectOutputMap{}, fmt.Errorf("could not export apk. Error: %v", err)
-			} else if aabPth != "" {
-				projectOutputs.Outputs = append(projectOutputs.Outputs, OutputModel{
-					Pth:        aabPth,
-					OutputType: constants.OutputTypeAAB,
-				})
-			} else {
-				log.Debugf("No valid aab path found.")
-			}
+			projectOutputs.Outputs = append(projectOutputs.Outputs, OutputModel{
+				Pth:        pth,
+				OutputType: outputType,
+			})
 		}
 
 		if len(projectOutputs.Outputs) > 0 {
@@ -546,7 +562,7 @@ func (builder Model) CollectXamarinUITestProjectOutputs(configuration, platform
 			continue
 		}
 
-		if dllPth, err := exportDLL(projectConfig.OutputDir, testProj.AssemblyName, startTime, endTime); err != nil {
+		if dllPth, err := resolveOutput(testProj, projectConfig, constants.OutputTypeDLL, startTime, endTime); err != nil {
 			return TestProjectOutputMap{}, warnings, err
 		} else if dllPth != "" {
 			referredProjectNames := []string{}
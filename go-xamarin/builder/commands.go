@@ -2,18 +2,23 @@ package builder
 
 import (
 	"fmt"
-
-	"github.com/toggl/go-xamarin/analyzers/project"
-	"github.com/toggl/go-xamarin/constants"
-	"github.com/toggl/go-xamarin/tools"
-	"github.com/toggl/go-xamarin/tools/buildtools"
-	"github.com/toggl/go-xamarin/tools/buildtools/msbuild"
-	"github.com/toggl/go-xamarin/tools/buildtools/xbuild"
-	"github.com/toggl/go-xamarin/tools/nunit"
-	"github.com/toggl/go-xamarin/utility"
+	"strings"
+
+	"github.com/bitrise-steplib/steps-xamarin-archive/go-xamarin/analyzers/project"
+	"github.com/bitrise-steplib/steps-xamarin-archive/go-xamarin/constants"
+	"github.com/bitrise-steplib/steps-xamarin-archive/go-xamarin/tools"
+	"github.com/bitrise-steplib/steps-xamarin-archive/go-xamarin/tools/buildtools"
+	"github.com/bitrise-steplib/steps-xamarin-archive/go-xamarin/tools/buildtools/msbuild"
+	"github.com/bitrise-steplib/steps-xamarin-archive/go-xamarin/tools/buildtools/xbuild"
+	"github.com/bitrise-steplib/steps-xamarin-archive/go-xamarin/tools/nunit"
+	"github.com/bitrise-steplib/steps-xamarin-archive/go-xamarin/utility"
 )
 
 func (builder Model) buildSolutionCommand(configuration, platform string) (tools.Runnable, error) {
+	if builder.buildTool == buildtools.Dotnet {
+		return builder.buildDotnetSolutionCommand(configuration, platform)
+	}
+
 	var buildCommand tools.Runnable
 
 	var command *xbuild.Model
@@ -29,6 +34,7 @@ func (builder Model) buildSolutionCommand(configuration, platform string) (tools
 		return nil, err
 	}
 
+	command.SetSolutionFilter(builder.solution.FilterPth)
 	command.SetTarget("Build")
 	command.SetConfiguration(configuration)
 	command.SetPlatform(platform)
@@ -52,6 +58,15 @@ func (builder Model) buildProjectCommand(configuration, platform string, proj pr
 		warnings = append(warnings, fmt.Sprintf("project (%s) contains mapping for solution config (%s), but does not have project configuration", proj.Name, solutionConfig))
 	}
 
+	if len(proj.IgnoredTargetFrameworks) > 0 {
+		warnings = append(warnings, fmt.Sprintf("project (%s) targets multiple platforms, only %s is built, ignored target frameworks: %s", proj.Name, proj.TargetFramework, strings.Join(proj.IgnoredTargetFrameworks, ", ")))
+	}
+
+	if builder.buildTool == buildtools.Dotnet {
+		buildCommands, err := builder.buildDotnetProjectCommand(proj, builder.buildConfig(proj, projectConfig), buildIpa)
+		return buildCommands, warnings, err
+	}
+
 	// Prepare build commands
 	buildCommands := []tools.Runnable{}
 
@@ -60,10 +75,16 @@ func (builder Model) buildProjectCommand(configuration, platform string, proj pr
 		var command *xbuild.Model
 		var err error
 
+		// SDK-style projects are built one by one, since the solution build would build every target framework
+		projectPth := ""
+		if proj.SDKStyle {
+			projectPth = proj.Pth
+		}
+
 		if builder.buildTool == buildtools.Msbuild {
-			command, err = msbuild.New(builder.solution.Pth, "")
+			command, err = msbuild.New(builder.solution.Pth, projectPth)
 		} else {
-			command, err = xbuild.New(builder.solution.Pth, "")
+			command, err = xbuild.New(builder.solution.Pth, projectPth)
 		}
 
 		if err != nil {
@@ -71,11 +92,19 @@ func (builder Model) buildProjectCommand(configuration, platform string, proj pr
 		}
 
 		command.SetTarget("Build")
-		command.SetConfiguration(configuration)
-		command.SetPlatform(platform)
+		if proj.SDKStyle {
+			command.SetConfiguration(projectConfig.Configuration)
+			if !isPlatformAnyCPU(projectConfig.Platform) {
+				command.SetPlatform(projectConfig.Platform)
+			}
+			command.SetTargetFramework(targetFrameworkOption(proj))
+		} else {
+			command.SetConfiguration(configuration)
+			command.SetPlatform(platform)
+		}
 		command.SetArchiveOnBuild(true)
 
-		if IsDeviceArch(projectConfig.MtouchArchs...) && buildIpa {
+		if isDeviceBuild(proj, projectConfig) && buildIpa {
 			command.SetBuildIpa(true)
 		}
 
@@ -84,10 +113,16 @@ func (builder Model) buildProjectCommand(configuration, platform string, proj pr
 		var command *xbuild.Model
 		var err error
 
+		// SDK-style projects are built one by one, since the solution build would build every target framework
+		projectPth := ""
+		if proj.SDKStyle {
+			projectPth = proj.Pth
+		}
+
 		if builder.buildTool == buildtools.Msbuild {
-			command, err = msbuild.New(builder.solution.Pth, "")
+			command, err = msbuild.New(builder.solution.Pth, projectPth)
 		} else {
-			command, err = xbuild.New(builder.solution.Pth, "")
+			command, err = xbuild.New(builder.solution.Pth, projectPth)
 		}
 
 		if err != nil {
@@ -95,8 +130,16 @@ func (builder Model) buildProjectCommand(configuration, platform string, proj pr
 		}
 
 		command.SetTarget("Build")
-		command.SetConfiguration(configuration)
-		command.SetPlatform(platform)
+		if proj.SDKStyle {
+			command.SetConfiguration(projectConfig.Configuration)
+			if !isPlatformAnyCPU(projectConfig.Platform) {
+				command.SetPlatform(projectConfig.Platform)
+			}
+			command.SetTargetFramework(targetFrameworkOption(proj))
+		} else {
+			command.SetConfiguration(configuration)
+			command.SetPlatform(platform)
+		}
 		command.SetArchiveOnBuild(true)
 
 		buildCommands = append(buildCommands, command)
@@ -114,7 +157,7 @@ func (builder Model) buildProjectCommand(configuration, platform string, proj pr
 			return []tools.Runnable{}, warnings, err
 		}
 
-		if projectConfig.SignAndroid {
+		if projectConfig.SignAndroid || builder.androidSigning != nil {
 			command.SetTarget("SignAndroidPackage")
 		} else {
 			command.SetTarget("PackageForAndroid")
@@ -126,6 +169,12 @@ func (builder Model) buildProjectCommand(configuration, platform string, proj pr
 			command.SetPlatform(projectConfig.Platform)
 		}
 
+		command.SetTargetFramework(targetFrameworkOption(proj))
+
+		if builder.androidSigning != nil {
+			builder.androidSigning.applyToXbuild(command)
+		}
+
 		buildCommands = append(buildCommands, command)
 	}
 
@@ -147,6 +196,11 @@ func (builder Model) buildXamarinUITestProjectCommand(configuration, platform st
 		warnings = append(warnings, fmt.Sprintf("project (%s) contains mapping for solution config (%s), but does not have project configuration", proj.Name, solutionConfig))
 	}
 
+	if builder.buildTool == buildtools.Dotnet {
+		command, err := builder.buildDotnetTestProjectCommand(proj, projectConfig)
+		return command, warnings, err
+	}
+
 	var command *xbuild.Model
 	var err error
 
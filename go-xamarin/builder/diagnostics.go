@@ -0,0 +1,75 @@
+package builder
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/bitrise-steplib/steps-xamarin-archive/go-xamarin/tools"
+	"github.com/bitrise-steplib/steps-xamarin-archive/go-xamarin/tools/buildtools/diagnostics"
+)
+
+// Diagnostics returns the errors and warnings parsed from the output of the performed commands.
+func (builder Model) Diagnostics() []diagnostics.Diagnostic {
+	if builder.diagnostics == nil {
+		return nil
+	}
+	return builder.diagnostics.Diagnostics()
+}
+
+// commandWriters returns the writers of the command's output: besides the given writers
+// the output is saved into the command's log file and parsed for diagnostics, the secrets are masked in all of them.
+// If the given writers are the same, the same writer is returned for both outputs, to keep the command's writes serialized.
+// The returned function has to be called once the command finished.
+func (builder Model) commandWriters(command tools.Runnable, projectName string, outWriter, errWriter io.Writer) (io.Writer, io.Writer, func()) {
+	if outWriter == nil {
+		outWriter = os.Stdout
+	}
+	if errWriter == nil {
+		errWriter = os.Stderr
+	}
+	combined := outWriter == errWriter
+
+	outWriter, errWriter, closeLog := builder.teeCommandLog(command, projectName, outWriter, errWriter)
+	closers := []func() error{}
+
+	if builder.diagnostics != nil {
+		if projectName == "" {
+			projectName = builder.solution.Name
+		}
+
+		// separate outputs are parsed separately, to not mix their lines
+		outDiagnostics := builder.diagnostics.Writer(projectName)
+		closers = append(closers, outDiagnostics.Close)
+		outWriter = io.MultiWriter(outWriter, outDiagnostics)
+
+		if combined {
+			errWriter = outWriter
+		} else {
+			errDiagnostics := builder.diagnostics.Writer(projectName)
+			closers = append(closers, errDiagnostics.Close)
+			errWriter = io.MultiWriter(errWriter, errDiagnostics)
+		}
+	}
+
+	outRedact := tools.NewRedactWriter(outWriter)
+	errRedact := outRedact
+	if !combined {
+		errRedact = tools.NewRedactWriter(errWriter)
+	}
+
+	// the redact writers flush before the writers they write into are closed
+	closers = append([]func() error{outRedact.Close}, closers...)
+	if !combined {
+		closers = append([]func() error{errRedact.Close}, closers...)
+	}
+
+	return outRedact, errRedact, func() {
+		for _, closer := range closers {
+			if err := closer(); err != nil {
+				fmt.Fprintf(os.Stderr, "failed to write command output, error: %s\n", err)
+			}
+		}
+		closeLog()
+	}
+}
@@ -0,0 +1,120 @@
+package builder
+
+import (
+	"path/filepath"
+
+	"github.com/bitrise-steplib/steps-xamarin-archive/go-xamarin/analyzers/project"
+	"github.com/bitrise-steplib/steps-xamarin-archive/go-xamarin/constants"
+	"github.com/bitrise-steplib/steps-xamarin-archive/go-xamarin/tools"
+	"github.com/bitrise-steplib/steps-xamarin-archive/go-xamarin/tools/buildtools"
+	"github.com/bitrise-steplib/steps-xamarin-archive/go-xamarin/tools/buildtools/dotnet"
+)
+
+func (builder Model) buildDotnetSolutionCommand(configuration, platform string) (tools.Runnable, error) {
+	command, err := dotnet.New(builder.solution.Pth, "")
+	if err != nil {
+		return nil, err
+	}
+
+	command.SetSolutionFilter(builder.solution.FilterPth)
+	command.SetCommand(dotnet.Build)
+	command.SetConfiguration(configuration)
+	command.SetPlatform(platform)
+
+	return command, nil
+}
+
+// buildDotnetProjectCommand creates the dotnet publish command of the project's SDK:
+// Apple projects are archived on build, the publish output of Android projects is the signed package.
+func (builder Model) buildDotnetProjectCommand(proj project.Model, projectConfig project.ConfigurationPlatformModel, buildIpa bool) ([]tools.Runnable, error) {
+	command, err := dotnet.New(builder.solution.Pth, proj.Pth)
+	if err != nil {
+		return []tools.Runnable{}, err
+	}
+
+	command.SetCommand(dotnet.Publish)
+	command.SetConfiguration(projectConfig.Configuration)
+	command.SetTargetFramework(targetFrameworkOption(proj))
+	command.SetRuntimeIdentifier(projectConfig.RuntimeIdentifier)
+
+	if !isPlatformAnyCPU(projectConfig.Platform) {
+		command.SetPlatform(projectConfig.Platform)
+	}
+
+	switch proj.SDK {
+	case constants.SDKIOS, constants.SDKTvOS:
+		command.SetProperty("ArchiveOnBuild", "true")
+
+		if buildIpa {
+			command.SetProperty("BuildIpa", "true")
+		}
+	case constants.SDKMacOS:
+		command.SetProperty("ArchiveOnBuild", "true")
+	case constants.SDKAndroid:
+		if builder.androidSigning != nil {
+			builder.androidSigning.applyToDotnet(command)
+		} else if projectConfig.SignAndroid {
+			command.SetProperty("AndroidKeyStore", "true")
+		}
+	default:
+		return []tools.Runnable{}, nil
+	}
+
+	return []tools.Runnable{command}, nil
+}
+
+func (builder Model) buildDotnetTestProjectCommand(proj project.Model, projectConfig project.ConfigurationPlatformModel) (tools.Runnable, error) {
+	command, err := dotnet.New(builder.solution.Pth, proj.Pth)
+	if err != nil {
+		return nil, err
+	}
+
+	command.SetCommand(dotnet.Build)
+	command.SetConfiguration(projectConfig.Configuration)
+	command.SetTargetFramework(targetFrameworkOption(proj))
+
+	return command, nil
+}
+
+// defaultDeviceRuntimeIdentifier returns the device runtime identifier of the Apple project, if it is built for devices.
+// Android and simulator builds are left to the defaults of the SDK: Android packages contain every default runtime.
+func defaultDeviceRuntimeIdentifier(proj project.Model, projectConfig project.ConfigurationPlatformModel) string {
+	if !proj.SDKStyle || !isDeviceBuild(proj, projectConfig) {
+		return ""
+	}
+
+	switch proj.SDK {
+	case constants.SDKIOS:
+		return "ios-arm64"
+	case constants.SDKTvOS:
+		return "tvos-arm64"
+	default:
+		return ""
+	}
+}
+
+// buildConfig returns the project configuration built by the build tool. The dotnet publish of an Apple device build
+// requires a runtime identifier, if the project does not define it, the device one is selected,
+// which adds the runtime identifier to the output dir, like bin/Release/net8.0-ios/ios-arm64/.
+func (builder Model) buildConfig(proj project.Model, projectConfig project.ConfigurationPlatformModel) project.ConfigurationPlatformModel {
+	if builder.buildTool != buildtools.Dotnet || projectConfig.RuntimeIdentifier != "" {
+		return projectConfig
+	}
+
+	runtimeIdentifier := defaultDeviceRuntimeIdentifier(proj, projectConfig)
+	if runtimeIdentifier == "" {
+		return projectConfig
+	}
+
+	projectConfig.RuntimeIdentifier = runtimeIdentifier
+	if projectConfig.OutputDir != "" {
+		defaultIpaPackageDir := filepath.Join(projectConfig.OutputDir, "publish")
+
+		projectConfig.OutputDir = filepath.Join(projectConfig.OutputDir, runtimeIdentifier)
+		if projectConfig.IpaPackageDir == defaultIpaPackageDir {
+			projectConfig.IpaPackageDir = filepath.Join(projectConfig.OutputDir, "publish")
+		}
+	}
+
+	return projectConfig
+}
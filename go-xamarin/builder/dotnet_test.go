@@ -0,0 +1,123 @@
+package builder
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bitrise-io/go-utils/command"
+	"github.com/bitrise-steplib/steps-xamarin-archive/go-xamarin/analyzers/project"
+	"github.com/bitrise-steplib/steps-xamarin-archive/go-xamarin/analyzers/solution"
+	"github.com/bitrise-steplib/steps-xamarin-archive/go-xamarin/constants"
+	"github.com/bitrise-steplib/steps-xamarin-archive/go-xamarin/tools"
+	"github.com/bitrise-steplib/steps-xamarin-archive/go-xamarin/tools/buildtools"
+	"github.com/bitrise-steplib/steps-xamarin-archive/go-xamarin/tools/toolpath"
+)
+
+func TestBuildDotnetProjectCommand(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "dotnet")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := os.RemoveAll(tmpDir); err != nil {
+			t.Log(err)
+		}
+	}()
+
+	dotnetPth := filepath.Join(tmpDir, "dotnet")
+	if err := ioutil.WriteFile(dotnetPth, []byte("#!/bin/sh\necho 8.0.100\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	toolpath.Override(toolpath.Dotnet, dotnetPth)
+	defer toolpath.Override(toolpath.Dotnet, "")
+
+	tests := []struct {
+		name     string
+		proj     project.Model
+		config   project.ConfigurationPlatformModel
+		signing  *AndroidSigningModel
+		buildIpa bool
+		want     []string // the command args after the project path, empty if no command is created
+	}{
+		{
+			name:     "iOS device build gets the device runtime identifier",
+			proj:     project.Model{Pth: "/src/App.iOS/App.iOS.csproj", SDK: constants.SDKIOS, SDKStyle: true},
+			config:   project.ConfigurationPlatformModel{Configuration: "Release", Platform: "iPhone"},
+			buildIpa: true,
+			want:     []string{"-c", "Release", "-r", "ios-arm64", "-p:SolutionDir=/src/", "-p:Platform=iPhone", "-p:ArchiveOnBuild=true", "-p:BuildIpa=true"},
+		},
+		{
+			name:   "iOS simulator build without ipa",
+			proj:   project.Model{Pth: "/src/App.iOS/App.iOS.csproj", SDK: constants.SDKIOS, SDKStyle: true},
+			config: project.ConfigurationPlatformModel{Configuration: "Debug", Platform: "iPhoneSimulator"},
+			want:   []string{"-c", "Debug", "-p:SolutionDir=/src/", "-p:Platform=iPhoneSimulator", "-p:ArchiveOnBuild=true"},
+		},
+		{
+			name:   "tvOS project runtime identifier is kept",
+			proj:   project.Model{Pth: "/src/App.tvOS/App.tvOS.csproj", SDK: constants.SDKTvOS, SDKStyle: true},
+			config: project.ConfigurationPlatformModel{Configuration: "Release", Platform: "AnyCPU", RuntimeIdentifier: "tvossimulator-arm64"},
+			want:   []string{"-c", "Release", "-r", "tvossimulator-arm64", "-p:SolutionDir=/src/", "-p:ArchiveOnBuild=true"},
+		},
+		{
+			name:   "macOS",
+			proj:   project.Model{Pth: "/src/App.Mac/App.Mac.csproj", SDK: constants.SDKMacOS, SDKStyle: true},
+			config: project.ConfigurationPlatformModel{Configuration: "Release", Platform: "Any CPU"},
+			want:   []string{"-c", "Release", "-p:SolutionDir=/src/", "-p:ArchiveOnBuild=true"},
+		},
+		{
+			name: "multi-platform Android project with the project's signing",
+			proj: project.Model{
+				Pth: "/src/App/App.csproj", SDK: constants.SDKAndroid, SDKStyle: true,
+				TargetFramework: "net8.0-android", TargetFrameworks: []string{"net8.0-android", "net8.0-ios"},
+			},
+			config: project.ConfigurationPlatformModel{Configuration: "Release", Platform: "AnyCPU", SignAndroid: true},
+			want:   []string{"-f", "net8.0-android", "-c", "Release", "-p:SolutionDir=/src/", "-p:AndroidKeyStore=true"},
+		},
+		{
+			name:    "Android signing inputs override the project's signing",
+			proj:    project.Model{Pth: "/src/App.Droid/App.Droid.csproj", SDK: constants.SDKAndroid, SDKStyle: true},
+			config:  project.ConfigurationPlatformModel{Configuration: "Release", Platform: "AnyCPU"},
+			signing: &AndroidSigningModel{KeystorePth: "/keys/release.jks", KeystorePassword: "store-password", KeyAlias: "release", KeyPassword: "key-password"},
+			want: []string{"-c", "Release", "-p:SolutionDir=/src/",
+				"-p:AndroidKeyStore=true", "-p:AndroidSigningKeyStore=/keys/release.jks", "-p:AndroidSigningStorePass=" + tools.SecretMask,
+				"-p:AndroidSigningKeyAlias=release", "-p:AndroidSigningKeyPass=" + tools.SecretMask},
+		},
+		{
+			name:   "unknown project type",
+			proj:   project.Model{Pth: "/src/Lib/Lib.csproj", SDK: constants.SDKUnknown, SDKStyle: true},
+			config: project.ConfigurationPlatformModel{Configuration: "Release", Platform: "AnyCPU"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			builder := Model{
+				solution:       solution.Model{Pth: "/src/App.sln"},
+				buildTool:      buildtools.Dotnet,
+				androidSigning: tt.signing,
+			}
+
+			commands, err := builder.buildDotnetProjectCommand(tt.proj, builder.buildConfig(tt.proj, tt.config), tt.buildIpa)
+			if err != nil {
+				t.Fatalf("buildDotnetProjectCommand() error = %s", err)
+			}
+
+			if tt.want == nil {
+				if len(commands) != 0 {
+					t.Errorf("buildDotnetProjectCommand() = %v, want no command", commands)
+				}
+				return
+			}
+			if len(commands) != 1 {
+				t.Fatalf("buildDotnetProjectCommand() returned %d commands, want 1", len(commands))
+			}
+
+			want := command.PrintableCommandArgs(true, append([]string{dotnetPth, "publish", tt.proj.Pth}, tt.want...))
+			if got := commands[0].String(); got != want {
+				t.Errorf("buildDotnetProjectCommand() =\n%s\nwant\n%s", got, want)
+			}
+		})
+	}
+}
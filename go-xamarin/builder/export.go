@@ -5,6 +5,7 @@ import (
 	"os"
 	"path/filepath"
 	"regexp"
+	"strings"
 	"time"
 
 	"github.com/bitrise-io/go-utils/log"
@@ -20,10 +21,10 @@ func findModTimesByPath(dir string, excludeDir bool) (ModTimesByPath, error) {
 	modTimesByPath := ModTimesByPath{}
 
 	if walkErr := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
-		log.Debugf("Walking for path: %s, modtime %v", path, info.ModTime())
 		if err != nil {
 			return err
 		}
+		log.Debugf("Walking for path: %s, modtime %v", path, info.ModTime())
 
 		if excludeDir && info.IsDir() {
 			return nil
@@ -162,12 +163,31 @@ func exportXCArchive(outputDir, assemblyName string, startTime, endTime time.Tim
 	)
 }
 
-func exportLatestXCArchiveFromXcodeArchives(assemblyName string, startTime, endTime time.Time) (string, error) {
+func xcodeArchivesDir() (string, error) {
 	userHomeDir, ok := os.LookupEnv("HOME")
 	if !ok {
 		return "", fmt.Errorf("failed to get user home dir")
 	}
-	xcodeArchivesDir := filepath.Join(userHomeDir, "Library/Developer/Xcode/Archives")
+	return filepath.Join(userHomeDir, "Library/Developer/Xcode/Archives"), nil
+}
+
+// IsXcodeArchivesPath returns true if the path is in the default archives dir of Xcode. The xcarchives found there
+// are attributed to a project by their name and creation time, so they may belong to another project.
+func IsXcodeArchivesPath(pth string) bool {
+	archivesDir, err := xcodeArchivesDir()
+	if err != nil {
+		return false
+	}
+
+	rel, err := filepath.Rel(archivesDir, pth)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+func exportLatestXCArchiveFromXcodeArchives(assemblyName string, startTime, endTime time.Time) (string, error) {
+	xcodeArchivesDir, err := xcodeArchivesDir()
+	if err != nil {
+		return "", err
+	}
 	if exist, err := pathutil.IsDirExists(xcodeArchivesDir); err != nil {
 		return "", err
 	} else if !exist {
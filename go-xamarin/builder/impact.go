@@ -0,0 +1,27 @@
+package builder
+
+import (
+	"github.com/bitrise-steplib/steps-xamarin-archive/go-xamarin/analyzers/solution"
+)
+
+// RestrictToChangedFiles restricts the projects to build to the ones affected by the given changed files:
+// the projects owning a changed file and the projects referring them, directly or transitively.
+// The changed file paths have to be absolute.
+func (builder *Model) RestrictToChangedFiles(changedPths []string) solution.ChangeImpactModel {
+	impact := builder.solution.ChangeImpact(builder.referenceGraph, changedPths)
+
+	builder.affectedProjectIDs = map[string]bool{}
+	for _, projectID := range impact.AffectedProjectIDs {
+		builder.affectedProjectIDs[projectID] = true
+	}
+
+	return impact
+}
+
+// isAffected returns if the project is affected by the changed files, every project is affected without a restriction.
+func (builder Model) isAffected(projectID string) bool {
+	if builder.affectedProjectIDs == nil {
+		return true
+	}
+	return builder.affectedProjectIDs[projectID]
+}
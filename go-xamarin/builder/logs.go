@@ -0,0 +1,109 @@
+package builder
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync"
+
+	"github.com/bitrise-steplib/steps-xamarin-archive/go-xamarin/tools"
+	"github.com/bitrise-steplib/steps-xamarin-archive/go-xamarin/tools/buildtools/dotnet"
+	"github.com/bitrise-steplib/steps-xamarin-archive/go-xamarin/tools/buildtools/xbuild"
+	"github.com/bitrise-steplib/steps-xamarin-archive/go-xamarin/tools/nunit"
+)
+
+var logFileNameUnsafeCharRegexp = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// commandLogs creates a log file for each build command in the log dir.
+type commandLogs struct {
+	dir string
+
+	mutex sync.Mutex
+	names map[string]bool
+}
+
+// SetLogDir sets the dir, where the output of each command is saved into a separate file
+// (besides writing it to the outputs), named after the project and the command's target: <project>-<target>.log.
+func (builder *Model) SetLogDir(dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create log dir (%s), error: %s", dir, err)
+	}
+
+	builder.logs = &commandLogs{
+		dir:   dir,
+		names: map[string]bool{},
+	}
+	return nil
+}
+
+// create returns the log file of the given command, the file name is made unique by a numeric suffix.
+func (logs *commandLogs) create(projectName, target string) (*os.File, error) {
+	logs.mutex.Lock()
+	defer logs.mutex.Unlock()
+
+	base := logFileNameUnsafeCharRegexp.ReplaceAllString(projectName+"-"+target, "_")
+	name := base + ".log"
+	for i := 2; logs.names[name]; i++ {
+		name = fmt.Sprintf("%s-%d.log", base, i)
+	}
+	logs.names[name] = true
+
+	return os.Create(filepath.Join(logs.dir, name))
+}
+
+// teeCommandLog returns the writers duplicating the output into the command's log file,
+// and the function closing the log file. Without a log dir the writers are returned as they are.
+func (builder Model) teeCommandLog(command tools.Runnable, projectName string, outWriter, errWriter io.Writer) (io.Writer, io.Writer, func()) {
+	if builder.logs == nil {
+		return outWriter, errWriter, func() {}
+	}
+
+	if projectName == "" {
+		projectName = builder.solution.Name
+	}
+
+	logFile, err := builder.logs.create(projectName, commandTarget(command))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to create log file of project (%s), error: %s\n", projectName, err)
+		return outWriter, errWriter, func() {}
+	}
+
+	if _, err := fmt.Fprintf(logFile, "$ %s\n\n", command.String()); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to write log file (%s), error: %s\n", logFile.Name(), err)
+	}
+
+	// the same writer is used for both outputs, if they are the same, to keep the command's writes serialized
+	logOutWriter := io.MultiWriter(outWriter, logFile)
+	logErrWriter := logOutWriter
+	if errWriter != outWriter {
+		logErrWriter = io.MultiWriter(errWriter, logFile)
+	}
+
+	return logOutWriter, logErrWriter, func() {
+		if err := logFile.Close(); err != nil {
+			fmt.Fprintf(os.Stderr, "failed to close log file (%s), error: %s\n", logFile.Name(), err)
+		}
+	}
+}
+
+// commandTarget returns the name of the command's target, used to name its log file.
+func commandTarget(command tools.Runnable) string {
+	switch cmd := command.(type) {
+	case *xbuild.Model:
+		if target := cmd.Target(); target != "" {
+			return target
+		}
+		return "Build"
+	case *dotnet.Model:
+		if target := cmd.Target(); target != "" {
+			return target
+		}
+		return string(cmd.Command())
+	case *nunit.Model:
+		return "test"
+	default:
+		return "command"
+	}
+}
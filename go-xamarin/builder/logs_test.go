@@ -0,0 +1,151 @@
+package builder
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/bitrise-steplib/steps-xamarin-archive/go-xamarin/analyzers/solution"
+	"github.com/bitrise-steplib/steps-xamarin-archive/go-xamarin/tools"
+	"github.com/bitrise-steplib/steps-xamarin-archive/go-xamarin/tools/buildtools/dotnet"
+)
+
+func TestTeeCommandLog(t *testing.T) {
+	publish := &dotnet.Model{BuildTool: "dotnet", ProjectPth: "/src/App/App.csproj"}
+	publish.SetCommand(dotnet.Publish)
+
+	type run struct {
+		command     tools.Runnable
+		projectName string
+	}
+
+	tests := []struct {
+		name     string
+		runs     []run
+		combined bool
+		wantLogs map[string]string // Log file name - content
+	}{
+		{
+			name:     "combined outputs",
+			runs:     []run{{command: &fakeCommand{name: "build App"}, projectName: "App"}},
+			combined: true,
+			wantLogs: map[string]string{"App-command.log": "$ build App\n\nout\nerr\n"},
+		},
+		{
+			name:     "separate outputs",
+			runs:     []run{{command: &fakeCommand{name: "build App"}, projectName: "App"}},
+			wantLogs: map[string]string{"App-command.log": "$ build App\n\nout\nerr\n"},
+		},
+		{
+			name: "log file names are unique",
+			runs: []run{
+				{command: &fakeCommand{name: "build App"}, projectName: "App"},
+				{command: &fakeCommand{name: "sign App"}, projectName: "App"},
+			},
+			combined: true,
+			wantLogs: map[string]string{
+				"App-command.log":   "$ build App\n\nout\nerr\n",
+				"App-command-2.log": "$ sign App\n\nout\nerr\n",
+			},
+		},
+		{
+			name: "log file names are named after the target",
+			runs: []run{
+				{command: publish, projectName: "My App/iOS"},
+				{command: &fakeCommand{name: "build solution"}},
+			},
+			combined: true,
+			wantLogs: map[string]string{
+				"My_App_iOS-publish.log": "$ " + publish.String() + "\n\nout\nerr\n",
+				"App-command.log":        "$ build solution\n\nout\nerr\n",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logDir, err := ioutil.TempDir("", "logs")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer func() {
+				if err := os.RemoveAll(logDir); err != nil {
+					t.Log(err)
+				}
+			}()
+
+			builder := Model{solution: solution.Model{Name: "App"}}
+			if err := builder.SetLogDir(logDir); err != nil {
+				t.Fatal(err)
+			}
+
+			for _, run := range tt.runs {
+				var outBuf, errBuf bytes.Buffer
+				var outWriter, errWriter io.Writer = &outBuf, &errBuf
+				if tt.combined {
+					errWriter = outWriter
+				}
+
+				logOutWriter, logErrWriter, closeLog := builder.teeCommandLog(run.command, run.projectName, outWriter, errWriter)
+				if tt.combined && logOutWriter != logErrWriter {
+					t.Errorf("teeCommandLog() returned different writers for the combined outputs")
+				}
+				if _, err := io.WriteString(logOutWriter, "out\n"); err != nil {
+					t.Fatal(err)
+				}
+				if _, err := io.WriteString(logErrWriter, "err\n"); err != nil {
+					t.Fatal(err)
+				}
+				closeLog()
+
+				if tt.combined {
+					if got := outBuf.String(); got != "out\nerr\n" {
+						t.Errorf("output = %q, want %q", got, "out\nerr\n")
+					}
+				} else if outBuf.String() != "out\n" || errBuf.String() != "err\n" {
+					t.Errorf("outputs = %q, %q, want %q, %q", outBuf.String(), errBuf.String(), "out\n", "err\n")
+				}
+			}
+
+			infos, err := ioutil.ReadDir(logDir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var names, wantNames []string
+			for _, info := range infos {
+				names = append(names, info.Name())
+			}
+			for name, want := range tt.wantLogs {
+				wantNames = append(wantNames, name)
+
+				content, err := ioutil.ReadFile(filepath.Join(logDir, name))
+				if err != nil {
+					t.Errorf("log file (%s) not found: %s", name, err)
+					continue
+				}
+				if string(content) != want {
+					t.Errorf("log file (%s) = %q, want %q", name, content, want)
+				}
+			}
+			sort.Strings(wantNames)
+			if !reflect.DeepEqual(names, wantNames) {
+				t.Errorf("log files = %v, want %v", names, wantNames)
+			}
+		})
+	}
+}
+
+func TestTeeCommandLogWithoutLogDir(t *testing.T) {
+	var outBuf, errBuf bytes.Buffer
+	outWriter, errWriter, closeLog := Model{}.teeCommandLog(&fakeCommand{name: "build"}, "App", &outBuf, &errBuf)
+	defer closeLog()
+
+	if outWriter != &outBuf || errWriter != &errBuf {
+		t.Errorf("teeCommandLog() without log dir changed the writers")
+	}
+}
@@ -0,0 +1,209 @@
+package builder
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/bitrise-io/go-utils/log"
+	"github.com/bitrise-steplib/steps-xamarin-archive/go-xamarin/analyzers/project"
+	"github.com/bitrise-steplib/steps-xamarin-archive/go-xamarin/tools"
+	"github.com/bitrise-steplib/steps-xamarin-archive/go-xamarin/tools/buildtools/dotnet"
+	"github.com/bitrise-steplib/steps-xamarin-archive/go-xamarin/tools/buildtools/xbuild"
+)
+
+// solutionResource is the resource of commands building the whole solution, it conflicts with every other command.
+const solutionResource = ""
+
+// buildJob is a build command with the project path it builds.
+type buildJob struct {
+	proj      project.Model
+	command   tools.Runnable
+	resources []string
+}
+
+type buildJobResult struct {
+	index    int
+	output   *bytes.Buffer
+	duration time.Duration
+	err      error
+}
+
+func (builder Model) buildProjectsInParallel(configuration, platform string, projects []project.Model, buildIpa bool, prepareCallback PrepareCommandCallback, callback BuildCommandCallback) ([]string, error) {
+	warnings := []string{}
+
+	jobs := []buildJob{}
+	perfomedCommands := []tools.Printable{}
+
+	for _, proj := range projects {
+		buildCommands, warns, err := builder.buildProjectCommand(configuration, platform, proj, buildIpa)
+		warnings = append(warnings, warns...)
+		if err != nil {
+			return warnings, fmt.Errorf("Failed to create build command, error: %s", err)
+		}
+
+		for _, buildCommand := range buildCommands {
+			// Callback to let the caller to modify the command
+			if prepareCallback != nil {
+				editabeCommand := tools.Editable(buildCommand)
+				prepareCallback(builder.solution.Name, proj.Name, proj.SDK, proj.TestFramework, &editabeCommand)
+			}
+
+			if tools.PrintableSliceContains(perfomedCommands, buildCommand) {
+				if callback != nil {
+					callback(builder.solution.Name, proj.Name, proj.SDK, proj.TestFramework, buildCommand.String(), true)
+				}
+				continue
+			}
+			perfomedCommands = append(perfomedCommands, buildCommand)
+
+			jobs = append(jobs, buildJob{
+				proj:      proj,
+				command:   buildCommand,
+				resources: builder.commandResources(buildCommand),
+			})
+		}
+	}
+
+	return warnings, builder.runJobs(jobs, callback)
+}
+
+// runJobs runs the jobs with at most builder.parallelism workers, jobs with common resources run one after another,
+// in the given order. After a failure no new job is started, the running ones are canceled.
+func (builder Model) runJobs(jobs []buildJob, callback BuildCommandCallback) error {
+	ctx, cancel := context.WithCancel(builder.context())
+	defer cancel()
+
+	results := make(chan buildJobResult)
+	busy := map[string]bool{}
+
+	pending := make([]int, len(jobs))
+	for i := range jobs {
+		pending[i] = i
+	}
+
+	running := 0
+	var firstErr error
+
+	for {
+		if firstErr == nil {
+			// resources of the jobs waiting ahead are reserved to keep the order of the conflicting jobs
+			reserved := map[string]bool{}
+			waiting := []int{}
+
+			for _, index := range pending {
+				job := jobs[index]
+				if running >= builder.parallelism || conflicts(busy, job.resources) || conflicts(reserved, job.resources) {
+					for _, resource := range job.resources {
+						reserved[resource] = true
+					}
+					waiting = append(waiting, index)
+					continue
+				}
+
+				for _, resource := range job.resources {
+					busy[resource] = true
+				}
+				running++
+
+				if callback != nil {
+					callback(builder.solution.Name, job.proj.Name, job.proj.SDK, job.proj.TestFramework, job.command.String(), false)
+				}
+
+				go func(index int, job buildJob) {
+					output := &bytes.Buffer{}
+					outWriter, errWriter, closeOutputs := builder.commandWriters(job.command, job.proj.Name, output, output)
+
+					startTime := time.Now()
+					err := builder.runCommandWithContext(ctx, job.command, outWriter, errWriter)
+					duration := time.Since(startTime)
+
+					closeOutputs()
+					results <- buildJobResult{index: index, output: output, duration: duration, err: err}
+				}(index, job)
+			}
+			pending = waiting
+		}
+
+		if running == 0 {
+			break
+		}
+
+		result := <-results
+		running--
+
+		job := jobs[result.index]
+		for _, resource := range job.resources {
+			delete(busy, resource)
+		}
+
+		builder.flushJob(job, result)
+
+		if result.err != nil && firstErr == nil {
+			firstErr = result.err
+			cancel()
+		}
+	}
+
+	return firstErr
+}
+
+// flushJob writes the buffered output of the finished job and notifies the caller about its result.
+// The caller is notified about the start of the job (by the build command callback) when the job is dispatched.
+func (builder Model) flushJob(job buildJob, result buildJobResult) {
+	proj := job.proj
+
+	status := "succeeded"
+	if result.err != nil {
+		status = "failed"
+	}
+	var outWriter io.Writer = os.Stdout
+	if builder.outWriter != nil {
+		outWriter = builder.outWriter
+	}
+
+	fmt.Fprintln(outWriter)
+	log.Infof("Build of project %s %s in %s, output:", proj.Name, status, result.duration.Round(time.Second))
+	if _, err := result.output.WriteTo(outWriter); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to write the output of project (%s), error: %s\n", proj.Name, err)
+	}
+
+	if builder.commandResultCallback != nil {
+		builder.commandResultCallback(builder.solution.Name, proj.Name, proj.SDK, proj.TestFramework, job.command.String(), result.duration, result.err)
+	}
+}
+
+// commandResources returns the project path the command builds, commands building the solution return the solution resource.
+// Referenced projects are not reserved: apps sharing a library build at the same time.
+func (builder Model) commandResources(command tools.Runnable) []string {
+	projectPth := ""
+	switch cmd := command.(type) {
+	case *xbuild.Model:
+		projectPth = cmd.ProjectPth
+	case *dotnet.Model:
+		projectPth = cmd.ProjectPth
+	}
+
+	if projectPth == "" {
+		return []string{solutionResource}
+	}
+	return []string{projectPth}
+}
+
+func conflicts(used map[string]bool, resources []string) bool {
+	if used[solutionResource] {
+		return true
+	}
+	for _, resource := range resources {
+		if resource == solutionResource && len(used) > 0 {
+			return true
+		}
+		if used[resource] {
+			return true
+		}
+	}
+	return false
+}
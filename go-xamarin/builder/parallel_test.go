@@ -0,0 +1,253 @@
+package builder
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bitrise-steplib/steps-xamarin-archive/go-xamarin/analyzers/project"
+	"github.com/bitrise-steplib/steps-xamarin-archive/go-xamarin/constants"
+	"github.com/bitrise-steplib/steps-xamarin-archive/go-xamarin/tools/buildtools/dotnet"
+)
+
+// jobRecorder records the start and the end of the fake commands.
+type jobRecorder struct {
+	mutex      sync.Mutex
+	running    map[string]bool
+	maxRunning int
+	overlaps   map[string][]string
+	startedCh  map[string]chan struct{}
+}
+
+func newJobRecorder() *jobRecorder {
+	return &jobRecorder{
+		running:   map[string]bool{},
+		overlaps:  map[string][]string{},
+		startedCh: map[string]chan struct{}{},
+	}
+}
+
+func (recorder *jobRecorder) startedChannel(name string) chan struct{} {
+	recorder.mutex.Lock()
+	defer recorder.mutex.Unlock()
+
+	if _, ok := recorder.startedCh[name]; !ok {
+		recorder.startedCh[name] = make(chan struct{})
+	}
+	return recorder.startedCh[name]
+}
+
+func (recorder *jobRecorder) start(name string) {
+	started := recorder.startedChannel(name)
+
+	recorder.mutex.Lock()
+	defer recorder.mutex.Unlock()
+
+	for other := range recorder.running {
+		recorder.overlaps[name] = append(recorder.overlaps[name], other)
+		recorder.overlaps[other] = append(recorder.overlaps[other], name)
+	}
+	recorder.running[name] = true
+	if len(recorder.running) > recorder.maxRunning {
+		recorder.maxRunning = len(recorder.running)
+	}
+	close(started)
+}
+
+func (recorder *jobRecorder) finish(name string) {
+	recorder.mutex.Lock()
+	defer recorder.mutex.Unlock()
+
+	delete(recorder.running, name)
+}
+
+// fakeCommand is a tools.Runnable recording its run into the recorder.
+type fakeCommand struct {
+	name     string
+	recorder *jobRecorder
+	duration time.Duration
+	waitFor  string // the command runs until the named command started
+	fail     bool
+	block    bool // the command runs until it is canceled
+}
+
+func (cmd *fakeCommand) String() string                     { return cmd.name }
+func (cmd *fakeCommand) SetCustomOptions(options ...string) {}
+func (cmd *fakeCommand) Run(outWriter, errWriter io.Writer) error {
+	return cmd.RunWithContext(context.Background(), outWriter, errWriter)
+}
+
+func (cmd *fakeCommand) RunWithContext(ctx context.Context, outWriter, errWriter io.Writer) error {
+	cmd.recorder.start(cmd.name)
+	defer cmd.recorder.finish(cmd.name)
+
+	if cmd.waitFor != "" {
+		select {
+		case <-cmd.recorder.startedChannel(cmd.waitFor):
+		case <-time.After(time.Second):
+			return errors.New(cmd.name + " did not run at the same time with " + cmd.waitFor)
+		}
+	}
+	if cmd.block {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+
+	time.Sleep(cmd.duration)
+	if cmd.fail {
+		return errors.New(cmd.name + " failed")
+	}
+	return nil
+}
+
+func TestRunJobs(t *testing.T) {
+	tests := []struct {
+		name           string
+		parallelism    int
+		jobs           []fakeCommand
+		resources      [][]string
+		wantDispatched []string
+		wantMax        int
+		wantErr        string
+	}{
+		{
+			name:           "serial build keeps the order",
+			parallelism:    1,
+			jobs:           []fakeCommand{{name: "A"}, {name: "B"}, {name: "C"}},
+			resources:      [][]string{{"A.csproj"}, {"B.csproj"}, {"C.csproj"}},
+			wantDispatched: []string{"A", "B", "C"},
+			wantMax:        1,
+		},
+		{
+			name:        "worker limit",
+			parallelism: 2,
+			jobs: []fakeCommand{
+				{name: "A", duration: 20 * time.Millisecond},
+				{name: "B", duration: 20 * time.Millisecond},
+				{name: "C", duration: 20 * time.Millisecond},
+				{name: "D", duration: 20 * time.Millisecond},
+			},
+			resources:      [][]string{{"A.csproj"}, {"B.csproj"}, {"C.csproj"}, {"D.csproj"}},
+			wantDispatched: []string{"A", "B", "C", "D"},
+			wantMax:        2,
+		},
+		{
+			name:        "commands of the same project run one after another in order",
+			parallelism: 3,
+			jobs: []fakeCommand{
+				{name: "A build", duration: 20 * time.Millisecond},
+				{name: "A sign"},
+				{name: "B", waitFor: "A build"},
+			},
+			resources:      [][]string{{"A.csproj"}, {"A.csproj"}, {"B.csproj"}},
+			wantDispatched: []string{"A build", "B", "A sign"},
+			wantMax:        2,
+		},
+		{
+			name:        "solution build runs alone",
+			parallelism: 3,
+			jobs: []fakeCommand{
+				{name: "A", duration: 20 * time.Millisecond},
+				{name: "solution"},
+				{name: "B"},
+			},
+			resources:      [][]string{{"A.csproj"}, {solutionResource}, {"B.csproj"}},
+			wantDispatched: []string{"A", "solution", "B"},
+			wantMax:        1,
+		},
+		{
+			name:        "failure cancels the running commands and starts no new one",
+			parallelism: 2,
+			jobs: []fakeCommand{
+				{name: "A", waitFor: "B", fail: true},
+				{name: "B", block: true},
+				{name: "C"},
+			},
+			resources:      [][]string{{"A.csproj"}, {"B.csproj"}, {"C.csproj"}},
+			wantDispatched: []string{"A", "B"},
+			wantMax:        2,
+			wantErr:        "A failed",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := newJobRecorder()
+			builder := Model{parallelism: tt.parallelism, outWriter: &bytes.Buffer{}}
+
+			jobs := []buildJob{}
+			for i := range tt.jobs {
+				command := tt.jobs[i]
+				command.recorder = recorder
+				jobs = append(jobs, buildJob{proj: project.Model{Name: command.name}, command: &command, resources: tt.resources[i]})
+			}
+
+			// the callback is called when a job is dispatched, in the order of dispatching
+			dispatched := []string{}
+			callback := func(solutionName string, projectName string, sdk constants.SDK, testFramework constants.TestFramework, commandStr string, alreadyPerformed bool) {
+				dispatched = append(dispatched, commandStr)
+			}
+
+			err := builder.runJobs(jobs, callback)
+			if tt.wantErr == "" && err != nil {
+				t.Fatalf("runJobs() error = %s", err)
+			}
+			if tt.wantErr != "" && (err == nil || err.Error() != tt.wantErr) {
+				t.Fatalf("runJobs() error = %v, want %s", err, tt.wantErr)
+			}
+
+			if !reflect.DeepEqual(dispatched, tt.wantDispatched) {
+				t.Errorf("dispatched = %v, want %v", dispatched, tt.wantDispatched)
+			}
+			if recorder.maxRunning != tt.wantMax {
+				t.Errorf("max running = %d, want %d", recorder.maxRunning, tt.wantMax)
+			}
+		})
+	}
+}
+
+func TestRunJobsAppsSharingReference(t *testing.T) {
+	// App1 and App2 both reference Lib, their builds run at the same time
+	recorder := newJobRecorder()
+	builder := Model{parallelism: 2, outWriter: &bytes.Buffer{}}
+
+	jobs := []buildJob{}
+	for _, app := range []struct {
+		name    string
+		waitFor string
+	}{
+		{name: "App1", waitFor: "App2"},
+		{name: "App2", waitFor: "App1"},
+	} {
+		resources := builder.commandResources(&dotnet.Model{ProjectPth: "/src/" + app.name + "/" + app.name + ".csproj"})
+		command := &fakeCommand{name: app.name, recorder: recorder, waitFor: app.waitFor}
+		jobs = append(jobs, buildJob{
+			proj:      project.Model{Name: app.name, ReferredProjectIDs: []string{"LIB-ID"}},
+			command:   command,
+			resources: resources,
+		})
+	}
+
+	if err := builder.runJobs(jobs, nil); err != nil {
+		t.Fatalf("runJobs() error = %s", err)
+	}
+	if !reflect.DeepEqual(recorder.overlaps["App1"], []string{"App2"}) {
+		t.Errorf("App1 overlapped with %v, want [App2]", recorder.overlaps["App1"])
+	}
+}
+
+func TestCommandResources(t *testing.T) {
+	builder := Model{}
+
+	if got := builder.commandResources(&dotnet.Model{ProjectPth: "/src/App/App.csproj"}); !reflect.DeepEqual(got, []string{"/src/App/App.csproj"}) {
+		t.Errorf("commandResources(project) = %v", got)
+	}
+	if got := builder.commandResources(&dotnet.Model{}); !reflect.DeepEqual(got, []string{solutionResource}) {
+		t.Errorf("commandResources(solution) = %v", got)
+	}
+}
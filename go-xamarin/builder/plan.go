@@ -0,0 +1,158 @@
+package builder
+
+import (
+	"fmt"
+
+	"github.com/bitrise-steplib/steps-xamarin-archive/go-xamarin/analyzers/project"
+	"github.com/bitrise-steplib/steps-xamarin-archive/go-xamarin/constants"
+	"github.com/bitrise-steplib/steps-xamarin-archive/go-xamarin/tools"
+	"github.com/bitrise-steplib/steps-xamarin-archive/go-xamarin/utility"
+)
+
+// PlanModel describes the build of a solution configuration, without running it.
+type PlanModel struct {
+	Solution      string `json:"solution"`
+	Configuration string `json:"configuration"`
+	Platform      string `json:"platform"`
+
+	Projects        []PlannedProjectModel `json:"projects"`
+	SkippedProjects []SkippedProjectModel `json:"skipped_projects"`
+	Commands        []PlannedCommandModel `json:"commands"`
+	Warnings        []string              `json:"warnings"`
+}
+
+// PlannedProjectModel ...
+type PlannedProjectModel struct {
+	Name          string               `json:"name"`
+	Pth           string               `json:"path"`
+	SDK           constants.SDK        `json:"sdk"`
+	Configuration string               `json:"configuration"` // Project Configuration|Platform
+	Outputs       []PlannedOutputModel `json:"outputs"`
+	Commands      []string             `json:"commands"`
+}
+
+// PlannedOutputModel ...
+type PlannedOutputModel struct {
+	OutputType    constants.OutputType `json:"output_type"`
+	ExpectedPaths []string             `json:"expected_paths"`
+}
+
+// PlannedCommandModel is a command to run, issued by one or more projects.
+type PlannedCommandModel struct {
+	Command      string        `json:"command"`
+	SDK          constants.SDK `json:"sdk"`
+	ProjectNames []string      `json:"projects"`
+}
+
+// Plan returns the projects BuildAllProjects would build with the given configuration and platform (in dependency order),
+// the commands it would run (after the prepare callback edits them, without duplicates) and the expected outputs.
+func (builder Model) Plan(configuration, platform string, buildIpa bool, prepareCallback PrepareCommandCallback) (PlanModel, error) {
+	plan := PlanModel{
+		Solution:        builder.solution.BuildPth(),
+		Configuration:   configuration,
+		Platform:        platform,
+		Projects:        []PlannedProjectModel{},
+		SkippedProjects: []SkippedProjectModel{},
+		Commands:        []PlannedCommandModel{},
+		Warnings:        []string{},
+	}
+
+	if err := validateSolutionConfig(builder.solution, configuration, platform); err != nil {
+		return PlanModel{}, err
+	}
+
+	plan.Warnings = append(plan.Warnings, builder.referenceGraph.Warnings...)
+	if _, err := builder.referenceGraph.TopologicalOrder(); err != nil {
+		plan.Warnings = append(plan.Warnings, err.Error())
+	}
+
+	buildableProjects, skippedProjects := builder.buildableProjects(configuration, platform)
+	plan.SkippedProjects = append(plan.SkippedProjects, skippedProjects...)
+
+	solutionConfig := utility.ToConfig(configuration, platform)
+
+	for _, proj := range buildableProjects {
+		buildCommands, warns, err := builder.buildProjectCommand(configuration, platform, proj, buildIpa)
+		plan.Warnings = append(plan.Warnings, warns...)
+		if err != nil {
+			return PlanModel{}, fmt.Errorf("Failed to create build command, error: %s", err)
+		}
+
+		projectConfigKey := proj.ConfigMap[solutionConfig]
+		projectConfig := builder.buildConfig(proj, proj.Configs[projectConfigKey])
+
+		plannedProject := PlannedProjectModel{
+			Name:          proj.Name,
+			Pth:           proj.Pth,
+			SDK:           proj.SDK,
+			Configuration: projectConfigKey,
+			Outputs:       []PlannedOutputModel{},
+			Commands:      []string{},
+		}
+
+		for _, outputType := range expectedOutputTypes(proj, projectConfig) {
+			expectedPths, err := ExpectedArtifactPaths(proj, projectConfig, outputType)
+			if err != nil {
+				plan.Warnings = append(plan.Warnings, fmt.Sprintf("Failed to determine the expected %s paths of project (%s), error: %s", outputType, proj.Name, err))
+				expectedPths = []string{}
+			}
+
+			plannedProject.Outputs = append(plannedProject.Outputs, PlannedOutputModel{
+				OutputType:    outputType,
+				ExpectedPaths: expectedPths,
+			})
+		}
+
+		for _, buildCommand := range buildCommands {
+			if prepareCallback != nil {
+				editabeCommand := tools.Editable(buildCommand)
+				prepareCallback(builder.solution.Name, proj.Name, proj.SDK, proj.TestFramework, &editabeCommand)
+			}
+
+			commandStr := buildCommand.String()
+			plannedProject.Commands = append(plannedProject.Commands, commandStr)
+			plan.addCommand(commandStr, proj.Name, proj.SDK)
+		}
+
+		plan.Projects = append(plan.Projects, plannedProject)
+	}
+
+	if len(plan.Projects) == 0 {
+		plan.Warnings = append(plan.Warnings, "No project to build found")
+	}
+
+	return plan, nil
+}
+
+// addCommand records the command, or the issuing project if the same command is already planned.
+func (plan *PlanModel) addCommand(commandStr, projectName string, sdk constants.SDK) {
+	for i, command := range plan.Commands {
+		if command.Command == commandStr {
+			plan.Commands[i].ProjectNames = append(plan.Commands[i].ProjectNames, projectName)
+			return
+		}
+	}
+
+	plan.Commands = append(plan.Commands, PlannedCommandModel{
+		Command:      commandStr,
+		SDK:          sdk,
+		ProjectNames: []string{projectName},
+	})
+}
+
+// expectedOutputTypes returns the output types the build of the project creates.
+func expectedOutputTypes(proj project.Model, projectConfig project.ConfigurationPlatformModel) []constants.OutputType {
+	outputTypes := []constants.OutputType{}
+	switch proj.SDK {
+	case constants.SDKIOS, constants.SDKTvOS:
+		if isDeviceBuild(proj, projectConfig) {
+			outputTypes = append(outputTypes, constants.OutputTypeXCArchive, constants.OutputTypeIPA, constants.OutputTypeDSYM)
+		}
+		outputTypes = append(outputTypes, constants.OutputTypeAPP)
+	case constants.SDKMacOS:
+		outputTypes = append(outputTypes, constants.OutputTypeAPP, constants.OutputTypePKG)
+	case constants.SDKAndroid:
+		outputTypes = append(outputTypes, constants.OutputTypeAPK, constants.OutputTypeAAB)
+	}
+	return outputTypes
+}
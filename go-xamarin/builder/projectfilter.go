@@ -0,0 +1,140 @@
+package builder
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/bitrise-steplib/steps-xamarin-archive/go-xamarin/analyzers/project"
+)
+
+// projectFilter selects projects by glob patterns, matched against the project's name,
+// its solution relative path (like: src/App.iOS/App.iOS.csproj) and its solution folder path (like: Apps/Mobile/App.iOS).
+// Besides the usual glob syntax, ** matches any number of path components.
+type projectFilter struct {
+	include []*regexp.Regexp
+	exclude []*regexp.Regexp
+
+	includePatterns []string
+	excludePatterns []string
+}
+
+// SetProjectFilter sets the glob patterns of the projects to build (empty include list means all projects)
+// and the projects to skip. The filter is applied together with the project type whitelist.
+func (builder *Model) SetProjectFilter(include, exclude []string) error {
+	filter := projectFilter{
+		includePatterns: include,
+		excludePatterns: exclude,
+	}
+
+	for _, pattern := range include {
+		re, err := globRegexp(pattern)
+		if err != nil {
+			return err
+		}
+		filter.include = append(filter.include, re)
+	}
+
+	for _, pattern := range exclude {
+		re, err := globRegexp(pattern)
+		if err != nil {
+			return err
+		}
+		filter.exclude = append(filter.exclude, re)
+	}
+
+	builder.projectFilter = filter
+	return nil
+}
+
+// allows returns if the filter allows the project, otherwise the reason of filtering it out.
+func (filter projectFilter) allows(proj project.Model, solutionDir string) (bool, string) {
+	candidates := projectFilterCandidates(proj, solutionDir)
+
+	if len(filter.include) > 0 {
+		if index := matchingPattern(filter.include, candidates); index < 0 {
+			return false, fmt.Sprintf("Project (%s) does not match any of the include patterns (%s), skipping...", proj.Name, strings.Join(filter.includePatterns, ", "))
+		}
+	}
+
+	if index := matchingPattern(filter.exclude, candidates); index >= 0 {
+		return false, fmt.Sprintf("Project (%s) matches the exclude pattern (%s), skipping...", proj.Name, filter.excludePatterns[index])
+	}
+
+	return true, ""
+}
+
+// projectFilterCandidates returns the project's name, solution relative path and solution folder path.
+func projectFilterCandidates(proj project.Model, solutionDir string) []string {
+	candidates := []string{proj.Name}
+
+	if relPth, err := filepath.Rel(solutionDir, proj.Pth); err == nil {
+		candidates = append(candidates, filepath.ToSlash(relPth))
+	}
+
+	if proj.SolutionFolder != "" {
+		candidates = append(candidates, proj.SolutionFolder+"/"+proj.Name)
+	}
+
+	return candidates
+}
+
+// matchingPattern returns the index of the first pattern matching any of the candidates, or -1.
+func matchingPattern(patterns []*regexp.Regexp, candidates []string) int {
+	for i, re := range patterns {
+		for _, candidate := range candidates {
+			if re.MatchString(candidate) {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// globRegexp converts the glob pattern into a regexp: * and ? do not match the path separator, ** does.
+func globRegexp(pattern string) (*regexp.Regexp, error) {
+	if _, err := filepath.Match(pattern, ""); err != nil {
+		return nil, fmt.Errorf("invalid project pattern (%s), error: %s", pattern, err)
+	}
+
+	expr := "^"
+	for i := 0; i < len(pattern); i++ {
+		c := pattern[i]
+		switch {
+		case c == '*' && i+1 < len(pattern) && pattern[i+1] == '*':
+			i++
+			if i+1 < len(pattern) && pattern[i+1] == '/' {
+				// **/ matches zero or more dirs
+				i++
+				expr += "(?:.*/)?"
+			} else {
+				expr += ".*"
+			}
+		case c == '*':
+			expr += "[^/]*"
+		case c == '?':
+			expr += "[^/]"
+		case c == '[':
+			// character classes follow the syntax of filepath.Match, which is valid in regexp as well
+			end := strings.IndexByte(pattern[i:], ']')
+			if end < 0 {
+				return nil, fmt.Errorf("invalid project pattern (%s), error: %s", pattern, filepath.ErrBadPattern)
+			}
+			expr += pattern[i : i+end+1]
+			i += end
+		case c == '\\' && i+1 < len(pattern):
+			i++
+			expr += regexp.QuoteMeta(string(pattern[i]))
+		default:
+			expr += regexp.QuoteMeta(string(c))
+		}
+	}
+	expr += "$"
+
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid project pattern (%s), error: %s", pattern, err)
+	}
+	return re, nil
+}
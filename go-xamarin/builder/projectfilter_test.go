@@ -0,0 +1,85 @@
+package builder
+
+import "testing"
+
+func TestGlobRegexp(t *testing.T) {
+	tests := []struct {
+		pattern   string
+		matches   []string
+		unmatches []string
+		wantErr   bool
+	}{
+		{
+			pattern:   "App.iOS",
+			matches:   []string{"App.iOS"},
+			unmatches: []string{"AppxiOS", "App.iOS.Tests", "My.App.iOS"},
+		},
+		{
+			pattern:   "*.Tests",
+			matches:   []string{"App.Tests", ".Tests"},
+			unmatches: []string{"Tests/App.Tests", "App.Tests.UI"},
+		},
+		{
+			pattern:   "App.?OS",
+			matches:   []string{"App.iOS"},
+			unmatches: []string{"App.tvOS", "App./OS"},
+		},
+		{
+			pattern:   "src/**/*.csproj",
+			matches:   []string{"src/App.csproj", "src/App/App.csproj", "src/a/b/App.csproj"},
+			unmatches: []string{"App.csproj", "test/src/App.csproj", "src/App/App.fsproj"},
+		},
+		{
+			pattern:   "Mobile/**",
+			matches:   []string{"Mobile/App", "Mobile/Shared/App"},
+			unmatches: []string{"Mobile", "Web/App"},
+		},
+		{
+			pattern:   "App.[it]OS",
+			matches:   []string{"App.iOS", "App.tOS"},
+			unmatches: []string{"App.mOS"},
+		},
+		{
+			pattern:   "App.[^i]OS",
+			matches:   []string{"App.mOS"},
+			unmatches: []string{"App.iOS"},
+		},
+		{
+			pattern:   `App\*`,
+			matches:   []string{"App*"},
+			unmatches: []string{"App.iOS"},
+		},
+		{
+			pattern:   "App(1)+",
+			matches:   []string{"App(1)+"},
+			unmatches: []string{"App1", "App11"},
+		},
+		{
+			pattern: "App.[iOS",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pattern, func(t *testing.T) {
+			re, err := globRegexp(tt.pattern)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("globRegexp(%q) error = %v, wantErr %v", tt.pattern, err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+
+			for _, candidate := range tt.matches {
+				if !re.MatchString(candidate) {
+					t.Errorf("globRegexp(%q) = %s does not match %q", tt.pattern, re, candidate)
+				}
+			}
+			for _, candidate := range tt.unmatches {
+				if re.MatchString(candidate) {
+					t.Errorf("globRegexp(%q) = %s matches %q", tt.pattern, re, candidate)
+				}
+			}
+		})
+	}
+}
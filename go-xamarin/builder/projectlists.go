@@ -2,42 +2,103 @@ package builder
 
 import (
 	"fmt"
+	"path/filepath"
+	"sort"
 
-	"github.com/toggl/go-xamarin/analyzers/project"
-	"github.com/toggl/go-xamarin/constants"
-	"github.com/toggl/go-xamarin/utility"
+	"github.com/bitrise-steplib/steps-xamarin-archive/go-xamarin/analyzers/project"
+	"github.com/bitrise-steplib/steps-xamarin-archive/go-xamarin/constants"
+	"github.com/bitrise-steplib/steps-xamarin-archive/go-xamarin/utility"
 )
 
 func (builder Model) whitelistedProjects() []project.Model {
+	projects, _ := builder.selectedProjects()
+	return projects
+}
+
+// selectedProjects returns the projects allowed by both the project type whitelist and the project filter,
+// and the projects skipped by the project filter.
+func (builder Model) selectedProjects() ([]project.Model, []SkippedProjectModel) {
 	projects := []project.Model{}
+	skippedProjects := []SkippedProjectModel{}
 
-	for _, proj := range builder.solution.ProjectMap {
+	solutionDir := filepath.Dir(builder.solution.Pth)
+
+	for projectID, proj := range builder.solution.ProjectMap {
 		if !whitelistAllows(proj.SDK, builder.projectTypeWhitelist...) {
 			continue
 		}
 
-		if proj.SDK != constants.SDKUnknown {
-			projects = append(projects, proj)
+		if proj.SDK == constants.SDKUnknown {
+			continue
 		}
+
+		if ok, reason := builder.projectFilter.allows(proj, solutionDir); !ok {
+			skippedProjects = append(skippedProjects, SkippedProjectModel{
+				ProjectName: proj.Name,
+				Reason:      reason,
+			})
+			continue
+		}
+
+		if !builder.isAffected(projectID) {
+			skippedProjects = append(skippedProjects, SkippedProjectModel{
+				ProjectName: proj.Name,
+				Reason:      fmt.Sprintf("Project (%s) is not affected by the changed files, skipping...", proj.Name),
+			})
+			continue
+		}
+
+		projects = append(projects, proj)
 	}
 
+	projects = builder.sortByDependencies(projects)
+	sort.Slice(skippedProjects, func(i, j int) bool {
+		return skippedProjects[i].ProjectName < skippedProjects[j].ProjectName
+	})
+
+	return projects, skippedProjects
+}
+
+// sortByDependencies orders the projects so, that every project comes after the projects it refers,
+// independent projects are ordered by name. If the project references contain a cycle, the projects are ordered by name.
+func (builder Model) sortByDependencies(projects []project.Model) []project.Model {
+	sort.Slice(projects, func(i, j int) bool {
+		return projects[i].Name < projects[j].Name
+	})
+
+	order, err := builder.referenceGraph.TopologicalOrder()
+	if err != nil {
+		return projects
+	}
+
+	positions := map[string]int{}
+	for position, projectID := range order {
+		positions[projectID] = position
+	}
+
+	sort.SliceStable(projects, func(i, j int) bool {
+		return positions[builder.solutionProjectID(projects[i])] < positions[builder.solutionProjectID(projects[j])]
+	})
+
 	return projects
 }
 
-func (builder Model) buildableProjects(configuration, platform string) ([]project.Model, []string) {
+func (builder Model) buildableProjects(configuration, platform string) ([]project.Model, []SkippedProjectModel) {
 	projects := []project.Model{}
-	warnings := []string{}
 
 	solutionConfig := utility.ToConfig(configuration, platform)
 
-	whitelistedProjects := builder.whitelistedProjects()
+	whitelistedProjects, skippedProjects := builder.selectedProjects()
 
 	for _, proj := range whitelistedProjects {
 		//
 		// Solution config - project config mapping
 		_, ok := proj.ConfigMap[solutionConfig]
 		if !ok {
-			warnings = append(warnings, fmt.Sprintf("Project (%s) do not have config for solution config (%s), skipping...", proj.Name, solutionConfig))
+			skippedProjects = append(skippedProjects, SkippedProjectModel{
+				ProjectName: proj.Name,
+				Reason:      fmt.Sprintf("Project (%s) do not have config for solution config (%s), skipping...", proj.Name, solutionConfig),
+			})
 			continue
 		}
 
@@ -45,12 +106,18 @@ func (builder Model) buildableProjects(configuration, platform string) ([]projec
 			proj.SDK == constants.SDKMacOS ||
 			proj.SDK == constants.SDKTvOS) &&
 			proj.OutputType != "exe" {
-			warnings = append(warnings, fmt.Sprintf("Project (%s) is not archivable based on output type (%s), skipping...", proj.Name, proj.OutputType))
+			skippedProjects = append(skippedProjects, SkippedProjectModel{
+				ProjectName: proj.Name,
+				Reason:      fmt.Sprintf("Project (%s) is not archivable based on output type (%s), skipping...", proj.Name, proj.OutputType),
+			})
 			continue
 		}
 		if proj.SDK == constants.SDKAndroid &&
 			!proj.AndroidApplication {
-			warnings = append(warnings, fmt.Sprintf("(%s) is not an android application project, skipping...", proj.Name))
+			skippedProjects = append(skippedProjects, SkippedProjectModel{
+				ProjectName: proj.Name,
+				Reason:      fmt.Sprintf("(%s) is not an android application project, skipping...", proj.Name),
+			})
 			continue
 		}
 
@@ -59,7 +126,7 @@ func (builder Model) buildableProjects(configuration, platform string) ([]projec
 		}
 	}
 
-	return projects, warnings
+	return projects, skippedProjects
 }
 
 func (builder Model) buildableXamarinUITestProjectsAndReferredProjects(configuration, platform string) ([]project.Model, []project.Model, []string) {
@@ -101,6 +168,10 @@ func (builder Model) buildableXamarinUITestProjectsAndReferredProjects(configura
 				continue
 			}
 
+			if ok, _ := builder.projectFilter.allows(referredProj, filepath.Dir(builder.solution.Pth)); !ok {
+				continue
+			}
+
 			if whitelistAllows(referredProj.SDK, builder.projectTypeWhitelist...) {
 				referredProjects = append(referredProjects, referredProj)
 			}
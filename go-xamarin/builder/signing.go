@@ -0,0 +1,62 @@
+package builder
+
+import (
+	"fmt"
+
+	"github.com/bitrise-io/go-utils/pathutil"
+	"github.com/bitrise-steplib/steps-xamarin-archive/go-xamarin/tools/buildtools/dotnet"
+	"github.com/bitrise-steplib/steps-xamarin-archive/go-xamarin/tools/buildtools/xbuild"
+)
+
+// AndroidSigningModel is the keystore to sign the Android packages with.
+type AndroidSigningModel struct {
+	KeystorePth      string
+	KeystorePassword string
+	KeyAlias         string
+	KeyPassword      string // The keystore password is used if empty
+}
+
+// SetAndroidSigning sets the keystore to sign every Android project's package with,
+// instead of the signing configuration of the projects. The keystore has to exist.
+func (builder *Model) SetAndroidSigning(signing AndroidSigningModel) error {
+	absPth, err := pathutil.AbsPath(signing.KeystorePth)
+	if err != nil {
+		return fmt.Errorf("failed to expand path (%s), error: %s", signing.KeystorePth, err)
+	}
+
+	if exist, err := pathutil.IsPathExists(absPth); err != nil {
+		return err
+	} else if !exist {
+		return fmt.Errorf("keystore not exist at: %s", absPth)
+	}
+
+	if signing.KeyAlias == "" {
+		return fmt.Errorf("no key alias specified for keystore: %s", absPth)
+	}
+
+	signing.KeystorePth = absPth
+	if signing.KeyPassword == "" {
+		signing.KeyPassword = signing.KeystorePassword
+	}
+	builder.androidSigning = &signing
+
+	return nil
+}
+
+// applyToXbuild sets the signing properties on an xbuild or msbuild command.
+func (signing AndroidSigningModel) applyToXbuild(command *xbuild.Model) {
+	command.SetProperty("AndroidKeyStore", "true")
+	command.SetProperty("AndroidSigningKeyStore", signing.KeystorePth)
+	command.SetSecretProperty("AndroidSigningStorePass", signing.KeystorePassword)
+	command.SetProperty("AndroidSigningKeyAlias", signing.KeyAlias)
+	command.SetSecretProperty("AndroidSigningKeyPass", signing.KeyPassword)
+}
+
+// applyToDotnet sets the signing properties on a dotnet command.
+func (signing AndroidSigningModel) applyToDotnet(command *dotnet.Model) {
+	command.SetProperty("AndroidKeyStore", "true")
+	command.SetProperty("AndroidSigningKeyStore", signing.KeystorePth)
+	command.SetSecretProperty("AndroidSigningStorePass", signing.KeystorePassword)
+	command.SetProperty("AndroidSigningKeyAlias", signing.KeyAlias)
+	command.SetSecretProperty("AndroidSigningKeyPass", signing.KeyPassword)
+}
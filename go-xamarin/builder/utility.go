@@ -0,0 +1,196 @@
+package builder
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/bitrise-io/go-utils/fileutil"
+	"github.com/bitrise-io/go-utils/pathutil"
+	"github.com/bitrise-steplib/steps-xamarin-archive/go-xamarin/analyzers/project"
+	"github.com/bitrise-steplib/steps-xamarin-archive/go-xamarin/analyzers/solution"
+	"github.com/bitrise-steplib/steps-xamarin-archive/go-xamarin/constants"
+	"github.com/bitrise-steplib/steps-xamarin-archive/go-xamarin/tools"
+	"github.com/bitrise-steplib/steps-xamarin-archive/go-xamarin/utility"
+)
+
+func validateSolutionPth(pth string) error {
+	ext := filepath.Ext(pth)
+	if ext != constants.SolutionExt && ext != constants.SolutionFilterExt {
+		return fmt.Errorf("path is not a solution or solution filter file path: %s", pth)
+	}
+	if exist, err := pathutil.IsPathExists(pth); err != nil {
+		return err
+	} else if !exist {
+		return fmt.Errorf("solution not exist at: %s", pth)
+	}
+	return nil
+}
+
+func validateSolutionConfig(solution solution.Model, configuration, platform string) error {
+	config := utility.ToConfig(configuration, platform)
+	if _, ok := solution.ConfigMap[config]; !ok {
+		return fmt.Errorf("invalid solution config, available: %v", solution.ConfigList())
+	}
+	return nil
+}
+
+func whitelistAllows(projectType constants.SDK, projectTypeWhiteList ...constants.SDK) bool {
+	if len(projectTypeWhiteList) == 0 {
+		return true
+	}
+
+	for _, filter := range projectTypeWhiteList {
+		switch filter {
+		case constants.SDKIOS:
+			if projectType == constants.SDKIOS {
+				return true
+			}
+		case constants.SDKTvOS:
+			if projectType == constants.SDKTvOS {
+				return true
+			}
+		case constants.SDKMacOS:
+			if projectType == constants.SDKMacOS {
+				return true
+			}
+		case constants.SDKAndroid:
+			if projectType == constants.SDKAndroid {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// IsDeviceArch based on:
+// default architecture: ARMv7
+// iPhone architecture: <MtouchArch>ARMv7,ARMv7s,ARM64</MtouchArch>
+// iPhoneSimulator architecture: <MtouchArch>i386, x86_64</MtouchArch>
+func IsDeviceArch(architectures ...string) bool {
+	return len(architectures) == 0 || strings.HasPrefix(strings.ToLower(architectures[0]), "arm")
+}
+
+// isDeviceBuild returns true if the project configuration builds for devices:
+// SDK-style projects are built for the simulator if their runtime identifier is a simulator one,
+// or if they do not define a runtime identifier and the iPhoneSimulator platform is built.
+func isDeviceBuild(proj project.Model, projectConfig project.ConfigurationPlatformModel) bool {
+	if proj.SDKStyle {
+		if projectConfig.RuntimeIdentifier != "" {
+			return !strings.Contains(strings.ToLower(projectConfig.RuntimeIdentifier), "simulator")
+		}
+		return !strings.EqualFold(projectConfig.Platform, "iPhoneSimulator")
+	}
+	return IsDeviceArch(projectConfig.MtouchArchs...)
+}
+
+func isPlatformAnyCPU(platform string) bool {
+	return (platform == "Any CPU" || platform == "AnyCPU")
+}
+
+func androidPackageName(manifestPth string) (string, error) {
+	content, err := fileutil.ReadStringFromFile(manifestPth)
+	if err != nil {
+		return "", err
+	}
+
+	return androidPackageNameFromManifestContent(content)
+}
+
+// targetFrameworkOption returns the target framework to build of a project targeting multiple frameworks,
+// projects with a single target framework do not need to specify it.
+func targetFrameworkOption(proj project.Model) string {
+	if len(proj.TargetFrameworks) > 1 {
+		return proj.TargetFramework
+	}
+	return ""
+}
+
+// projectAndroidPackageName returns the package name of the Android project,
+// the ApplicationId of SDK-style projects overrides the package defined in the manifest.
+func projectAndroidPackageName(proj project.Model) (string, error) {
+	if proj.ApplicationID != "" {
+		return proj.ApplicationID, nil
+	}
+
+	packageName, err := androidPackageName(proj.ManifestPth)
+	if err != nil {
+		return "", fmt.Errorf("could get package name from manifest file at %v. Error: %v", proj.ManifestPth, err)
+	}
+	return packageName, nil
+}
+
+func androidPackageNameFromManifestContent(manifestContent string) (string, error) {
+	// package is attribute of the rott xml element
+	manifestContent = "<a>" + manifestContent + "</a>"
+
+	type Manifest struct {
+		Package string `xml:"package,attr"`
+	}
+
+	type Result struct {
+		Manifest Manifest `xml:"manifest"`
+	}
+
+	var result Result
+	if err := xml.Unmarshal([]byte(manifestContent), &result); err != nil {
+		return "", err
+	}
+
+	return result.Manifest.Package, nil
+}
+
+func (builder Model) runCommand(command tools.Runnable, projectName string, sdk constants.SDK, testFramework constants.TestFramework) error {
+	outWriter, errWriter, closeOutputs := builder.commandWriters(command, projectName, builder.outWriter, builder.errWriter)
+	defer closeOutputs()
+
+	startTime := time.Now()
+	err := builder.runCommandWithContext(builder.context(), command, outWriter, errWriter)
+
+	// Callback to notify the caller about the finished command
+	if builder.commandResultCallback != nil {
+		builder.commandResultCallback(builder.solution.Name, projectName, sdk, testFramework, command.String(), time.Since(startTime), err)
+	}
+
+	return err
+}
+
+// runCommandWithContext runs the command with the command timeout, the returned error names the command if it timed out.
+func (builder Model) runCommandWithContext(ctx context.Context, command tools.Runnable, outWriter, errWriter io.Writer) error {
+	if builder.commandTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, builder.commandTimeout)
+		defer cancel()
+	}
+
+	err := command.RunWithContext(ctx, outWriter, errWriter)
+	if err != nil && ctx.Err() == context.DeadlineExceeded {
+		return fmt.Errorf("command timed out after %s, killed its process tree: %s", builder.commandTimeout, command.String())
+	}
+	if err != nil && ctx.Err() == context.Canceled {
+		return fmt.Errorf("command canceled, killed its process tree: %s", command.String())
+	}
+	return err
+}
+
+// solutionProjectID returns the ID of the project in the solution, which might differ from the project's ProjectGuid.
+func (builder Model) solutionProjectID(proj project.Model) string {
+	for projectID, p := range builder.solution.ProjectMap {
+		if p.Pth == proj.Pth {
+			return projectID
+		}
+	}
+	return proj.ID
+}
+
+func (builder Model) context() context.Context {
+	if builder.ctx == nil {
+		return context.Background()
+	}
+	return builder.ctx
+}
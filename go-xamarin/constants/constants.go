@@ -1,6 +1,9 @@
 package constants
 
-import "fmt"
+import (
+	"fmt"
+	"strings"
+)
 
 const (
 	// MsbuildPath ...
@@ -15,6 +18,8 @@ const (
 const (
 	// SolutionExt ...
 	SolutionExt = ".sln"
+	// SolutionFilterExt ...
+	SolutionFilterExt = ".slnf"
 	// CSProjExt ...
 	CSProjExt = ".csproj"
 	// FSProjExt ...
@@ -23,6 +28,9 @@ const (
 	SHProjExt = ".shproj"
 )
 
+// SolutionFolderTypeGUID is the project type GUID of the solution folder entries in the solution file.
+const SolutionFolderTypeGUID = "2150E333-8FDC-42A3-9474-1A3956D46DE8"
+
 // SDK ...
 type SDK string
 
@@ -108,6 +116,33 @@ func ParseProjectTypeGUID(guid string) (SDK, error) {
 	}
 }
 
+// ParseTargetFramework returns the SDK of the given target framework moniker,
+// like net8.0-android, net8.0-ios17.0, net8.0-maccatalyst or the legacy monoandroid90 and xamarinios10.
+func ParseTargetFramework(targetFramework string) (SDK, error) {
+	tfm := strings.ToLower(strings.TrimSpace(targetFramework))
+
+	platform := ""
+	if split := strings.SplitN(tfm, "-", 2); len(split) == 2 {
+		// net8.0-ios17.0: the platform may be followed by its version
+		platform = strings.TrimRight(split[1], "0123456789.")
+	} else {
+		platform = strings.TrimRight(tfm, "0123456789.")
+	}
+
+	switch platform {
+	case "android", "monoandroid":
+		return SDKAndroid, nil
+	case "ios", "xamarinios":
+		return SDKIOS, nil
+	case "tvos", "xamarintvos":
+		return SDKTvOS, nil
+	case "maccatalyst", "macos", "xamarinmac":
+		return SDKMacOS, nil
+	default:
+		return SDKUnknown, fmt.Errorf("can not identify target framework: %s", targetFramework)
+	}
+}
+
 // OutputType ...
 type OutputType string
 
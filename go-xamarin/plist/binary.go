@@ -0,0 +1,311 @@
+package plist
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"time"
+	"unicode/utf16"
+
+	"github.com/bitrise-io/go-utils/fileutil"
+)
+
+const (
+	binaryHeader      = "bplist00"
+	binaryTrailerSize = 32
+	maxBinaryDepth    = 512
+)
+
+// binaryEpoch is the reference date of the binary property list dates.
+var binaryEpoch = time.Date(2001, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// binaryDecoder reads the objects of a binary property list.
+type binaryDecoder struct {
+	data          []byte
+	offsets       []uint64
+	objectRefSize int
+}
+
+// IsBinary returns true if the content is a binary property list.
+func IsBinary(content []byte) bool {
+	return bytes.HasPrefix(content, []byte(binaryHeader))
+}
+
+// Parse parses the XML or binary property list content and returns its root value.
+func Parse(content []byte) (Value, error) {
+	if IsBinary(content) {
+		return ParseBinary(content)
+	}
+
+	document, err := ParseXML(content)
+	if err != nil {
+		return nil, err
+	}
+	return document.Root, nil
+}
+
+// ReadFile parses the XML or binary property list at the given path and returns its root value.
+func ReadFile(pth string) (Value, error) {
+	content, err := fileutil.ReadBytesFromFile(pth)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read property list (%s), error: %s", pth, err)
+	}
+
+	value, err := Parse(content)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse property list (%s), error: %s", pth, err)
+	}
+	return value, nil
+}
+
+// ParseBinary parses the binary property list content and returns its root value.
+// UIDs are returned as integers, sets as arrays.
+func ParseBinary(content []byte) (Value, error) {
+	if !IsBinary(content) {
+		return nil, fmt.Errorf("not a binary property list")
+	}
+	if len(content) < len(binaryHeader)+binaryTrailerSize {
+		return nil, fmt.Errorf("truncated binary property list")
+	}
+
+	trailer := content[len(content)-binaryTrailerSize:]
+	offsetIntSize := int(trailer[6])
+	objectRefSize := int(trailer[7])
+	numObjects := binary.BigEndian.Uint64(trailer[8:])
+	topObject := binary.BigEndian.Uint64(trailer[16:])
+	offsetTableOffset := binary.BigEndian.Uint64(trailer[24:])
+
+	if offsetIntSize < 1 || offsetIntSize > 8 || objectRefSize < 1 || objectRefSize > 8 {
+		return nil, fmt.Errorf("invalid binary property list trailer")
+	}
+	tableEnd := offsetTableOffset + numObjects*uint64(offsetIntSize)
+	if numObjects > uint64(len(content)) || offsetTableOffset > uint64(len(content)) || tableEnd > uint64(len(content)-binaryTrailerSize) {
+		return nil, fmt.Errorf("invalid binary property list offset table")
+	}
+	if topObject >= numObjects {
+		return nil, fmt.Errorf("invalid binary property list top object: %d", topObject)
+	}
+
+	decoder := binaryDecoder{
+		data:          content,
+		offsets:       make([]uint64, numObjects),
+		objectRefSize: objectRefSize,
+	}
+	for i := range decoder.offsets {
+		start := offsetTableOffset + uint64(i*offsetIntSize)
+		decoder.offsets[i] = readUint(content[start : start+uint64(offsetIntSize)])
+	}
+
+	return decoder.object(topObject, 0)
+}
+
+// readUint reads a big-endian unsigned integer of at most 8 bytes.
+func readUint(data []byte) uint64 {
+	var value uint64
+	for _, b := range data {
+		value = value<<8 | uint64(b)
+	}
+	return value
+}
+
+// bytes returns the given number of bytes at the offset.
+func (decoder binaryDecoder) bytes(offset, length uint64) ([]byte, error) {
+	end := offset + length
+	if end < offset || end > uint64(len(decoder.data)) {
+		return nil, fmt.Errorf("unexpected end of data at offset: %d", offset)
+	}
+	return decoder.data[offset:end], nil
+}
+
+// length returns the element count of the object with the given marker, and the offset of its content.
+// Counts of 15 or more are stored as an integer object after the marker.
+func (decoder binaryDecoder) length(marker byte, offset uint64) (uint64, uint64, error) {
+	count := uint64(marker & 0x0F)
+	if count != 0x0F {
+		return count, offset + 1, nil
+	}
+
+	intMarker, err := decoder.bytes(offset+1, 1)
+	if err != nil {
+		return 0, 0, err
+	}
+	if intMarker[0]&0xF0 != 0x10 {
+		return 0, 0, fmt.Errorf("invalid length at offset: %d", offset)
+	}
+
+	size := uint64(1) << (intMarker[0] & 0x0F)
+	data, err := decoder.bytes(offset+2, size)
+	if err != nil {
+		return 0, 0, err
+	}
+	return readUint(data), offset + 2 + size, nil
+}
+
+func (decoder binaryDecoder) refs(offset, count uint64) ([]uint64, error) {
+	if count > uint64(len(decoder.data)) {
+		return nil, fmt.Errorf("invalid object count: %d", count)
+	}
+
+	data, err := decoder.bytes(offset, count*uint64(decoder.objectRefSize))
+	if err != nil {
+		return nil, err
+	}
+
+	refs := make([]uint64, count)
+	for i := range refs {
+		start := i * decoder.objectRefSize
+		refs[i] = readUint(data[start : start+decoder.objectRefSize])
+	}
+	return refs, nil
+}
+
+func (decoder binaryDecoder) object(ref uint64, depth int) (Value, error) {
+	if ref >= uint64(len(decoder.offsets)) {
+		return nil, fmt.Errorf("invalid object reference: %d", ref)
+	}
+	if depth > maxBinaryDepth {
+		return nil, fmt.Errorf("property list nesting is too deep, or has a reference cycle")
+	}
+
+	offset := decoder.offsets[ref]
+	markerData, err := decoder.bytes(offset, 1)
+	if err != nil {
+		return nil, err
+	}
+	marker := markerData[0]
+
+	switch marker & 0xF0 {
+	case 0x00:
+		switch marker {
+		case 0x08:
+			return false, nil
+		case 0x09:
+			return true, nil
+		default:
+			return nil, nil
+		}
+	case 0x10:
+		size := uint64(1) << (marker & 0x0F)
+		data, err := decoder.bytes(offset+1, size)
+		if err != nil {
+			return nil, err
+		}
+		// 16 byte integers are stored for large unsigned values, the low 8 bytes hold the value
+		if size > 8 {
+			data = data[size-8:]
+		}
+		// integers shorter than 8 bytes are unsigned, 8 byte integers are signed
+		return int64(readUint(data)), nil
+	case 0x20:
+		size := uint64(1) << (marker & 0x0F)
+		data, err := decoder.bytes(offset+1, size)
+		if err != nil {
+			return nil, err
+		}
+		switch size {
+		case 4:
+			return float64(math.Float32frombits(uint32(readUint(data)))), nil
+		case 8:
+			return math.Float64frombits(readUint(data)), nil
+		default:
+			return nil, fmt.Errorf("invalid real size: %d", size)
+		}
+	case 0x30:
+		data, err := decoder.bytes(offset+1, 8)
+		if err != nil {
+			return nil, err
+		}
+		seconds := math.Float64frombits(readUint(data))
+		return binaryEpoch.Add(time.Duration(seconds * float64(time.Second))), nil
+	case 0x40:
+		count, start, err := decoder.length(marker, offset)
+		if err != nil {
+			return nil, err
+		}
+		data, err := decoder.bytes(start, count)
+		if err != nil {
+			return nil, err
+		}
+		return append([]byte{}, data...), nil
+	case 0x50:
+		count, start, err := decoder.length(marker, offset)
+		if err != nil {
+			return nil, err
+		}
+		data, err := decoder.bytes(start, count)
+		if err != nil {
+			return nil, err
+		}
+		return string(data), nil
+	case 0x60:
+		count, start, err := decoder.length(marker, offset)
+		if err != nil {
+			return nil, err
+		}
+		data, err := decoder.bytes(start, count*2)
+		if err != nil {
+			return nil, err
+		}
+		units := make([]uint16, count)
+		for i := range units {
+			units[i] = binary.BigEndian.Uint16(data[i*2:])
+		}
+		return string(utf16.Decode(units)), nil
+	case 0x80:
+		data, err := decoder.bytes(offset+1, uint64(marker&0x0F)+1)
+		if err != nil {
+			return nil, err
+		}
+		return int64(readUint(data)), nil
+	case 0xA0, 0xC0:
+		count, start, err := decoder.length(marker, offset)
+		if err != nil {
+			return nil, err
+		}
+		refs, err := decoder.refs(start, count)
+		if err != nil {
+			return nil, err
+		}
+
+		array := make([]Value, 0, count)
+		for _, itemRef := range refs {
+			item, err := decoder.object(itemRef, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			array = append(array, item)
+		}
+		return array, nil
+	case 0xD0:
+		count, start, err := decoder.length(marker, offset)
+		if err != nil {
+			return nil, err
+		}
+		refs, err := decoder.refs(start, count*2)
+		if err != nil {
+			return nil, err
+		}
+
+		dict := NewDict()
+		for i := uint64(0); i < count; i++ {
+			key, err := decoder.object(refs[i], depth+1)
+			if err != nil {
+				return nil, err
+			}
+			keyStr, ok := key.(string)
+			if !ok {
+				return nil, fmt.Errorf("dict key is not a string: %v", key)
+			}
+
+			value, err := decoder.object(refs[count+i], depth+1)
+			if err != nil {
+				return nil, err
+			}
+			dict.Set(keyStr, value)
+		}
+		return dict, nil
+	default:
+		return nil, fmt.Errorf("unknown object marker: 0x%02x", marker)
+	}
+}
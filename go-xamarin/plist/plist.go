@@ -0,0 +1,95 @@
+package plist
+
+import (
+	"fmt"
+	"time"
+)
+
+// Value is a property list value, one of:
+// string, int64 (integer), float64 (real), bool, time.Time (date), []byte (data), []Value (array) or *Dict (dict).
+type Value interface{}
+
+// Dict is a property list dictionary, which keeps the order of its keys.
+type Dict struct {
+	keys   []string
+	values map[string]Value
+}
+
+// NewDict ...
+func NewDict() *Dict {
+	return &Dict{values: map[string]Value{}}
+}
+
+// Keys returns the keys in the order of the property list.
+func (dict *Dict) Keys() []string {
+	return append([]string{}, dict.keys...)
+}
+
+// Get ...
+func (dict *Dict) Get(key string) (Value, bool) {
+	value, ok := dict.values[key]
+	return value, ok
+}
+
+// GetString returns the string value of the key, the second return value is false if the key is missing or not a string.
+func (dict *Dict) GetString(key string) (string, bool) {
+	value, ok := dict.values[key].(string)
+	return value, ok
+}
+
+// GetDict returns the dict value of the key, the second return value is false if the key is missing or not a dict.
+func (dict *Dict) GetDict(key string) (*Dict, bool) {
+	value, ok := dict.values[key].(*Dict)
+	return value, ok
+}
+
+// Set sets the value of the key, a new key is appended to the end of the dict.
+func (dict *Dict) Set(key string, value Value) {
+	if _, ok := dict.values[key]; !ok {
+		dict.keys = append(dict.keys, key)
+	}
+	dict.values[key] = value
+}
+
+// Delete ...
+func (dict *Dict) Delete(key string) {
+	if _, ok := dict.values[key]; !ok {
+		return
+	}
+	delete(dict.values, key)
+
+	for i, k := range dict.keys {
+		if k == key {
+			dict.keys = append(dict.keys[:i], dict.keys[i+1:]...)
+			break
+		}
+	}
+}
+
+// Len ...
+func (dict *Dict) Len() int {
+	return len(dict.keys)
+}
+
+func checkValue(value Value) error {
+	switch v := value.(type) {
+	case string, int64, float64, bool, time.Time, []byte:
+		return nil
+	case []Value:
+		for _, item := range v {
+			if err := checkValue(item); err != nil {
+				return err
+			}
+		}
+		return nil
+	case *Dict:
+		for _, key := range v.keys {
+			if err := checkValue(v.values[key]); err != nil {
+				return err
+			}
+		}
+		return nil
+	default:
+		return fmt.Errorf("unsupported property list value type: %T", value)
+	}
+}
@@ -0,0 +1,187 @@
+package plist
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// testdata/Info.plist is a binary property list written by Python's plistlib,
+// testdata/Info.xml.plist is an XML property list in the format Xcode writes.
+
+func readTestPlist(t *testing.T, name string) []byte {
+	content, err := ioutil.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return content
+}
+
+func TestParseBinary(t *testing.T) {
+	content := readTestPlist(t, "Info.plist")
+	if !IsBinary(content) {
+		t.Fatalf("IsBinary() = false, want true")
+	}
+
+	root, err := Parse(content)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	dict, ok := root.(*Dict)
+	if !ok {
+		t.Fatalf("root value is %T, want *Dict", root)
+	}
+
+	icon := make([]byte, 64)
+	for i := range icon {
+		icon[i] = byte(i)
+	}
+
+	tests := []struct {
+		key  string
+		want Value
+	}{
+		{key: "CFBundleDisplayName", want: "Ünïcode App"},
+		{key: "CFBundleIdentifier", want: "com.example.app"},
+		{key: "CFBundleVersion", want: "42"},
+		{key: "LSRequiresIPhoneOS", want: true},
+		{key: "UIRequiresFullScreen", want: false},
+		{key: "UIDeviceFamily", want: []Value{int64(1), int64(2)}},
+		{key: "NegativeInteger", want: int64(-7)},
+		{key: "LargeInteger", want: int64(4294967296)},
+		{key: "Scale", want: 2.5},
+		{key: "BuildDate", want: time.Date(2026, time.October, 16, 12, 30, 0, 0, time.UTC)},
+		{key: "Icon", want: icon},
+		{key: "UIAppFonts", want: []Value{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			got, ok := dict.Get(tt.key)
+			if !ok {
+				t.Fatalf("Get(%q) is missing", tt.key)
+			}
+			if gotTime, ok := got.(time.Time); ok {
+				if !gotTime.Equal(tt.want.(time.Time)) {
+					t.Errorf("Get(%q) = %v, want %v", tt.key, got, tt.want)
+				}
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Get(%q) = %#v, want %#v", tt.key, got, tt.want)
+			}
+		})
+	}
+
+	ats, ok := dict.GetDict("NSAppTransportSecurity")
+	if !ok {
+		t.Fatalf("GetDict(NSAppTransportSecurity) is missing")
+	}
+	if want := []string{"NSAllowsArbitraryLoads", "NSExceptionDomains"}; !reflect.DeepEqual(ats.Keys(), want) {
+		t.Errorf("NSAppTransportSecurity keys = %v, want %v", ats.Keys(), want)
+	}
+}
+
+func TestParseBinaryInvalid(t *testing.T) {
+	content := readTestPlist(t, "Info.plist")
+
+	tests := []struct {
+		name    string
+		content []byte
+	}{
+		{name: "header only", content: []byte(binaryHeader)},
+		{name: "truncated", content: content[:len(content)-binaryTrailerSize/2]},
+		{name: "truncated objects", content: append(append([]byte{}, content[:len(binaryHeader)+4]...), content[len(content)-binaryTrailerSize:]...)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseBinary(tt.content); err == nil {
+				t.Errorf("ParseBinary() error = nil, want error")
+			}
+		})
+	}
+}
+
+func TestBinaryToXMLRoundTrip(t *testing.T) {
+	root, err := Parse(readTestPlist(t, "Info.plist"))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	content, err := NewDocument(root).Marshal()
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+
+	document, err := ParseXML(content)
+	if err != nil {
+		t.Fatalf("ParseXML() error = %v\n%s", err, content)
+	}
+	if !reflect.DeepEqual(document.Root, root) {
+		t.Errorf("XML round trip of the binary property list = %#v, want %#v", document.Root, root)
+	}
+}
+
+func TestXMLRoundTrip(t *testing.T) {
+	content := readTestPlist(t, "Info.xml.plist")
+
+	document, err := ParseXML(content)
+	if err != nil {
+		t.Fatalf("ParseXML() error = %v", err)
+	}
+
+	got, err := document.Marshal()
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	if !bytes.Equal(got, content) {
+		t.Errorf("Marshal() of the unmodified document:\n%s\nwant:\n%s", got, content)
+	}
+
+	dict, err := document.RootDict()
+	if err != nil {
+		t.Fatalf("RootDict() error = %v", err)
+	}
+	if name, _ := dict.GetString("CFBundleDisplayName"); name != `Ünïcode & "App"` {
+		t.Errorf("CFBundleDisplayName = %q, want %q", name, `Ünïcode & "App"`)
+	}
+
+	dict.Set("CFBundleVersion", "43")
+	dict.Set("NewKey", "value")
+	got, err = document.Marshal()
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+
+	want := bytes.Replace(content, []byte("<string>42</string>"), []byte("<string>43</string>"), 1)
+	want = bytes.Replace(want, []byte("</dict>\n</plist>"), []byte("\t<key>NewKey</key>\n\t<string>value</string>\n</dict>\n</plist>"), 1)
+	if !bytes.Equal(got, want) {
+		t.Errorf("Marshal() of the modified document:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestParseXMLInvalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{name: "empty", content: ""},
+		{name: "not a plist", content: `<?xml version="1.0"?><dict></dict>`},
+		{name: "unclosed dict", content: `<plist version="1.0"><dict><key>A</key><string>a</string></plist>`},
+		{name: "key without value", content: `<plist version="1.0"><dict><key>A</key></dict></plist>`},
+		{name: "invalid integer", content: `<plist version="1.0"><integer>one</integer></plist>`},
+		{name: "invalid date", content: `<plist version="1.0"><date>yesterday</date></plist>`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseXML([]byte(tt.content)); err == nil {
+				t.Errorf("ParseXML(%q) error = nil, want error", tt.content)
+			}
+		})
+	}
+}
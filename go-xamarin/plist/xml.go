@@ -0,0 +1,339 @@
+package plist
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bitrise-io/go-utils/fileutil"
+)
+
+const (
+	xmlHeader  = `<?xml version="1.0" encoding="UTF-8"?>` + "\n"
+	xmlDoctype = `<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">` + "\n"
+
+	dateFormat = "2006-01-02T15:04:05Z"
+)
+
+// Document is an XML property list. Writing an unmodified document reproduces the content written by Xcode:
+// the prolog is kept as it is and the values are written in Xcode's format.
+type Document struct {
+	Root Value
+
+	prolog  string // Content before the plist element: the XML declaration, the doctype and comments
+	version string
+}
+
+// ParseXML parses the XML property list content.
+func ParseXML(content []byte) (*Document, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(content))
+	decoder.Strict = true
+
+	for {
+		offset := decoder.InputOffset()
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return nil, fmt.Errorf("no plist element found")
+		} else if err != nil {
+			return nil, err
+		}
+
+		element, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		if element.Name.Local != "plist" {
+			return nil, fmt.Errorf("root element is %s instead of plist", element.Name.Local)
+		}
+
+		document := &Document{
+			prolog:  string(content[:offset]),
+			version: "1.0",
+		}
+		for _, attr := range element.Attr {
+			if attr.Name.Local == "version" {
+				document.version = attr.Value
+			}
+		}
+
+		root, err := decodeXMLValue(decoder, nil)
+		if err != nil {
+			return nil, err
+		}
+		document.Root = root
+
+		return document, nil
+	}
+}
+
+// ReadXMLFile parses the XML property list at the given path.
+func ReadXMLFile(pth string) (*Document, error) {
+	content, err := fileutil.ReadBytesFromFile(pth)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read property list (%s), error: %s", pth, err)
+	}
+
+	document, err := ParseXML(content)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse property list (%s), error: %s", pth, err)
+	}
+	return document, nil
+}
+
+// NewDocument returns an XML property list with the given root value.
+func NewDocument(root Value) *Document {
+	return &Document{
+		Root:    root,
+		prolog:  xmlHeader + xmlDoctype,
+		version: "1.0",
+	}
+}
+
+// RootDict returns the root value, if it is a dict.
+func (document *Document) RootDict() (*Dict, error) {
+	dict, ok := document.Root.(*Dict)
+	if !ok {
+		return nil, fmt.Errorf("root value is not a dict")
+	}
+	return dict, nil
+}
+
+// Marshal returns the XML content of the property list.
+func (document *Document) Marshal() ([]byte, error) {
+	if err := checkValue(document.Root); err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(document.prolog)
+	buf.WriteString(`<plist version="` + escapeXML(document.version) + `">` + "\n")
+	if document.Root != nil {
+		writeXMLValue(&buf, document.Root, 0)
+	}
+	buf.WriteString("</plist>\n")
+
+	return buf.Bytes(), nil
+}
+
+// WriteXMLFile writes the XML content of the property list to the given path.
+func (document *Document) WriteXMLFile(pth string) error {
+	content, err := document.Marshal()
+	if err != nil {
+		return err
+	}
+
+	if err := fileutil.WriteBytesToFile(pth, content); err != nil {
+		return fmt.Errorf("failed to write property list (%s), error: %s", pth, err)
+	}
+	return nil
+}
+
+// decodeXMLValue decodes the next value, start is the value's start element if it is already read.
+func decodeXMLValue(decoder *xml.Decoder, start *xml.StartElement) (Value, error) {
+	if start == nil {
+		element, err := nextStartElement(decoder)
+		if err != nil {
+			return nil, err
+		}
+		if element == nil {
+			return nil, nil
+		}
+		start = element
+	}
+
+	switch start.Name.Local {
+	case "dict":
+		dict := NewDict()
+		for {
+			element, err := nextStartElement(decoder)
+			if err != nil {
+				return nil, err
+			}
+			if element == nil {
+				return dict, nil
+			}
+			if element.Name.Local != "key" {
+				return nil, fmt.Errorf("expected key in dict, got: %s", element.Name.Local)
+			}
+
+			key, err := elementText(decoder)
+			if err != nil {
+				return nil, err
+			}
+
+			valueElement, err := nextStartElement(decoder)
+			if err != nil {
+				return nil, err
+			}
+			if valueElement == nil {
+				return nil, fmt.Errorf("missing value of key: %s", key)
+			}
+
+			value, err := decodeXMLValue(decoder, valueElement)
+			if err != nil {
+				return nil, err
+			}
+			dict.Set(key, value)
+		}
+	case "array":
+		array := []Value{}
+		for {
+			element, err := nextStartElement(decoder)
+			if err != nil {
+				return nil, err
+			}
+			if element == nil {
+				return array, nil
+			}
+
+			value, err := decodeXMLValue(decoder, element)
+			if err != nil {
+				return nil, err
+			}
+			array = append(array, value)
+		}
+	case "true", "false":
+		if err := decoder.Skip(); err != nil {
+			return nil, err
+		}
+		return start.Name.Local == "true", nil
+	}
+
+	text, err := elementText(decoder)
+	if err != nil {
+		return nil, err
+	}
+
+	switch start.Name.Local {
+	case "string":
+		return text, nil
+	case "integer":
+		value, err := strconv.ParseInt(strings.TrimSpace(text), 0, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid integer: %s", text)
+		}
+		return value, nil
+	case "real":
+		value, err := strconv.ParseFloat(strings.TrimSpace(text), 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid real: %s", text)
+		}
+		return value, nil
+	case "date":
+		value, err := time.Parse(dateFormat, strings.TrimSpace(text))
+		if err != nil {
+			return nil, fmt.Errorf("invalid date: %s", text)
+		}
+		return value, nil
+	case "data":
+		value, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(text), ""))
+		if err != nil {
+			return nil, fmt.Errorf("invalid data: %s", err)
+		}
+		return value, nil
+	default:
+		return nil, fmt.Errorf("unknown property list element: %s", start.Name.Local)
+	}
+}
+
+// nextStartElement returns the next start element, or nil if the enclosing element ends first.
+func nextStartElement(decoder *xml.Decoder) (*xml.StartElement, error) {
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			return &t, nil
+		case xml.EndElement:
+			return nil, nil
+		}
+	}
+}
+
+// elementText returns the text content of the current element and reads its end element.
+func elementText(decoder *xml.Decoder) (string, error) {
+	var text strings.Builder
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return "", err
+		}
+
+		switch t := token.(type) {
+		case xml.CharData:
+			text.Write(t)
+		case xml.EndElement:
+			return text.String(), nil
+		case xml.StartElement:
+			return "", fmt.Errorf("unexpected element in %s", t.Name.Local)
+		}
+	}
+}
+
+func writeXMLValue(buf *bytes.Buffer, value Value, depth int) {
+	indent := strings.Repeat("\t", depth)
+
+	switch v := value.(type) {
+	case *Dict:
+		if v.Len() == 0 {
+			buf.WriteString(indent + "<dict/>\n")
+			return
+		}
+		buf.WriteString(indent + "<dict>\n")
+		for _, key := range v.keys {
+			buf.WriteString(indent + "\t<key>" + escapeXML(key) + "</key>\n")
+			writeXMLValue(buf, v.values[key], depth+1)
+		}
+		buf.WriteString(indent + "</dict>\n")
+	case []Value:
+		if len(v) == 0 {
+			buf.WriteString(indent + "<array/>\n")
+			return
+		}
+		buf.WriteString(indent + "<array>\n")
+		for _, item := range v {
+			writeXMLValue(buf, item, depth+1)
+		}
+		buf.WriteString(indent + "</array>\n")
+	case string:
+		buf.WriteString(indent + "<string>" + escapeXML(v) + "</string>\n")
+	case int64:
+		buf.WriteString(indent + "<integer>" + strconv.FormatInt(v, 10) + "</integer>\n")
+	case float64:
+		buf.WriteString(indent + "<real>" + strconv.FormatFloat(v, 'g', -1, 64) + "</real>\n")
+	case bool:
+		if v {
+			buf.WriteString(indent + "<true/>\n")
+		} else {
+			buf.WriteString(indent + "<false/>\n")
+		}
+	case time.Time:
+		buf.WriteString(indent + "<date>" + v.UTC().Format(dateFormat) + "</date>\n")
+	case []byte:
+		buf.WriteString(indent + "<data>\n")
+		encoded := base64.StdEncoding.EncodeToString(v)
+		for len(encoded) > 0 {
+			n := len(encoded)
+			if n > 68 {
+				n = 68
+			}
+			buf.WriteString(indent + encoded[:n] + "\n")
+			encoded = encoded[n:]
+		}
+		buf.WriteString(indent + "</data>\n")
+	}
+}
+
+// escapeXML escapes the text the way Xcode does: quotes are not escaped.
+func escapeXML(text string) string {
+	replacer := strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+	return replacer.Replace(text)
+}
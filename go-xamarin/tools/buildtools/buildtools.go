@@ -8,4 +8,6 @@ const (
 	Msbuild BuildTool = iota
 	// Xbuild ...
 	Xbuild
+	// Dotnet ...
+	Dotnet
 )
@@ -0,0 +1,181 @@
+package diagnostics
+
+import (
+	"bytes"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Severity ...
+type Severity string
+
+const (
+	// SeverityError ...
+	SeverityError Severity = "error"
+	// SeverityWarning ...
+	SeverityWarning Severity = "warning"
+)
+
+// Diagnostic is an error or warning reported by the build tool in the canonical MSBuild format:
+// file(line,col): error CS1234: message [project]
+type Diagnostic struct {
+	Project    string   `json:"project"`
+	ProjectPth string   `json:"project_path,omitempty"`
+	File       string   `json:"file,omitempty"`
+	Line       int      `json:"line,omitempty"`
+	Column     int      `json:"column,omitempty"`
+	Severity   Severity `json:"severity"`
+	Code       string   `json:"code,omitempty"`
+	Message    string   `json:"message"`
+}
+
+// String returns the diagnostic in the canonical MSBuild format.
+func (diagnostic Diagnostic) String() string {
+	s := ""
+	if diagnostic.File != "" {
+		s = diagnostic.File
+		if diagnostic.Line > 0 {
+			location := strconv.Itoa(diagnostic.Line)
+			if diagnostic.Column > 0 {
+				location += "," + strconv.Itoa(diagnostic.Column)
+			}
+			s += "(" + location + ")"
+		}
+		s += ": "
+	}
+
+	s += string(diagnostic.Severity)
+	if diagnostic.Code != "" {
+		s += " " + diagnostic.Code
+	}
+	return s + ": " + diagnostic.Message
+}
+
+// The canonical MSBuild diagnostic format:
+// origin[(line[,col[,endLine,endCol]])]: [subcategory ]error|warning [code]: text [project]
+// The origin is a file path or a tool name, the code is like CS1234, MSB3073 or XA5300.
+var diagnosticRegexp = regexp.MustCompile(`^\s*(?:(.*?)(?:\((\d+)(?:-\d+)?(?:,(\d+))?(?:[,-]\d+)*\))?\s*:\s*)?(?:[A-Za-z][A-Za-z ]*\s)?(error|warning)(?:\s+([A-Z]+\d+))?\s*:\s*(.*?)(?:\s+\[([^\]]+)\])?\s*$`)
+
+// Parse parses a line of the build output, the second return value is false if the line is not a diagnostic.
+// A line is only a diagnostic if it has a code or its origin has a location,
+// so that log lines like `Task "Exec": The command returned error: ...` are not reported.
+func Parse(line string) (Diagnostic, bool) {
+	match := diagnosticRegexp.FindStringSubmatch(line)
+	if match == nil {
+		return Diagnostic{}, false
+	}
+
+	lineNumber, _ := strconv.Atoi(match[2])
+	column, _ := strconv.Atoi(match[3])
+	code := match[5]
+
+	if code == "" && lineNumber == 0 {
+		return Diagnostic{}, false
+	}
+
+	return Diagnostic{
+		ProjectPth: match[7],
+		File:       strings.TrimSpace(match[1]),
+		Line:       lineNumber,
+		Column:     column,
+		Severity:   Severity(match[4]),
+		Code:       code,
+		Message:    match[6],
+	}, true
+}
+
+// Collector collects the diagnostics of the build commands, without duplicates.
+// MSBuild repeats every diagnostic in the summary at the end of the build.
+type Collector struct {
+	mutex       sync.Mutex
+	diagnostics []Diagnostic
+	seen        map[Diagnostic]bool
+}
+
+// NewCollector ...
+func NewCollector() *Collector {
+	return &Collector{
+		seen: map[Diagnostic]bool{},
+	}
+}
+
+// Add ...
+func (collector *Collector) Add(diagnostic Diagnostic) {
+	collector.mutex.Lock()
+	defer collector.mutex.Unlock()
+
+	if collector.seen[diagnostic] {
+		return
+	}
+	collector.seen[diagnostic] = true
+	collector.diagnostics = append(collector.diagnostics, diagnostic)
+}
+
+// Diagnostics returns the collected diagnostics in the order of reporting.
+func (collector *Collector) Diagnostics() []Diagnostic {
+	collector.mutex.Lock()
+	defer collector.mutex.Unlock()
+
+	return append([]Diagnostic{}, collector.diagnostics...)
+}
+
+// Writer returns a writer parsing the output of the given project's build command line by line.
+// The writer has to be closed to parse the last, unterminated line.
+func (collector *Collector) Writer(projectName string) *Writer {
+	return &Writer{
+		collector:   collector,
+		projectName: projectName,
+	}
+}
+
+// Writer parses the written build output and adds the found diagnostics to the collector.
+type Writer struct {
+	collector   *Collector
+	projectName string
+
+	mutex sync.Mutex
+	buf   bytes.Buffer
+}
+
+// Write ...
+func (writer *Writer) Write(p []byte) (int, error) {
+	writer.mutex.Lock()
+	defer writer.mutex.Unlock()
+
+	writer.buf.Write(p)
+	for {
+		index := bytes.IndexByte(writer.buf.Bytes(), '\n')
+		if index < 0 {
+			break
+		}
+
+		line := string(writer.buf.Next(index + 1))
+		writer.parse(line)
+	}
+
+	return len(p), nil
+}
+
+// Close parses the remaining output.
+func (writer *Writer) Close() error {
+	writer.mutex.Lock()
+	defer writer.mutex.Unlock()
+
+	if writer.buf.Len() > 0 {
+		writer.parse(writer.buf.String())
+		writer.buf.Reset()
+	}
+	return nil
+}
+
+func (writer *Writer) parse(line string) {
+	diagnostic, ok := Parse(strings.TrimRight(line, "\r\n"))
+	if !ok {
+		return
+	}
+
+	diagnostic.Project = writer.projectName
+	writer.collector.Add(diagnostic)
+}
@@ -0,0 +1,122 @@
+package diagnostics
+
+import "testing"
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name   string
+		line   string
+		want   Diagnostic
+		wantOk bool
+	}{
+		{
+			name: "compiler error",
+			line: "/src/App/MainPage.cs(12,5): error CS0103: The name 'Foo' does not exist in the current context [/src/App/App.csproj]",
+			want: Diagnostic{
+				ProjectPth: "/src/App/App.csproj",
+				File:       "/src/App/MainPage.cs",
+				Line:       12,
+				Column:     5,
+				Severity:   SeverityError,
+				Code:       "CS0103",
+				Message:    "The name 'Foo' does not exist in the current context",
+			},
+			wantOk: true,
+		},
+		{
+			name: "warning with line range and target framework",
+			line: "  /src/App/Model.cs(3-4,1-8): warning CS8618: Non-nullable property [/src/App/App.csproj::TargetFramework=net8.0-ios]",
+			want: Diagnostic{
+				ProjectPth: "/src/App/App.csproj::TargetFramework=net8.0-ios",
+				File:       "/src/App/Model.cs",
+				Line:       3,
+				Column:     1,
+				Severity:   SeverityWarning,
+				Code:       "CS8618",
+				Message:    "Non-nullable property",
+			},
+			wantOk: true,
+		},
+		{
+			name: "tool origin with code",
+			line: "MSBUILD : error MSB1009: Project file does not exist.",
+			want: Diagnostic{
+				File:     "MSBUILD",
+				Severity: SeverityError,
+				Code:     "MSB1009",
+				Message:  "Project file does not exist.",
+			},
+			wantOk: true,
+		},
+		{
+			name: "subcategory",
+			line: "/usr/share/dotnet/sdk/Microsoft.Common.targets(4650,5): fatal error MSB3030: Could not copy the file",
+			want: Diagnostic{
+				File:     "/usr/share/dotnet/sdk/Microsoft.Common.targets",
+				Line:     4650,
+				Column:   5,
+				Severity: SeverityError,
+				Code:     "MSB3030",
+				Message:  "Could not copy the file",
+			},
+			wantOk: true,
+		},
+		{
+			name: "no origin",
+			line: "error NETSDK1045: The current .NET SDK does not support targeting .NET 9.0.",
+			want: Diagnostic{
+				Severity: SeverityError,
+				Code:     "NETSDK1045",
+				Message:  "The current .NET SDK does not support targeting .NET 9.0.",
+			},
+			wantOk: true,
+		},
+		{
+			name: "location without code",
+			line: "/src/App/Resources/layout/main.xml(7): error: No resource found",
+			want: Diagnostic{
+				File:     "/src/App/Resources/layout/main.xml",
+				Line:     7,
+				Severity: SeverityError,
+				Message:  "No resource found",
+			},
+			wantOk: true,
+		},
+		{
+			name: "task output",
+			line: `  Task "Exec": The command returned error: something`,
+		},
+		{
+			name: "no code and no location",
+			line: "EXEC : error : something went wrong",
+		},
+		{
+			name: "summary count",
+			line: "    0 Warning(s)",
+		},
+		{
+			name: "errors word",
+			line: "Build FAILED with errors: see above",
+		},
+		{
+			name: "lowercase code",
+			line: "Program: error cs0103: The name does not exist",
+		},
+		{
+			name: "plain log line",
+			line: "Restored /src/App/App.csproj (in 120 ms).",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := Parse(tt.line)
+			if ok != tt.wantOk {
+				t.Fatalf("Parse(%q) ok = %v, want %v (diagnostic: %+v)", tt.line, ok, tt.wantOk, got)
+			}
+			if got != tt.want {
+				t.Errorf("Parse(%q) = %+v, want %+v", tt.line, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,196 @@
+package dotnet
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path/filepath"
+
+	"github.com/bitrise-io/go-utils/command"
+	"github.com/bitrise-io/go-utils/pathutil"
+	"github.com/bitrise-steplib/steps-xamarin-archive/go-xamarin/tools"
+	"github.com/bitrise-steplib/steps-xamarin-archive/go-xamarin/tools/toolpath"
+)
+
+// Command ...
+type Command string
+
+const (
+	// Build ...
+	Build Command = "build"
+	// Publish ...
+	Publish Command = "publish"
+)
+
+// Model ...
+type Model struct {
+	BuildTool string
+
+	SolutionPth       string
+	SolutionFilterPth string // Built instead of the solution, if set
+	ProjectPth        string
+
+	command           Command
+	target            string
+	configuration     string
+	platform          string
+	targetFramework   string
+	runtimeIdentifier string
+
+	properties    []string
+	customOptions []string
+}
+
+// New ...
+func New(solutionPth, projectPth string) (*Model, error) {
+	absSolutionPth, err := pathutil.AbsPath(solutionPth)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to expand path (%s), error: %s", solutionPth, err)
+	}
+
+	absProjectPth := ""
+	if projectPth != "" {
+		absPth, err := pathutil.AbsPath(projectPth)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to expand path (%s), error: %s", projectPth, err)
+		}
+		absProjectPth = absPth
+	}
+
+	buildTool, err := toolpath.Path(toolpath.Dotnet)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Model{SolutionPth: absSolutionPth, ProjectPth: absProjectPth, BuildTool: buildTool, command: Build}, nil
+}
+
+// SetCommand ...
+func (dotnet *Model) SetCommand(command Command) *Model {
+	dotnet.command = command
+	return dotnet
+}
+
+// Command ...
+func (dotnet Model) Command() Command {
+	return dotnet.command
+}
+
+// SetSolutionFilter sets the solution filter (.slnf) to build instead of the solution, if no project is given.
+// The SolutionDir property still points to the solution's dir.
+func (dotnet *Model) SetSolutionFilter(pth string) *Model {
+	dotnet.SolutionFilterPth = pth
+	return dotnet
+}
+
+// SetTarget ...
+func (dotnet *Model) SetTarget(target string) *Model {
+	dotnet.target = target
+	return dotnet
+}
+
+// Target ...
+func (dotnet Model) Target() string {
+	return dotnet.target
+}
+
+// SetConfiguration ...
+func (dotnet *Model) SetConfiguration(configuration string) *Model {
+	dotnet.configuration = configuration
+	return dotnet
+}
+
+// SetPlatform ...
+func (dotnet *Model) SetPlatform(platform string) *Model {
+	dotnet.platform = platform
+	return dotnet
+}
+
+// SetTargetFramework ...
+func (dotnet *Model) SetTargetFramework(targetFramework string) *Model {
+	dotnet.targetFramework = targetFramework
+	return dotnet
+}
+
+// SetRuntimeIdentifier ...
+func (dotnet *Model) SetRuntimeIdentifier(runtimeIdentifier string) *Model {
+	dotnet.runtimeIdentifier = runtimeIdentifier
+	return dotnet
+}
+
+// SetProperty adds an MSBuild property (-p:name=value), properties are passed in the order of setting.
+// The value of a sensitive property is registered as a secret.
+func (dotnet *Model) SetProperty(name, value string) *Model {
+	property := fmt.Sprintf("-p:%s=%s", name, value)
+	tools.RegisterSensitiveArgs(property)
+	dotnet.properties = append(dotnet.properties, property)
+	return dotnet
+}
+
+// SetSecretProperty adds an MSBuild property like SetProperty, and registers its value as a secret,
+// to mask it in the printed command and the command output.
+func (dotnet *Model) SetSecretProperty(name, value string) *Model {
+	tools.RegisterSecrets(value)
+	return dotnet.SetProperty(name, value)
+}
+
+// SetCustomOptions sets the options appended to the command, the values of their sensitive properties are registered as secrets.
+func (dotnet *Model) SetCustomOptions(options ...string) {
+	tools.RegisterSensitiveArgs(options...)
+	dotnet.customOptions = options
+}
+
+func (dotnet Model) buildCommands() []string {
+	cmdSlice := []string{dotnet.BuildTool, string(dotnet.command)}
+
+	if dotnet.ProjectPth != "" {
+		cmdSlice = append(cmdSlice, dotnet.ProjectPth)
+	} else if dotnet.SolutionFilterPth != "" {
+		cmdSlice = append(cmdSlice, dotnet.SolutionFilterPth)
+	} else {
+		cmdSlice = append(cmdSlice, dotnet.SolutionPth)
+	}
+
+	if dotnet.targetFramework != "" {
+		cmdSlice = append(cmdSlice, "-f", dotnet.targetFramework)
+	}
+
+	if dotnet.configuration != "" {
+		cmdSlice = append(cmdSlice, "-c", dotnet.configuration)
+	}
+
+	if dotnet.runtimeIdentifier != "" {
+		cmdSlice = append(cmdSlice, "-r", dotnet.runtimeIdentifier)
+	}
+
+	if dotnet.target != "" {
+		cmdSlice = append(cmdSlice, fmt.Sprintf("-t:%s", dotnet.target))
+	}
+
+	cmdSlice = append(cmdSlice, fmt.Sprintf("-p:SolutionDir=%s", filepath.Dir(dotnet.SolutionPth)+string(filepath.Separator)))
+
+	if dotnet.platform != "" {
+		cmdSlice = append(cmdSlice, fmt.Sprintf("-p:Platform=%s", dotnet.platform))
+	}
+
+	cmdSlice = append(cmdSlice, dotnet.properties...)
+	cmdSlice = append(cmdSlice, dotnet.customOptions...)
+
+	return cmdSlice
+}
+
+// String returns the printable command, with the secrets masked.
+func (dotnet Model) String() string {
+	cmdSlice := tools.RedactArgs(dotnet.buildCommands())
+	return command.PrintableCommandArgs(true, cmdSlice)
+}
+
+// Run ...
+func (dotnet Model) Run(outWriter, errWriter io.Writer) error {
+	return dotnet.RunWithContext(context.Background(), outWriter, errWriter)
+}
+
+// RunWithContext runs the command until it finishes or the context is done, in which case the command's process tree is killed.
+func (dotnet Model) RunWithContext(ctx context.Context, outWriter, errWriter io.Writer) error {
+	return tools.RunCommandSlice(ctx, dotnet.buildCommands(), outWriter, errWriter)
+}
@@ -4,8 +4,8 @@ import (
 	"fmt"
 
 	"github.com/bitrise-io/go-utils/pathutil"
-	"github.com/toggl/go-xamarin/constants"
-	"github.com/toggl/go-xamarin/tools/buildtools/xbuild"
+	"github.com/bitrise-steplib/steps-xamarin-archive/go-xamarin/tools/buildtools/xbuild"
+	"github.com/bitrise-steplib/steps-xamarin-archive/go-xamarin/tools/toolpath"
 )
 
 // New ...
@@ -24,5 +24,10 @@ func New(solutionPth, projectPth string) (*xbuild.Model, error) {
 		absProjectPth = absPth
 	}
 
-	return &xbuild.Model{SolutionPth: absSolutionPth, ProjectPth: absProjectPth, BuildTool: constants.MsbuildPath}, nil
+	buildTool, err := toolpath.Path(toolpath.Msbuild)
+	if err != nil {
+		return nil, err
+	}
+
+	return &xbuild.Model{SolutionPth: absSolutionPth, ProjectPth: absProjectPth, BuildTool: buildTool}, nil
 }
@@ -1,30 +1,34 @@
 package xbuild
 
 import (
+	"context"
 	"fmt"
 	"io"
-	"os"
 	"path/filepath"
 
 	"github.com/bitrise-io/go-utils/command"
 	"github.com/bitrise-io/go-utils/pathutil"
-	"github.com/toggl/go-xamarin/constants"
+	"github.com/bitrise-steplib/steps-xamarin-archive/go-xamarin/tools"
+	"github.com/bitrise-steplib/steps-xamarin-archive/go-xamarin/tools/toolpath"
 )
 
 // Model ...
 type Model struct {
 	BuildTool string
 
-	SolutionPth string
-	ProjectPth  string
+	SolutionPth       string
+	SolutionFilterPth string // Built instead of the solution, if set
+	ProjectPth        string
 
-	target        string
-	configuration string
-	platform      string
+	target          string
+	configuration   string
+	platform        string
+	targetFramework string
 
 	buildIpa       bool
 	archiveOnBuild bool
 
+	properties    []string
 	customOptions []string
 }
 
@@ -44,7 +48,19 @@ func New(solutionPth, projectPth string) (*Model, error) {
 		absProjectPth = absPth
 	}
 
-	return &Model{SolutionPth: absSolutionPth, ProjectPth: absProjectPth, BuildTool: constants.XbuildPath}, nil
+	buildTool, err := toolpath.Path(toolpath.Xbuild)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Model{SolutionPth: absSolutionPth, ProjectPth: absProjectPth, BuildTool: buildTool}, nil
+}
+
+// SetSolutionFilter sets the solution filter (.slnf) to build instead of the solution, if no project is given.
+// The SolutionDir property still points to the solution's dir.
+func (xbuild *Model) SetSolutionFilter(pth string) *Model {
+	xbuild.SolutionFilterPth = pth
+	return xbuild
 }
 
 // SetTarget ...
@@ -53,6 +69,11 @@ func (xbuild *Model) SetTarget(target string) *Model {
 	return xbuild
 }
 
+// Target ...
+func (xbuild Model) Target() string {
+	return xbuild.target
+}
+
 // SetConfiguration ...
 func (xbuild *Model) SetConfiguration(configuration string) *Model {
 	xbuild.configuration = configuration
@@ -65,6 +86,12 @@ func (xbuild *Model) SetPlatform(platform string) *Model {
 	return xbuild
 }
 
+// SetTargetFramework ...
+func (xbuild *Model) SetTargetFramework(targetFramework string) *Model {
+	xbuild.targetFramework = targetFramework
+	return xbuild
+}
+
 // SetBuildIpa ...
 func (xbuild *Model) SetBuildIpa(buildIpa bool) *Model {
 	xbuild.buildIpa = buildIpa
@@ -77,8 +104,25 @@ func (xbuild *Model) SetArchiveOnBuild(archive bool) *Model {
 	return xbuild
 }
 
-// SetCustomOptions ...
+// SetProperty adds an MSBuild property (/p:name=value), properties are passed in the order of setting.
+// The value of a sensitive property is registered as a secret.
+func (xbuild *Model) SetProperty(name, value string) *Model {
+	property := fmt.Sprintf("/p:%s=%s", name, value)
+	tools.RegisterSensitiveArgs(property)
+	xbuild.properties = append(xbuild.properties, property)
+	return xbuild
+}
+
+// SetSecretProperty adds an MSBuild property like SetProperty, and registers its value as a secret,
+// to mask it in the printed command and the command output.
+func (xbuild *Model) SetSecretProperty(name, value string) *Model {
+	tools.RegisterSecrets(value)
+	return xbuild.SetProperty(name, value)
+}
+
+// SetCustomOptions sets the options appended to the command, the values of their sensitive properties are registered as secrets.
 func (xbuild *Model) SetCustomOptions(options ...string) {
+	tools.RegisterSensitiveArgs(options...)
 	xbuild.customOptions = options
 }
 
@@ -87,6 +131,8 @@ func (xbuild Model) buildCommands() []string {
 
 	if xbuild.ProjectPth != "" {
 		cmdSlice = append(cmdSlice, xbuild.ProjectPth)
+	} else if xbuild.SolutionFilterPth != "" {
+		cmdSlice = append(cmdSlice, xbuild.SolutionFilterPth)
 	} else {
 		cmdSlice = append(cmdSlice, xbuild.SolutionPth)
 	}
@@ -105,6 +151,10 @@ func (xbuild Model) buildCommands() []string {
 		cmdSlice = append(cmdSlice, fmt.Sprintf("/p:Platform=%s", xbuild.platform))
 	}
 
+	if xbuild.targetFramework != "" {
+		cmdSlice = append(cmdSlice, fmt.Sprintf("/p:TargetFramework=%s", xbuild.targetFramework))
+	}
+
 	if xbuild.archiveOnBuild {
 		cmdSlice = append(cmdSlice, "/p:ArchiveOnBuild=true")
 	}
@@ -113,6 +163,7 @@ func (xbuild Model) buildCommands() []string {
 		cmdSlice = append(cmdSlice, "/p:BuildIpa=true")
 	}
 
+	cmdSlice = append(cmdSlice, xbuild.properties...)
 	cmdSlice = append(cmdSlice, xbuild.customOptions...)
 
 	//cmdSlice = append(cmdSlice, "/verbosity:minimal", "/nologo")
@@ -120,30 +171,18 @@ func (xbuild Model) buildCommands() []string {
 	return cmdSlice
 }
 
-// String ...
+// String returns the printable command, with the secrets masked.
 func (xbuild Model) String() string {
-	cmdSlice := xbuild.buildCommands()
+	cmdSlice := tools.RedactArgs(xbuild.buildCommands())
 	return command.PrintableCommandArgs(true, cmdSlice)
 }
 
 // Run ...
 func (xbuild Model) Run(outWriter, errWriter io.Writer) error {
-	if outWriter == nil {
-		outWriter = os.Stdout
-	}
-	if errWriter == nil {
-		errWriter = os.Stderr
-	}
-
-	cmdSlice := xbuild.buildCommands()
-
-	command, err := command.NewFromSlice(cmdSlice)
-	if err != nil {
-		return err
-	}
-
-	command.SetStdout(outWriter)
-	command.SetStderr(errWriter)
+	return xbuild.RunWithContext(context.Background(), outWriter, errWriter)
+}
 
-	return command.Run()
+// RunWithContext runs the command until it finishes or the context is done, in which case the command's process tree is killed.
+func (xbuild Model) RunWithContext(ctx context.Context, outWriter, errWriter io.Writer) error {
+	return tools.RunCommandSlice(ctx, xbuild.buildCommands(), outWriter, errWriter)
 }
@@ -1,6 +1,7 @@
 package nunit
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"os"
@@ -8,7 +9,8 @@ import (
 
 	"github.com/bitrise-io/go-utils/command"
 	"github.com/bitrise-io/go-utils/pathutil"
-	"github.com/toggl/go-xamarin/constants"
+	"github.com/bitrise-steplib/steps-xamarin-archive/go-xamarin/tools"
+	"github.com/bitrise-steplib/steps-xamarin-archive/go-xamarin/tools/toolpath"
 )
 
 const (
@@ -17,6 +19,7 @@ const (
 
 // Model ...
 type Model struct {
+	monoPth         string
 	nunitConsolePth string
 
 	projectPth string
@@ -54,7 +57,12 @@ func New(nunitConsolePth string) (*Model, error) {
 		return nil, fmt.Errorf("Failed to expand path (%s), error: %s", nunitConsolePth, err)
 	}
 
-	return &Model{nunitConsolePth: absNunitConsolePth}, nil
+	monoPth, err := toolpath.Path(toolpath.Mono)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Model{monoPth: monoPth, nunitConsolePth: absNunitConsolePth}, nil
 }
 
 // SetProjectPth ...
@@ -87,13 +95,14 @@ func (nunitConsole *Model) SetResultLogPth(resultLogPth string) *Model {
 	return nunitConsole
 }
 
-// SetCustomOptions ...
+// SetCustomOptions sets the options appended to the command, the values of their sensitive properties are registered as secrets.
 func (nunitConsole *Model) SetCustomOptions(options ...string) {
+	tools.RegisterSensitiveArgs(options...)
 	nunitConsole.customOptions = options
 }
 
 func (nunitConsole Model) commandSlice() []string {
-	cmdSlice := []string{constants.MonoPath}
+	cmdSlice := []string{nunitConsole.monoPth}
 	cmdSlice = append(cmdSlice, nunitConsole.nunitConsolePth)
 
 	if nunitConsole.projectPth != "" {
@@ -118,30 +127,18 @@ func (nunitConsole Model) commandSlice() []string {
 	return cmdSlice
 }
 
-// String ...
+// String returns the printable command, with the secrets masked.
 func (nunitConsole Model) String() string {
-	cmdSlice := nunitConsole.commandSlice()
+	cmdSlice := tools.RedactArgs(nunitConsole.commandSlice())
 	return command.PrintableCommandArgs(true, cmdSlice)
 }
 
 // Run ...
 func (nunitConsole Model) Run(outWriter, errWriter io.Writer) error {
-	if outWriter == nil {
-		outWriter = os.Stdout
-	}
-	if errWriter == nil {
-		errWriter = os.Stderr
-	}
-
-	cmdSlice := nunitConsole.commandSlice()
-
-	command, err := command.NewFromSlice(cmdSlice)
-	if err != nil {
-		return err
-	}
-
-	command.SetStdout(outWriter)
-	command.SetStderr(errWriter)
+	return nunitConsole.RunWithContext(context.Background(), outWriter, errWriter)
+}
 
-	return command.Run()
+// RunWithContext runs the command until it finishes or the context is done, in which case the command's process tree is killed.
+func (nunitConsole Model) RunWithContext(ctx context.Context, outWriter, errWriter io.Writer) error {
+	return tools.RunCommandSlice(ctx, nunitConsole.commandSlice(), outWriter, errWriter)
 }
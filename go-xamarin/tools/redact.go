@@ -0,0 +1,215 @@
+package tools
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/bitrise-io/go-utils/log"
+)
+
+// minSecretLength is the length of the shortest secret masked in the outputs: shorter values, like 123 or true,
+// are part of unrelated output too, masking them would make the build log unreadable.
+const minSecretLength = 6
+
+// sensitiveNameParts are the parts of the names of MSBuild properties holding secrets,
+// like AndroidSigningStorePass, ApiSecretValue or SigningPasswordFile.
+var sensitiveNameParts = []string{"pass", "secret"}
+
+// nonSensitivePropertyNames are the lower case names of the properties matching a sensitive name part,
+// which are known to not hold secrets.
+var nonSensitivePropertyNames = map[string]bool{
+	"passthrough":                  true,
+	"bypassframeworkinstallchecks": true,
+}
+
+// propertyArgRegexp matches an MSBuild property argument: /p:, -p:, /property: or -property:, followed by
+// semicolon separated name=value pairs.
+var propertyArgRegexp = regexp.MustCompile(`(?i)^([/-](?:p|property):)(.*)$`)
+
+// secretRegistry holds the values to mask in every printed command and command output.
+var secretRegistry = struct {
+	sync.RWMutex
+	values map[string]bool
+	short  map[string]bool
+}{values: map[string]bool{}, short: map[string]bool{}}
+
+// RegisterSecrets adds values to mask in the printed commands and command outputs, empty values are ignored.
+// Values shorter than minSecretLength are not masked, a warning is printed instead.
+func RegisterSecrets(values ...string) {
+	secretRegistry.Lock()
+	defer secretRegistry.Unlock()
+
+	for _, value := range values {
+		if strings.TrimSpace(value) == "" {
+			continue
+		}
+		if len(value) < minSecretLength {
+			if !secretRegistry.short[value] {
+				secretRegistry.short[value] = true
+				log.Warnf("A secret is shorter than %d characters, it is not masked in the build output", minSecretLength)
+			}
+			continue
+		}
+		secretRegistry.values[value] = true
+	}
+}
+
+// RegisterSecretEnvs registers the values of the given environment variables as secrets.
+func RegisterSecretEnvs(names ...string) {
+	for _, name := range names {
+		RegisterSecrets(os.Getenv(name))
+	}
+}
+
+// IsSensitivePropertyName returns true if the name of the MSBuild property suggests that its value is a secret:
+// it contains a sensitive name part, case insensitively, and it is not a known non-sensitive name.
+func IsSensitivePropertyName(name string) bool {
+	name = strings.ToLower(name)
+	if nonSensitivePropertyNames[name] {
+		return false
+	}
+	for _, part := range sensitiveNameParts {
+		if strings.Contains(name, part) {
+			return true
+		}
+	}
+	return false
+}
+
+// sensitivePropertyValues returns the non-empty values of the sensitive MSBuild properties of the command arg.
+func sensitivePropertyValues(arg string) []string {
+	match := propertyArgRegexp.FindStringSubmatch(arg)
+	if match == nil {
+		return nil
+	}
+
+	values := []string{}
+	for _, property := range strings.Split(match[2], ";") {
+		split := strings.SplitN(property, "=", 2)
+		if len(split) == 2 && IsSensitivePropertyName(split[0]) && split[1] != "" {
+			values = append(values, split[1])
+		}
+	}
+	return values
+}
+
+// RegisterSensitiveArgs registers the values of the sensitive MSBuild properties of the command args as secrets,
+// to mask them in the command output too. The commands call it when their args are set.
+func RegisterSensitiveArgs(cmdSlice ...string) {
+	for _, arg := range cmdSlice {
+		RegisterSecrets(sensitivePropertyValues(arg)...)
+	}
+}
+
+// secrets returns the registered values, the longest first, to mask a value before its substrings.
+func secrets() []string {
+	secretRegistry.RLock()
+	defer secretRegistry.RUnlock()
+
+	values := make([]string, 0, len(secretRegistry.values))
+	for value := range secretRegistry.values {
+		values = append(values, value)
+	}
+	sort.Slice(values, func(i, j int) bool {
+		if len(values[i]) != len(values[j]) {
+			return len(values[i]) > len(values[j])
+		}
+		return values[i] < values[j]
+	})
+	return values
+}
+
+// Redact returns the text with the registered secrets masked.
+func Redact(text string) string {
+	for _, secret := range secrets() {
+		text = strings.Replace(text, secret, SecretMask, -1)
+	}
+	return text
+}
+
+// RedactArgs returns the command args to print: the values of the sensitive MSBuild properties and the registered secrets
+// are masked.
+func RedactArgs(cmdSlice []string) []string {
+	redacted := make([]string, 0, len(cmdSlice))
+	for _, arg := range cmdSlice {
+		if match := propertyArgRegexp.FindStringSubmatch(arg); match != nil {
+			properties := strings.Split(match[2], ";")
+			for i, property := range properties {
+				split := strings.SplitN(property, "=", 2)
+				if len(split) == 2 && IsSensitivePropertyName(split[0]) && split[1] != "" {
+					properties[i] = split[0] + "=" + SecretMask
+				}
+			}
+			arg = match[1] + strings.Join(properties, ";")
+		}
+		redacted = append(redacted, Redact(arg))
+	}
+	return redacted
+}
+
+// RedactJSON returns the JSON content with the registered secrets masked in its string values.
+func RedactJSON(content []byte) []byte {
+	for _, secret := range secrets() {
+		encoded, err := json.Marshal(secret)
+		if err != nil {
+			continue
+		}
+		// the encoded value without the quotes
+		content = bytes.Replace(content, encoded[1:len(encoded)-1], []byte(SecretMask), -1)
+	}
+	return content
+}
+
+// RedactWriter masks the registered secrets in the written content before passing it to the underlying writer.
+// The content is passed line by line, to mask the secrets split between writes: Close writes the last unfinished line.
+type RedactWriter struct {
+	mutex  sync.Mutex
+	writer io.Writer
+	buf    []byte
+}
+
+// NewRedactWriter ...
+func NewRedactWriter(writer io.Writer) *RedactWriter {
+	return &RedactWriter{writer: writer}
+}
+
+// Write ...
+func (writer *RedactWriter) Write(p []byte) (int, error) {
+	writer.mutex.Lock()
+	defer writer.mutex.Unlock()
+
+	writer.buf = append(writer.buf, p...)
+
+	end := bytes.LastIndexByte(writer.buf, '\n')
+	if end == -1 {
+		return len(p), nil
+	}
+
+	lines := writer.buf[:end+1]
+	writer.buf = append([]byte{}, writer.buf[end+1:]...)
+
+	if _, err := io.WriteString(writer.writer, Redact(string(lines))); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Close writes the buffered content.
+func (writer *RedactWriter) Close() error {
+	writer.mutex.Lock()
+	defer writer.mutex.Unlock()
+
+	if len(writer.buf) == 0 {
+		return nil
+	}
+
+	_, err := io.WriteString(writer.writer, Redact(string(writer.buf)))
+	writer.buf = nil
+	return err
+}
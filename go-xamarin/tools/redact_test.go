@@ -0,0 +1,144 @@
+package tools
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestIsSensitivePropertyName(t *testing.T) {
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{name: "AndroidSigningStorePass", want: true},
+		{name: "AndroidSigningKeyPass", want: true},
+		{name: "KeystorePassword", want: true},
+		{name: "KEY_PASSWORD", want: true},
+		{name: "client-secret", want: true},
+		{name: "Password", want: true},
+		{name: "ApiSecretValue", want: true},
+		{name: "SigningPasswordFile", want: true},
+		{name: "apisecretvalue", want: true},
+		{name: "PassThrough", want: false},
+		{name: "BypassFrameworkInstallChecks", want: false},
+		{name: "AndroidSigningKeyAlias", want: false},
+		{name: "", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsSensitivePropertyName(tt.name); got != tt.want {
+				t.Errorf("IsSensitivePropertyName(%q) = %v, want %v", tt.name, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRedact(t *testing.T) {
+	RegisterSecrets("keystore-secret", "keystore-secret-2", "123", "  ")
+
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{
+			name: "registered secret",
+			text: "signing with keystore-secret",
+			want: "signing with " + SecretMask,
+		},
+		{
+			name: "longest secret first",
+			text: "keystore-secret-2 and keystore-secret",
+			want: SecretMask + " and " + SecretMask,
+		},
+		{
+			name: "short secret is not masked",
+			text: "Build 123 succeeded",
+			want: "Build 123 succeeded",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Redact(tt.text); got != tt.want {
+				t.Errorf("Redact(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRedactArgs(t *testing.T) {
+	RegisterSecrets("keystore-secret")
+
+	tests := []struct {
+		name    string
+		cmdArgs []string
+		want    []string
+	}{
+		{
+			name:    "sensitive properties",
+			cmdArgs: []string{"dotnet", "publish", "/p:AndroidSigningKeyAlias=key;AndroidSigningStorePass=store-password", "-p:AndroidSigningKeyPass=pwd"},
+			want:    []string{"dotnet", "publish", "/p:AndroidSigningKeyAlias=key;AndroidSigningStorePass=" + SecretMask, "-p:AndroidSigningKeyPass=" + SecretMask},
+		},
+		{
+			name:    "non sensitive properties",
+			cmdArgs: []string{"msbuild", "/property:PassThrough=true;BypassFrameworkInstallChecks=true"},
+			want:    []string{"msbuild", "/property:PassThrough=true;BypassFrameworkInstallChecks=true"},
+		},
+		{
+			name:    "registered value in other args",
+			cmdArgs: []string{"echo", "keystore-secret"},
+			want:    []string{"echo", SecretMask},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := RedactArgs(tt.cmdArgs); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("RedactArgs(%v) = %v, want %v", tt.cmdArgs, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRegisterSensitiveArgs(t *testing.T) {
+	RedactArgs([]string{"-p:ApiSecretValue=unregistered-value"})
+	if got := Redact("unregistered-value"); got != "unregistered-value" {
+		t.Errorf("RedactArgs() registered the property value, Redact() = %q", got)
+	}
+
+	RegisterSensitiveArgs("-p:AndroidSigningKeyAlias=key-alias;AndroidSigningStorePass=store-password", "registered-arg")
+	if got, want := Redact("store-password key-alias registered-arg"), SecretMask+" key-alias registered-arg"; got != want {
+		t.Errorf("Redact() = %q, want %q", got, want)
+	}
+}
+
+func TestRedactJSON(t *testing.T) {
+	RegisterSecrets(`quote"secret`)
+
+	got := RedactJSON([]byte(`{"value":"quote\"secret"}`))
+	if want := `{"value":"` + SecretMask + `"}`; string(got) != want {
+		t.Errorf("RedactJSON() = %s, want %s", got, want)
+	}
+}
+
+func TestRedactWriter(t *testing.T) {
+	RegisterSecrets("split-secret")
+
+	var buf bytes.Buffer
+	writer := NewRedactWriter(&buf)
+	for _, chunk := range []string{"line with split-", "secret\nlast split-sec", "ret"} {
+		if _, err := writer.Write([]byte(chunk)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if want := "line with " + SecretMask + "\nlast " + SecretMask; buf.String() != want {
+		t.Errorf("RedactWriter wrote %q, want %q", buf.String(), want)
+	}
+}
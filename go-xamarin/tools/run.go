@@ -0,0 +1,61 @@
+package tools
+
+import (
+	"context"
+	"io"
+	"os"
+	"time"
+
+	"github.com/bitrise-io/go-utils/command"
+)
+
+// killWaitTimeout is how long RunCommandSlice waits for the killed command to finish: a process which left the killed
+// process group might keep the command's outputs open.
+var killWaitTimeout = 10 * time.Second
+
+// RunCommandSlice runs the given command, until it finishes or the context is done.
+// When the context is done, the command's whole process tree is killed and the context's error is returned.
+func RunCommandSlice(ctx context.Context, cmdSlice []string, outWriter, errWriter io.Writer) error {
+	if outWriter == nil {
+		outWriter = os.Stdout
+	}
+	if errWriter == nil {
+		errWriter = os.Stderr
+	}
+
+	cmd, err := command.NewFromSlice(cmdSlice)
+	if err != nil {
+		return err
+	}
+
+	cmd.SetStdout(outWriter)
+	cmd.SetStderr(errWriter)
+
+	execCmd := cmd.GetCmd()
+	setProcessGroup(execCmd)
+
+	if err := execCmd.Start(); err != nil {
+		return err
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- execCmd.Wait()
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		killProcessGroup(execCmd)
+
+		timer := time.NewTimer(killWaitTimeout)
+		defer timer.Stop()
+
+		select {
+		case <-done:
+		case <-timer.C:
+		}
+		return ctx.Err()
+	}
+}
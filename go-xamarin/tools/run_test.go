@@ -0,0 +1,48 @@
+//go:build !windows
+// +build !windows
+
+package tools
+
+import (
+	"bytes"
+	"context"
+	"os/exec"
+	"testing"
+	"time"
+)
+
+func TestRunCommandSliceCanceled(t *testing.T) {
+	if _, err := exec.LookPath("setsid"); err != nil {
+		t.Skip("setsid not found")
+	}
+
+	defer func(timeout time.Duration) { killWaitTimeout = timeout }(killWaitTimeout)
+	killWaitTimeout = 100 * time.Millisecond
+
+	tests := []struct {
+		name    string
+		cmdArgs []string
+	}{
+		{name: "command", cmdArgs: []string{"sleep", "30"}},
+		// the process leaving the process group keeps the output open after the kill
+		{name: "process left the process group", cmdArgs: []string{"sh", "-c", "setsid sleep 30 & sleep 30"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+			defer cancel()
+
+			var output bytes.Buffer
+			startTime := time.Now()
+			err := RunCommandSlice(ctx, tt.cmdArgs, &output, &output)
+
+			if err != context.DeadlineExceeded {
+				t.Errorf("RunCommandSlice() error = %v, want %s", err, context.DeadlineExceeded)
+			}
+			if duration := time.Since(startTime); duration > 5*time.Second {
+				t.Errorf("RunCommandSlice() returned after %s", duration)
+			}
+		})
+	}
+}
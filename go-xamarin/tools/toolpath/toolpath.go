@@ -0,0 +1,238 @@
+package toolpath
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bitrise-io/go-utils/log"
+	"github.com/bitrise-io/go-utils/pathutil"
+	"github.com/bitrise-steplib/steps-xamarin-archive/go-xamarin/constants"
+	"github.com/bitrise-steplib/steps-xamarin-archive/go-xamarin/tools"
+)
+
+// Source ...
+type Source string
+
+const (
+	// SourceExplicit ...
+	SourceExplicit Source = "explicit"
+	// SourceEnv ...
+	SourceEnv Source = "env"
+	// SourcePath ...
+	SourcePath Source = "PATH"
+	// SourceWellKnown ...
+	SourceWellKnown Source = "well-known location"
+)
+
+// Tool describes where to look for a command line tool.
+type Tool struct {
+	Name        string   // Executable name, looked up in the PATH
+	EnvKey      string   // Environment variable holding the tool's path
+	KnownPaths  []string // Well-known locations, checked in order
+	VersionArgs []string // Arguments making the tool print its version
+}
+
+// Resolved ...
+type Resolved struct {
+	Path    string
+	Version string
+	Source  Source
+}
+
+// String ...
+func (resolved Resolved) String() string {
+	version := resolved.Version
+	if version == "" {
+		version = "unknown version"
+	}
+	return fmt.Sprintf("%s (%s, from %s)", resolved.Path, version, resolved.Source)
+}
+
+var (
+	// Msbuild ...
+	Msbuild = Tool{
+		Name:   "msbuild",
+		EnvKey: "XAMARIN_MSBUILD_PATH",
+		KnownPaths: []string{
+			constants.MsbuildPath,
+			"/usr/local/bin/msbuild",
+			"/usr/bin/msbuild",
+		},
+		VersionArgs: []string{"-version", "-nologo"},
+	}
+	// Xbuild ...
+	Xbuild = Tool{
+		Name:   "xbuild",
+		EnvKey: "XAMARIN_XBUILD_PATH",
+		KnownPaths: []string{
+			constants.XbuildPath,
+			"/usr/local/bin/xbuild",
+			"/usr/bin/xbuild",
+		},
+		VersionArgs: []string{"/version"},
+	}
+	// Mono ...
+	Mono = Tool{
+		Name:   "mono",
+		EnvKey: "XAMARIN_MONO_PATH",
+		KnownPaths: []string{
+			constants.MonoPath,
+			"/usr/local/bin/mono",
+			"/usr/bin/mono",
+		},
+		VersionArgs: []string{"--version"},
+	}
+	// Dotnet ...
+	Dotnet = Tool{
+		Name:   "dotnet",
+		EnvKey: "XAMARIN_DOTNET_PATH",
+		KnownPaths: []string{
+			"$DOTNET_ROOT/dotnet",
+			"/usr/local/share/dotnet/dotnet",
+			"/usr/share/dotnet/dotnet",
+			"$HOME/.dotnet/dotnet",
+		},
+		VersionArgs: []string{"--version"},
+	}
+)
+
+var (
+	mutex     sync.Mutex
+	overrides = map[string]string{}   // Tool name - explicit path
+	resolved  = map[string]Resolved{} // Tool name - resolved tool
+	versionRe = regexp.MustCompile(`\d+(\.\d+)+`)
+)
+
+// Override sets the explicit path of the tool, which takes precedence over every other location.
+// An empty path removes the override.
+func Override(tool Tool, pth string) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	if pth == "" {
+		delete(overrides, tool.Name)
+	} else {
+		overrides[tool.Name] = pth
+	}
+	delete(resolved, tool.Name)
+}
+
+// Resolve returns the path and version of the tool. The tool is looked up at the explicit path first,
+// then at the path set in the tool's environment variable, then in the PATH and finally at the well-known locations.
+func Resolve(tool Tool) (Resolved, error) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	if r, ok := resolved[tool.Name]; ok {
+		return r, nil
+	}
+
+	r, err := resolve(tool, overrides[tool.Name])
+	if err != nil {
+		return Resolved{}, err
+	}
+
+	r.Version = version(r.Path, tool.VersionArgs...)
+	resolved[tool.Name] = r
+
+	log.Debugf("Resolved %s: %s", tool.Name, r)
+
+	return r, nil
+}
+
+// Path returns the resolved path of the tool.
+func Path(tool Tool) (string, error) {
+	r, err := Resolve(tool)
+	if err != nil {
+		return "", err
+	}
+	return r.Path, nil
+}
+
+func resolve(tool Tool, explicitPth string) (Resolved, error) {
+	if explicitPth != "" {
+		pth, err := executablePath(explicitPth)
+		if err != nil {
+			return Resolved{}, fmt.Errorf("%s not found at the given path (%s), error: %s", tool.Name, explicitPth, err)
+		}
+		return Resolved{Path: pth, Source: SourceExplicit}, nil
+	}
+
+	if envPth := os.Getenv(tool.EnvKey); tool.EnvKey != "" && envPth != "" {
+		pth, err := executablePath(envPth)
+		if err != nil {
+			return Resolved{}, fmt.Errorf("%s not found at the path set in %s (%s), error: %s", tool.Name, tool.EnvKey, envPth, err)
+		}
+		return Resolved{Path: pth, Source: SourceEnv}, nil
+	}
+
+	if pth, err := exec.LookPath(tool.Name); err == nil {
+		if absPth, err := filepath.Abs(pth); err == nil {
+			pth = absPth
+		}
+		return Resolved{Path: pth, Source: SourcePath}, nil
+	}
+
+	checked := []string{}
+	for _, knownPth := range tool.KnownPaths {
+		knownPth = os.ExpandEnv(knownPth)
+		if !filepath.IsAbs(knownPth) {
+			// the path depends on an unset environment variable
+			continue
+		}
+
+		checked = append(checked, knownPth)
+		if pth, err := executablePath(knownPth); err == nil {
+			return Resolved{Path: pth, Source: SourceWellKnown}, nil
+		}
+	}
+
+	return Resolved{}, fmt.Errorf("%s not found: set its path in %s, add it to the PATH or install it to one of: %s", tool.Name, tool.EnvKey, strings.Join(checked, ", "))
+}
+
+func executablePath(pth string) (string, error) {
+	absPth, err := pathutil.AbsPath(pth)
+	if err != nil {
+		return "", err
+	}
+
+	info, err := os.Stat(absPth)
+	if err != nil {
+		return "", err
+	}
+	if info.IsDir() || info.Mode()&0111 == 0 {
+		return "", fmt.Errorf("not an executable: %s", absPth)
+	}
+	return absPth, nil
+}
+
+// versionTimeout is the time limit of the version probe, a hanging tool is killed after it.
+var versionTimeout = 10 * time.Second
+
+// version returns the first version number the tool prints, or empty string if it can not be determined.
+func version(pth string, args ...string) string {
+	if len(args) == 0 {
+		return ""
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), versionTimeout)
+	defer cancel()
+
+	var output bytes.Buffer
+	err := tools.RunCommandSlice(ctx, append([]string{pth}, args...), &output, &output)
+	out := strings.TrimSpace(output.String())
+	if err != nil {
+		log.Debugf("Failed to get the version of %s, output: %s, error: %s", pth, out, err)
+		return ""
+	}
+
+	return versionRe.FindString(out)
+}
@@ -1,12 +1,19 @@
 package tools
 
-import "io"
+import (
+	"context"
+	"io"
+)
+
+// SecretMask replaces the secret values in the printed commands.
+const SecretMask = "[REDACTED]"
 
 // Runnable ...
 type Runnable interface {
 	String() string
 	SetCustomOptions(options ...string)
 	Run(outWriter, errWriter io.Writer) error
+	RunWithContext(ctx context.Context, outWriter, errWriter io.Writer) error
 }
 
 // Printable ...
@@ -19,7 +26,6 @@ type Editable interface {
 	SetCustomOptions(options ...string)
 }
 
-//
 // EmptyCommand - for return type in case of failed to create a RunnableCommand
 type EmptyCommand struct{}
 
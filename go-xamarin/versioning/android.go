@@ -0,0 +1,167 @@
+package versioning
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/bitrise-io/go-utils/fileutil"
+)
+
+const androidNamespace = "http://schemas.android.com/apk/res/android"
+
+// maxAndroidVersionCode is the greatest version code Google Play accepts.
+const maxAndroidVersionCode = 2100000000
+
+// AndroidVersion is the version of an Android application, defined by the root element of the manifest.
+type AndroidVersion struct {
+	Code string // android:versionCode
+	Name string // android:versionName
+}
+
+// ParseAndroidVersionCode returns the version code with the offset added,
+// the version code has to be a positive integer, not greater than the limit of Google Play.
+func ParseAndroidVersionCode(code string, offset int) (string, error) {
+	value, err := strconv.Atoi(strings.TrimSpace(code))
+	if err != nil {
+		return "", fmt.Errorf("version code (%s) is not an integer", code)
+	}
+
+	value += offset
+	if value < 1 || value > maxAndroidVersionCode {
+		return "", fmt.Errorf("version code (%d) should be between 1 and %d", value, maxAndroidVersionCode)
+	}
+	return strconv.Itoa(value), nil
+}
+
+// manifestTag is the start tag of the manifest element in the manifest content.
+type manifestTag struct {
+	start, end int // Byte offsets of the tag in the content
+	prefix     string
+	attrs      []xml.Attr
+}
+
+func findManifestTag(content []byte) (manifestTag, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(content))
+	for {
+		start := int(decoder.InputOffset())
+		token, err := decoder.RawToken()
+		if err == io.EOF {
+			return manifestTag{}, fmt.Errorf("no manifest element found")
+		} else if err != nil {
+			return manifestTag{}, err
+		}
+
+		element, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		if element.Name.Local != "manifest" {
+			return manifestTag{}, fmt.Errorf("root element is %s instead of manifest", element.Name.Local)
+		}
+
+		tag := manifestTag{
+			start:  start,
+			end:    int(decoder.InputOffset()),
+			prefix: "android",
+			attrs:  element.Attr,
+		}
+		for _, attr := range element.Attr {
+			if attr.Name.Space == "xmlns" && attr.Value == androidNamespace {
+				tag.prefix = attr.Name.Local
+			}
+		}
+		return tag, nil
+	}
+}
+
+func (tag manifestTag) attr(name string) string {
+	for _, attr := range tag.attrs {
+		if attr.Name.Space == tag.prefix && attr.Name.Local == name {
+			return attr.Value
+		}
+	}
+	return ""
+}
+
+// ReadAndroidManifestVersion returns the version defined by the manifest.
+func ReadAndroidManifestVersion(pth string) (AndroidVersion, error) {
+	content, err := fileutil.ReadBytesFromFile(pth)
+	if err != nil {
+		return AndroidVersion{}, fmt.Errorf("failed to read manifest (%s), error: %s", pth, err)
+	}
+
+	tag, err := findManifestTag(content)
+	if err != nil {
+		return AndroidVersion{}, fmt.Errorf("failed to parse manifest (%s), error: %s", pth, err)
+	}
+
+	return AndroidVersion{
+		Code: tag.attr("versionCode"),
+		Name: tag.attr("versionName"),
+	}, nil
+}
+
+// StampAndroidManifest sets the non empty fields of the given version in the manifest,
+// only the attributes of the manifest element are rewritten, the rest of the content is kept intact.
+// Returns the original content of the manifest.
+func StampAndroidManifest(pth string, version AndroidVersion) ([]byte, error) {
+	original, err := fileutil.ReadBytesFromFile(pth)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest (%s), error: %s", pth, err)
+	}
+
+	tag, err := findManifestTag(original)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse manifest (%s), error: %s", pth, err)
+	}
+
+	tagContent := string(original[tag.start:tag.end])
+	if version.Code != "" {
+		tagContent = setAttribute(tagContent, tag.prefix+":versionCode", version.Code)
+	}
+	if version.Name != "" {
+		tagContent = setAttribute(tagContent, tag.prefix+":versionName", version.Name)
+	}
+
+	content := append([]byte{}, original[:tag.start]...)
+	content = append(content, tagContent...)
+	content = append(content, original[tag.end:]...)
+
+	if err := fileutil.WriteBytesToFile(pth, content); err != nil {
+		return nil, fmt.Errorf("failed to write manifest (%s), error: %s", pth, err)
+	}
+
+	return original, nil
+}
+
+// setAttribute replaces the value of the attribute in the start tag, or appends the attribute if the tag does not have it.
+func setAttribute(tag, name, value string) string {
+	escaped := escapeAttributeValue(value)
+
+	attributeRegexp := regexp.MustCompile(`(\s` + regexp.QuoteMeta(name) + `\s*=\s*)("[^"]*"|'[^']*')`)
+	if attributeRegexp.MatchString(tag) {
+		return attributeRegexp.ReplaceAllStringFunc(tag, func(attribute string) string {
+			match := attributeRegexp.FindStringSubmatch(attribute)
+			return match[1] + `"` + escaped + `"`
+		})
+	}
+
+	end := len(tag) - len(">")
+	if strings.HasSuffix(tag, "/>") {
+		end = len(tag) - len("/>")
+	}
+	return strings.TrimRight(tag[:end], " \t") + " " + name + `="` + escaped + `"` + tag[end:]
+}
+
+func escapeAttributeValue(value string) string {
+	var buf bytes.Buffer
+	if err := xml.EscapeText(&buf, []byte(value)); err != nil {
+		return value
+	}
+	return buf.String()
+}
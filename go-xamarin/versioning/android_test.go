@@ -0,0 +1,166 @@
+package versioning
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseAndroidVersionCode(t *testing.T) {
+	tests := []struct {
+		code    string
+		offset  int
+		want    string
+		wantErr bool
+	}{
+		{code: "42", want: "42"},
+		{code: " 42 ", offset: 1000, want: "1042"},
+		{code: "10", offset: -9, want: "1"},
+		{code: "10", offset: -10, wantErr: true},
+		{code: "2100000000", want: "2100000000"},
+		{code: "2100000000", offset: 1, wantErr: true},
+		{code: "1.0", wantErr: true},
+		{code: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.code, func(t *testing.T) {
+			got, err := ParseAndroidVersionCode(tt.code, tt.offset)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseAndroidVersionCode(%q, %d) error = %v, wantErr %v", tt.code, tt.offset, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseAndroidVersionCode(%q, %d) = %s, want %s", tt.code, tt.offset, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStampAndroidManifest(t *testing.T) {
+	tests := []struct {
+		name         string
+		manifest     string
+		version      AndroidVersion
+		want         string
+		wantOriginal AndroidVersion
+		wantErr      bool
+	}{
+		{
+			name: "existing attributes are replaced",
+			manifest: `<?xml version="1.0" encoding="utf-8"?>
+<!-- the version is set by the CI -->
+<manifest xmlns:android="http://schemas.android.com/apk/res/android" android:versionCode="1" android:versionName="1.0" package="com.app">
+	<application android:label="App"></application>
+</manifest>`,
+			version: AndroidVersion{Code: "42", Name: "1.4.42"},
+			want: `<?xml version="1.0" encoding="utf-8"?>
+<!-- the version is set by the CI -->
+<manifest xmlns:android="http://schemas.android.com/apk/res/android" android:versionCode="42" android:versionName="1.4.42" package="com.app">
+	<application android:label="App"></application>
+</manifest>`,
+			wantOriginal: AndroidVersion{Code: "1", Name: "1.0"},
+		},
+		{
+			name: "single quoted and multiline attributes",
+			manifest: `<manifest xmlns:android='http://schemas.android.com/apk/res/android'
+    android:versionCode = '1'
+    package='com.app' />`,
+			version: AndroidVersion{Code: "42"},
+			want: `<manifest xmlns:android='http://schemas.android.com/apk/res/android'
+    android:versionCode = "42"
+    package='com.app' />`,
+			wantOriginal: AndroidVersion{Code: "1"},
+		},
+		{
+			name:         "missing attributes are added",
+			manifest:     `<manifest xmlns:android="http://schemas.android.com/apk/res/android" package="com.app"><application /></manifest>`,
+			version:      AndroidVersion{Code: "42", Name: "1.4"},
+			want:         `<manifest xmlns:android="http://schemas.android.com/apk/res/android" package="com.app" android:versionCode="42" android:versionName="1.4"><application /></manifest>`,
+			wantOriginal: AndroidVersion{},
+		},
+		{
+			name:         "self closing manifest",
+			manifest:     `<manifest xmlns:android="http://schemas.android.com/apk/res/android" package="com.app" />`,
+			version:      AndroidVersion{Name: "1.4"},
+			want:         `<manifest xmlns:android="http://schemas.android.com/apk/res/android" package="com.app" android:versionName="1.4"/>`,
+			wantOriginal: AndroidVersion{},
+		},
+		{
+			name:         "custom namespace prefix",
+			manifest:     `<manifest xmlns:a="http://schemas.android.com/apk/res/android" a:versionCode="7" a:versionName="0.7" android:versionCode="0"></manifest>`,
+			version:      AndroidVersion{Code: "8"},
+			want:         `<manifest xmlns:a="http://schemas.android.com/apk/res/android" a:versionCode="8" a:versionName="0.7" android:versionCode="0"></manifest>`,
+			wantOriginal: AndroidVersion{Code: "7", Name: "0.7"},
+		},
+		{
+			name:         "values are escaped",
+			manifest:     `<manifest xmlns:android="http://schemas.android.com/apk/res/android" android:versionName="1.0"></manifest>`,
+			version:      AndroidVersion{Name: `1.0 "beta" <&>`},
+			want:         `<manifest xmlns:android="http://schemas.android.com/apk/res/android" android:versionName="1.0 &#34;beta&#34; &lt;&amp;&gt;"></manifest>`,
+			wantOriginal: AndroidVersion{Name: "1.0"},
+		},
+		{
+			name:     "root element is not manifest",
+			manifest: `<resources><string name="app_name">App</string></resources>`,
+			version:  AndroidVersion{Code: "42"},
+			wantErr:  true,
+		},
+		{
+			name:     "invalid xml",
+			manifest: `<manifest android:versionCode="1`,
+			version:  AndroidVersion{Code: "42"},
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpDir, err := ioutil.TempDir("", "manifest")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer func() {
+				if err := os.RemoveAll(tmpDir); err != nil {
+					t.Log(err)
+				}
+			}()
+
+			pth := filepath.Join(tmpDir, "AndroidManifest.xml")
+			if err := ioutil.WriteFile(pth, []byte(tt.manifest), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			originalVersion, err := ReadAndroidManifestVersion(pth)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ReadAndroidManifestVersion() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if originalVersion != tt.wantOriginal {
+				t.Errorf("ReadAndroidManifestVersion() = %+v, want %+v", originalVersion, tt.wantOriginal)
+			}
+
+			original, err := StampAndroidManifest(pth, tt.version)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("StampAndroidManifest() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			content, err := ioutil.ReadFile(pth)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.wantErr {
+				if string(content) != tt.manifest {
+					t.Errorf("StampAndroidManifest() failed, but changed the manifest to:\n%s", content)
+				}
+				return
+			}
+
+			if string(original) != tt.manifest {
+				t.Errorf("StampAndroidManifest() returned original content:\n%s\nwant:\n%s", original, tt.manifest)
+			}
+			if string(content) != tt.want {
+				t.Errorf("stamped manifest:\n%s\nwant:\n%s", content, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,87 @@
+package versioning
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/bitrise-io/go-utils/fileutil"
+	"github.com/bitrise-steplib/steps-xamarin-archive/go-xamarin/plist"
+)
+
+const (
+	bundleVersionKey      = "CFBundleVersion"
+	bundleShortVersionKey = "CFBundleShortVersionString"
+)
+
+// appleVersionRegexp matches the version format the App Store accepts: one to three period-separated integers.
+var appleVersionRegexp = regexp.MustCompile(`^\d+(\.\d+){0,2}$`)
+
+// AppleVersion is the version of an Apple application, defined by the Info.plist.
+type AppleVersion struct {
+	BundleVersion      string // CFBundleVersion, the build number
+	BundleShortVersion string // CFBundleShortVersionString, the release version
+}
+
+// ValidateAppleVersion returns an error if the version is not one to three period-separated integers.
+func ValidateAppleVersion(version string) error {
+	if !appleVersionRegexp.MatchString(version) {
+		return fmt.Errorf("version (%s) should be one to three period-separated integers, like: 1.4.2", version)
+	}
+	return nil
+}
+
+func readInfoPlist(pth string) (*plist.Document, *plist.Dict, error) {
+	document, err := plist.ReadXMLFile(pth)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	dict, err := document.RootDict()
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid Info.plist (%s), error: %s", pth, err)
+	}
+	return document, dict, nil
+}
+
+// ReadInfoPlistVersion returns the version defined by the Info.plist.
+func ReadInfoPlistVersion(pth string) (AppleVersion, error) {
+	_, dict, err := readInfoPlist(pth)
+	if err != nil {
+		return AppleVersion{}, err
+	}
+
+	bundleVersion, _ := dict.GetString(bundleVersionKey)
+	bundleShortVersion, _ := dict.GetString(bundleShortVersionKey)
+	return AppleVersion{
+		BundleVersion:      strings.TrimSpace(bundleVersion),
+		BundleShortVersion: strings.TrimSpace(bundleShortVersion),
+	}, nil
+}
+
+// StampInfoPlist sets the non empty fields of the given version in the Info.plist,
+// the other entries are kept in their original order. Returns the original content of the Info.plist.
+func StampInfoPlist(pth string, version AppleVersion) ([]byte, error) {
+	original, err := fileutil.ReadBytesFromFile(pth)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read Info.plist (%s), error: %s", pth, err)
+	}
+
+	document, dict, err := readInfoPlist(pth)
+	if err != nil {
+		return nil, err
+	}
+
+	if version.BundleVersion != "" {
+		dict.Set(bundleVersionKey, version.BundleVersion)
+	}
+	if version.BundleShortVersion != "" {
+		dict.Set(bundleShortVersionKey, version.BundleShortVersion)
+	}
+
+	if err := document.WriteXMLFile(pth); err != nil {
+		return nil, err
+	}
+
+	return original, nil
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	steputiltools "github.com/bitrise-io/go-steputils/tools"
+	"github.com/bitrise-io/go-utils/command"
+	"github.com/bitrise-io/go-utils/log"
+	"github.com/bitrise-steplib/steps-xamarin-archive/go-xamarin/analyzers/solution"
+)
+
+const nothingToBuildEnvKey = "BITRISE_XAMARIN_NOTHING_TO_BUILD"
+
+// changedFilesBaseDir returns the dir to resolve the relative changed file paths against: the root of the git repository
+// containing the solution, as git diff --name-only prints the paths relative to it, or the working dir outside of a repository.
+func changedFilesBaseDir(solutionPth string) (string, error) {
+	out, err := command.New("git", "rev-parse", "--show-toplevel").SetDir(filepath.Dir(solutionPth)).RunAndReturnTrimmedCombinedOutput()
+	if err == nil && out != "" {
+		return out, nil
+	}
+
+	log.Warnf("Failed to find the git repository of the solution, relative changed file paths are resolved against the working dir")
+	return os.Getwd()
+}
+
+// readChangedFiles reads the newline separated list of changed file paths,
+// relative paths (like the output of git diff --name-only) are resolved against the base dir.
+func readChangedFiles(pth, baseDir string) ([]string, error) {
+	file, err := os.Open(pth)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open changed files list (%s), error: %s", pth, err)
+	}
+	defer func() {
+		if err := file.Close(); err != nil {
+			log.Warnf("Failed to close changed files list (%s), error: %s", pth, err)
+		}
+	}()
+
+	var changedPths []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		if !filepath.IsAbs(line) {
+			line = filepath.Join(baseDir, line)
+		}
+		changedPths = append(changedPths, filepath.Clean(line))
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read changed files list (%s), error: %s", pth, err)
+	}
+
+	return changedPths, nil
+}
+
+// reportChangeImpact is the change impact in the build report, with project names instead of project IDs.
+type reportChangeImpact struct {
+	ChangedFiles     int      `json:"changed_files"`
+	ChangedProjects  []string `json:"changed_projects"`
+	AffectedProjects []string `json:"affected_projects"`
+	BuildFilePaths   []string `json:"build_file_paths"`
+	UnownedPaths     []string `json:"unowned_paths"`
+	BuildAll         bool     `json:"build_all"`
+	NothingToBuild   bool     `json:"nothing_to_build"`
+}
+
+func newReportChangeImpact(impact solution.ChangeImpactModel, graph solution.GraphModel, changedFiles int) *reportChangeImpact {
+	projectNames := func(projectIDs []string) []string {
+		names := []string{}
+		for _, projectID := range projectIDs {
+			names = append(names, graph.Name(projectID))
+		}
+		return names
+	}
+
+	return &reportChangeImpact{
+		ChangedFiles:     changedFiles,
+		ChangedProjects:  projectNames(impact.ChangedProjectIDs),
+		AffectedProjects: projectNames(impact.AffectedProjectIDs),
+		BuildFilePaths:   impact.BuildFilePths,
+		UnownedPaths:     impact.UnownedPths,
+		BuildAll:         impact.BuildAll,
+	}
+}
+
+// printChangeImpact logs the projects changed and affected by the changed files.
+func printChangeImpact(impact *reportChangeImpact) {
+	fmt.Println()
+	log.Infof("Change impact of %d changed file(s):", impact.ChangedFiles)
+	log.Printf("- changed projects: %s", strings.Join(impact.ChangedProjects, ", "))
+	log.Printf("- affected projects: %s", strings.Join(impact.AffectedProjects, ", "))
+	if len(impact.BuildFilePaths) > 0 {
+		log.Printf("- changed build files: %s", strings.Join(impact.BuildFilePaths, ", "))
+	}
+	if len(impact.UnownedPaths) > 0 {
+		log.Printf("- %d changed file(s) not owned by any project", len(impact.UnownedPaths))
+	}
+	if impact.BuildAll {
+		log.Warnf("A changed file in the solution dir is not owned by any project, every project is affected")
+	}
+}
+
+// exportNothingToBuild exports if the changed files affect none of the projects to build.
+func exportNothingToBuild(nothingToBuild bool) error {
+	value := "false"
+	if nothingToBuild {
+		value = "true"
+	}
+
+	if err := steputiltools.ExportEnvironmentWithEnvman(nothingToBuildEnvKey, value); err != nil {
+		return fmt.Errorf("failed to export (%s) into (%s)", value, nothingToBuildEnvKey)
+	}
+	return nil
+}
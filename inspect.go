@@ -0,0 +1,124 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/bitrise-io/go-utils/fileutil"
+	"github.com/bitrise-io/go-utils/log"
+	"github.com/bitrise-steplib/steps-xamarin-archive/go-xamarin/analyzers/apk"
+	"github.com/bitrise-steplib/steps-xamarin-archive/go-xamarin/analyzers/bundle"
+	"github.com/bitrise-steplib/steps-xamarin-archive/go-xamarin/analyzers/project"
+	"github.com/bitrise-steplib/steps-xamarin-archive/go-xamarin/builder"
+	"github.com/bitrise-steplib/steps-xamarin-archive/go-xamarin/constants"
+)
+
+// appleMetadataEnvPrefixes are the prefixes of the env keys of the Apple artifacts' metadata, by project type.
+var appleMetadataEnvPrefixes = map[constants.SDK]string{
+	constants.SDKIOS:   "BITRISE_IOS",
+	constants.SDKTvOS:  "BITRISE_TVOS",
+	constants.SDKMacOS: "BITRISE_MACOS",
+}
+
+// metadataEnv is an env var holding a piece of an artifact's metadata.
+type metadataEnv struct {
+	Key   string
+	Value string
+}
+
+// inspectArtifact reads the metadata of the artifact and returns the env vars to export it into.
+// Artifacts of output types which are not inspected are returned as they are.
+func inspectArtifact(artifact exportedArtifact) (exportedArtifact, error) {
+	switch artifact.OutputType {
+	case constants.OutputTypeAPK:
+		model, err := apk.Inspect(artifact.Pth)
+		if err != nil {
+			return artifact, err
+		}
+
+		artifact.APK = &model
+		artifact.Metadata = []metadataEnv{
+			{Key: "BITRISE_ANDROID_PACKAGE_NAME", Value: model.PackageName},
+			{Key: "BITRISE_ANDROID_VERSION_CODE", Value: model.VersionCode},
+			{Key: "BITRISE_ANDROID_VERSION_NAME", Value: model.VersionName},
+			{Key: "BITRISE_ANDROID_MIN_SDK_VERSION", Value: model.MinSDKVersion},
+			{Key: "BITRISE_ANDROID_TARGET_SDK_VERSION", Value: model.TargetSDKVersion},
+			{Key: "BITRISE_ANDROID_PERMISSIONS", Value: strings.Join(model.Permissions, listSeparator)},
+			{Key: "BITRISE_ANDROID_ABIS", Value: strings.Join(model.ABIs, listSeparator)},
+		}
+	case constants.OutputTypeIPA, constants.OutputTypeXCArchive:
+		prefix, ok := appleMetadataEnvPrefixes[artifact.ProjectType]
+		if !ok {
+			return artifact, nil
+		}
+
+		var model bundle.Model
+		var err error
+		if artifact.OutputType == constants.OutputTypeIPA {
+			model, err = bundle.InspectIPA(artifact.Pth)
+		} else {
+			model, err = bundle.InspectXCArchive(artifact.Pth)
+		}
+		if err != nil {
+			return artifact, err
+		}
+
+		artifact.Bundle = &model
+		artifact.Metadata = []metadataEnv{
+			{Key: prefix + "_BUNDLE_ID", Value: model.BundleID},
+			{Key: prefix + "_VERSION", Value: model.Version},
+			{Key: prefix + "_BUILD_NUMBER", Value: model.BuildNumber},
+			{Key: prefix + "_MIN_OS_VERSION", Value: model.MinimumOSVersion},
+			{Key: prefix + "_DEVICE_FAMILIES", Value: strings.Join(model.DeviceFamilies, listSeparator)},
+		}
+	}
+	return artifact, nil
+}
+
+// projectBundleID returns the bundle id the project defines: the ApplicationId property of SDK-style projects,
+// or the CFBundleIdentifier of the project's Info.plist. Empty if the project does not define it literally.
+func projectBundleID(proj project.Model) string {
+	if proj.ApplicationID != "" {
+		return proj.ApplicationID
+	}
+	if proj.InfoPlistPth == "" {
+		return ""
+	}
+
+	content, err := fileutil.ReadBytesFromFile(proj.InfoPlistPth)
+	if err != nil {
+		return ""
+	}
+	model, err := bundle.ParseInfoPlist(content)
+	if err != nil || strings.Contains(model.BundleID, "$(") {
+		return ""
+	}
+	return model.BundleID
+}
+
+// xcarchiveBundleIDWarning returns a warning if the xcarchive was picked from the archives dir of Xcode,
+// and its bundle id differs from the bundle id of the project it is attributed to.
+func xcarchiveBundleIDWarning(artifact exportedArtifact, proj project.Model) string {
+	if artifact.OutputType != constants.OutputTypeXCArchive || artifact.Bundle == nil || !builder.IsXcodeArchivesPath(artifact.SourcePth) {
+		return ""
+	}
+
+	expected := projectBundleID(proj)
+	if expected == "" || artifact.Bundle.BundleID == "" || expected == artifact.Bundle.BundleID {
+		return ""
+	}
+	return fmt.Sprintf("The xcarchive (%s) picked from the Xcode archives for project (%s) has bundle id (%s), the project's bundle id is (%s), it may belong to another project",
+		artifact.SourcePth, proj.Name, artifact.Bundle.BundleID, expected)
+}
+
+// printArtifactMetadata logs the metadata of the artifact, described like in the export logs.
+func printArtifactMetadata(description string, artifact exportedArtifact) {
+	if len(artifact.Metadata) == 0 {
+		return
+	}
+
+	log.Printf("Metadata of the %s:", description)
+	for _, env := range artifact.Metadata {
+		log.Printf("- %s: %s", env.Key, env.Value)
+	}
+}
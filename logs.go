@@ -0,0 +1,29 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+
+	steputiltools "github.com/bitrise-io/go-steputils/tools"
+	"github.com/bitrise-steplib/steps-xamarin-archive/go-xamarin/builder"
+)
+
+const (
+	buildLogsDirName   = "logs"
+	buildLogsDirEnvKey = "BITRISE_XAMARIN_BUILD_LOGS_DIR"
+)
+
+// setupBuildLogs makes the builder save the output of every command into the logs dir of the deploy dir
+// and exports the logs dir path.
+func setupBuildLogs(b *builder.Model, deployDir string) (string, error) {
+	logsDir := filepath.Join(deployDir, buildLogsDirName)
+	if err := b.SetLogDir(logsDir); err != nil {
+		return "", err
+	}
+
+	if err := steputiltools.ExportEnvironmentWithEnvman(buildLogsDirEnvKey, logsDir); err != nil {
+		return "", fmt.Errorf("failed to export build logs dir (%s) into (%s)", logsDir, buildLogsDirEnvKey)
+	}
+
+	return logsDir, nil
+}
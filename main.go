@@ -1,21 +1,24 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"os"
-	"path/filepath"
+	"os/signal"
+	"strconv"
 	"strings"
+	"syscall"
 	"time"
 
 	"github.com/bitrise-io/go-steputils/input"
-	steputiltools "github.com/bitrise-io/go-steputils/tools"
-	"github.com/bitrise-io/go-utils/command"
 	"github.com/bitrise-io/go-utils/log"
+	"github.com/bitrise-steplib/steps-xamarin-archive/go-xamarin/analyzers/project"
+	"github.com/bitrise-steplib/steps-xamarin-archive/go-xamarin/builder"
+	"github.com/bitrise-steplib/steps-xamarin-archive/go-xamarin/constants"
+	"github.com/bitrise-steplib/steps-xamarin-archive/go-xamarin/tools"
+	"github.com/bitrise-steplib/steps-xamarin-archive/go-xamarin/tools/buildtools"
+	"github.com/bitrise-steplib/steps-xamarin-archive/go-xamarin/tools/toolpath"
 	"github.com/kballard/go-shellquote"
-	"github.com/toggl/go-xamarin/builder"
-	"github.com/toggl/go-xamarin/constants"
-	"github.com/toggl/go-xamarin/tools"
-	"github.com/toggl/go-xamarin/tools/buildtools"
 )
 
 // ConfigsModel ...
@@ -24,12 +27,33 @@ type ConfigsModel struct {
 	XamarinConfiguration string
 	XamarinPlatform      string
 	ProjectTypeWhitelist string
+	ProjectInclude       string
+	ProjectExclude       string
+	ChangedFilesPath     string
+	MainProject          string
 
 	AndroidCustomOptions string
 	IOSCustomOptions     string
 	TvOSCustomOptions    string
 	MacOSCustomOptions   string
 	BuildTool            string
+	BuildToolPath        string
+	DryRun               string
+	MaxParallelBuilds    string
+	CommandTimeout       string
+
+	AndroidVersionCode       string
+	AndroidVersionCodeOffset string
+	AndroidVersionName       string
+	AppleBundleVersion       string
+	AppleBundleShortVersion  string
+	RestoreVersionedFiles    string
+
+	AndroidKeystorePath     string
+	AndroidKeystoreAlias    string
+	AndroidKeystorePassword string
+	AndroidKeyPassword      string
+	SecretEnvVars           string
 
 	DeployDir string
 }
@@ -40,12 +64,33 @@ func createConfigsModelFromEnvs() ConfigsModel {
 		XamarinConfiguration: os.Getenv("xamarin_configuration"),
 		XamarinPlatform:      os.Getenv("xamarin_platform"),
 		ProjectTypeWhitelist: os.Getenv("project_type_whitelist"),
+		ProjectInclude:       os.Getenv("project_include"),
+		ProjectExclude:       os.Getenv("project_exclude"),
+		ChangedFilesPath:     os.Getenv("changed_files_path"),
+		MainProject:          os.Getenv("main_project"),
 
 		AndroidCustomOptions: os.Getenv("android_build_command_custom_options"),
 		IOSCustomOptions:     os.Getenv("ios_build_command_custom_options"),
 		TvOSCustomOptions:    os.Getenv("tvos_build_command_custom_options"),
 		MacOSCustomOptions:   os.Getenv("macos_build_command_custom_options"),
 		BuildTool:            os.Getenv("build_tool"),
+		BuildToolPath:        os.Getenv("build_tool_path"),
+		DryRun:               os.Getenv("dry_run"),
+		MaxParallelBuilds:    os.Getenv("max_parallel_builds"),
+		CommandTimeout:       os.Getenv("command_timeout"),
+
+		AndroidVersionCode:       os.Getenv("android_version_code"),
+		AndroidVersionCodeOffset: os.Getenv("android_version_code_offset"),
+		AndroidVersionName:       os.Getenv("android_version_name"),
+		AppleBundleVersion:       os.Getenv("apple_bundle_version"),
+		AppleBundleShortVersion:  os.Getenv("apple_bundle_short_version"),
+		RestoreVersionedFiles:    os.Getenv("restore_versioned_files"),
+
+		AndroidKeystorePath:     os.Getenv("android_keystore_path"),
+		AndroidKeystoreAlias:    os.Getenv("android_keystore_alias"),
+		AndroidKeystorePassword: os.Getenv("android_keystore_password"),
+		AndroidKeyPassword:      os.Getenv("android_key_password"),
+		SecretEnvVars:           os.Getenv("secret_env_vars"),
 
 		DeployDir: os.Getenv("BITRISE_DEPLOY_DIR"),
 	}
@@ -58,14 +103,33 @@ func (configs ConfigsModel) print() {
 	log.Printf("- XamarinConfiguration: %s", configs.XamarinConfiguration)
 	log.Printf("- XamarinPlatform: %s", configs.XamarinPlatform)
 	log.Printf("- ProjectTypeWhitelist: %s", configs.ProjectTypeWhitelist)
+	log.Printf("- ProjectInclude: %s", configs.ProjectInclude)
+	log.Printf("- ProjectExclude: %s", configs.ProjectExclude)
+	log.Printf("- ChangedFilesPath: %s", configs.ChangedFilesPath)
+	log.Printf("- MainProject: %s", configs.MainProject)
+	log.Printf("- DryRun: %s", configs.DryRun)
+	log.Printf("- MaxParallelBuilds: %s", configs.MaxParallelBuilds)
+	log.Printf("- CommandTimeout: %s", configs.CommandTimeout)
+	log.Printf("- AndroidVersionCode: %s", configs.AndroidVersionCode)
+	log.Printf("- AndroidVersionCodeOffset: %s", configs.AndroidVersionCodeOffset)
+	log.Printf("- AndroidVersionName: %s", configs.AndroidVersionName)
+	log.Printf("- AppleBundleVersion: %s", configs.AppleBundleVersion)
+	log.Printf("- AppleBundleShortVersion: %s", configs.AppleBundleShortVersion)
+	log.Printf("- RestoreVersionedFiles: %s", configs.RestoreVersionedFiles)
+	log.Printf("- AndroidKeystorePath: %s", configs.AndroidKeystorePath)
+	log.Printf("- AndroidKeystoreAlias: %s", configs.AndroidKeystoreAlias)
+	log.Printf("- AndroidKeystorePassword: %s", secretInput(configs.AndroidKeystorePassword))
+	log.Printf("- AndroidKeyPassword: %s", secretInput(configs.AndroidKeyPassword))
+	log.Printf("- SecretEnvVars: %s", configs.SecretEnvVars)
 
 	log.Infof("Experimental Configs:")
 
-	log.Printf("- AndroidCustomOptions: %s", configs.AndroidCustomOptions)
-	log.Printf("- IOSCustomOptions: %s", configs.IOSCustomOptions)
-	log.Printf("- TvOSCustomOptions: %s", configs.TvOSCustomOptions)
-	log.Printf("- MacOSCustomOptions: %s", configs.MacOSCustomOptions)
+	log.Printf("- AndroidCustomOptions: %s", tools.Redact(configs.AndroidCustomOptions))
+	log.Printf("- IOSCustomOptions: %s", tools.Redact(configs.IOSCustomOptions))
+	log.Printf("- TvOSCustomOptions: %s", tools.Redact(configs.TvOSCustomOptions))
+	log.Printf("- MacOSCustomOptions: %s", tools.Redact(configs.MacOSCustomOptions))
 	log.Printf("- BuildTool: %s", configs.BuildTool)
+	log.Printf("- BuildToolPath: %s", configs.BuildToolPath)
 
 	log.Infof("Other Configs:")
 
@@ -77,6 +141,12 @@ func (configs ConfigsModel) validate() error {
 		return fmt.Errorf("XamarinSolution - %s", err)
 	}
 
+	if configs.ChangedFilesPath != "" {
+		if err := input.ValidateIfPathExists(configs.ChangedFilesPath); err != nil {
+			return fmt.Errorf("ChangedFilesPath - %s", err)
+		}
+	}
+
 	if err := input.ValidateIfNotEmpty(configs.XamarinConfiguration); err != nil {
 		return fmt.Errorf("XamarinConfiguration - %s", err)
 	}
@@ -85,68 +155,80 @@ func (configs ConfigsModel) validate() error {
 		return fmt.Errorf("XamarinPlatform - %s", err)
 	}
 
-	if err := input.ValidateWithOptions(configs.BuildTool, "msbuild", "xbuild"); err != nil {
+	if err := input.ValidateWithOptions(configs.BuildTool, "msbuild", "xbuild", "dotnet"); err != nil {
 		return fmt.Errorf("BuildTool - %s", err)
 	}
 
-	return nil
-}
+	if err := input.ValidateWithOptions(configs.DryRun, "yes", "no"); err != nil {
+		return fmt.Errorf("DryRun - %s", err)
+	}
 
-func exportZippedArtifactDir(pth, deployDir, envKey string) (string, error) {
-	parentDir := filepath.Dir(pth)
-	dirName := filepath.Base(pth)
-	deployPth := filepath.Join(deployDir, dirName+".zip")
-	cmd := command.New("/usr/bin/zip", "-rTy", deployPth, dirName)
-	cmd.SetDir(parentDir)
-	out, err := cmd.RunAndReturnTrimmedCombinedOutput()
-	if err != nil {
-		return "", fmt.Errorf("failed to zip dir: %s, output: %s, error: %s", pth, out, err)
+	if _, err := configs.maxParallelBuilds(); err != nil {
+		return fmt.Errorf("MaxParallelBuilds - %s", err)
 	}
 
-	if err := steputiltools.ExportEnvironmentWithEnvman(envKey, deployPth); err != nil {
-		return "", fmt.Errorf("failed to export artifact path (%s) into (%s)", deployPth, envKey)
+	if _, err := configs.commandTimeout(); err != nil {
+		return fmt.Errorf("CommandTimeout - %s", err)
 	}
 
-	return deployPth, nil
-}
+	if _, err := configs.androidVersion(); err != nil {
+		return fmt.Errorf("AndroidVersionCode - %s", err)
+	}
 
-func exportArtifactDir(pth, deployDir, envKey string) (string, error) {
-	base := filepath.Base(pth)
-	deployPth := filepath.Join(deployDir, base)
+	if _, err := configs.appleVersion(); err != nil {
+		return fmt.Errorf("AppleVersion - %s", err)
+	}
+
+	if err := input.ValidateWithOptions(configs.RestoreVersionedFiles, "yes", "no"); err != nil {
+		return fmt.Errorf("RestoreVersionedFiles - %s", err)
+	}
 
-	if err := command.CopyDir(pth, deployDir, false); err != nil {
-		return "", fmt.Errorf("failed to move artifact (%s) to (%s)", pth, deployDir)
+	if _, err := configs.androidSigning(); err != nil {
+		return fmt.Errorf("AndroidKeystorePath - %s", err)
 	}
 
-	if err := steputiltools.ExportEnvironmentWithEnvman(envKey, deployPth); err != nil {
-		return "", fmt.Errorf("failed to export artifact path (%s) into (%s)", deployPth, envKey)
+	if configs.AndroidKeystorePath != "" {
+		if err := input.ValidateIfPathExists(configs.AndroidKeystorePath); err != nil {
+			return fmt.Errorf("AndroidKeystorePath - %s", err)
+		}
 	}
 
-	return deployPth, nil
+	return nil
 }
 
-func exportArtifactFile(pth, deployDir, envKey string) (string, error) {
-	base := filepath.Base(pth)
-	deployPth := filepath.Join(deployDir, base)
+func (configs ConfigsModel) maxParallelBuilds() (int, error) {
+	if configs.MaxParallelBuilds == "" {
+		return 1, nil
+	}
 
-	if err := command.CopyFile(pth, deployPth); err != nil {
-		return "", fmt.Errorf("failed to move artifact (%s) to (%s)", pth, deployPth)
+	workers, err := strconv.Atoi(configs.MaxParallelBuilds)
+	if err != nil || workers < 1 {
+		return 0, fmt.Errorf("should be a positive integer, got: %s", configs.MaxParallelBuilds)
 	}
+	return workers, nil
+}
 
-	if err := steputiltools.ExportEnvironmentWithEnvman(envKey, deployPth); err != nil {
-		return "", fmt.Errorf("failed to export artifact path (%s) into (%s)", deployPth, envKey)
+func (configs ConfigsModel) commandTimeout() (time.Duration, error) {
+	if configs.CommandTimeout == "" || configs.CommandTimeout == "0" {
+		return 0, nil
 	}
 
-	return deployPth, nil
+	timeout, err := time.ParseDuration(configs.CommandTimeout)
+	if err != nil || timeout < 0 {
+		return 0, fmt.Errorf("should be a duration (like 45m or 1h30m), got: %s", configs.CommandTimeout)
+	}
+	return timeout, nil
 }
 
 func failf(format string, v ...interface{}) {
-	log.Errorf(format, v...)
+	log.Errorf("%s", tools.Redact(fmt.Sprintf(format, v...)))
+	restoreStampedFiles()
 	os.Exit(1)
 }
 
 func main() {
 	configs := createConfigsModelFromEnvs()
+	registerSecrets(configs)
 
 	fmt.Println()
 	configs.print()
@@ -192,7 +274,7 @@ func main() {
 
 		split, err := shellquote.Split(rawOptions)
 		if err != nil {
-			log.Errorf("failed to split options (%s), error: %s", rawOptions, err)
+			log.Errorf("failed to split options (%s), error: %s", tools.Redact(rawOptions), err)
 		}
 		projectTypeCustomOptions[projectType] = split
 	}
@@ -203,55 +285,226 @@ func main() {
 	fmt.Println()
 	log.Infof("Building all projects in solution: %s", configs.XamarinSolution)
 
-	buildTool := buildtools.Msbuild
-	if configs.BuildTool == "xbuild" {
-		buildTool = buildtools.Xbuild
+	buildTool, tool := buildtools.Msbuild, toolpath.Msbuild
+	switch configs.BuildTool {
+	case "xbuild":
+		buildTool, tool = buildtools.Xbuild, toolpath.Xbuild
+	case "dotnet":
+		buildTool, tool = buildtools.Dotnet, toolpath.Dotnet
 	}
 
+	toolpath.Override(tool, configs.BuildToolPath)
+	resolvedTool, err := toolpath.Resolve(tool)
+	if err != nil {
+		failf("Failed to find %s, error: %s", configs.BuildTool, err)
+	}
+	log.Printf("Using %s: %s", configs.BuildTool, resolvedTool)
+
 	b, err := builder.New(configs.XamarinSolution, projectTypeWhitelist, buildTool)
 	if err != nil {
 		failf("Failed to create xamarin builder, error: %s", err)
 	}
 
+	if err := b.SetProjectFilter(splitPatterns(configs.ProjectInclude), splitPatterns(configs.ProjectExclude)); err != nil {
+		failf("Failed to set project filter, error: %s", err)
+	}
+
+	if signing, _ := configs.androidSigning(); signing != nil {
+		if err := b.SetAndroidSigning(*signing); err != nil {
+			failf("Failed to set Android signing, error: %s", err)
+		}
+	}
+
+	var changeImpact *reportChangeImpact
+	if configs.ChangedFilesPath != "" {
+		baseDir, err := changedFilesBaseDir(configs.XamarinSolution)
+		if err != nil {
+			failf("Failed to find the base dir of the changed files, error: %s", err)
+		}
+
+		changedPths, err := readChangedFiles(configs.ChangedFilesPath, baseDir)
+		if err != nil {
+			failf("Failed to read changed files, error: %s", err)
+		}
+
+		impact := b.RestrictToChangedFiles(changedPths)
+		changeImpact = newReportChangeImpact(impact, b.ReferenceGraph(), len(changedPths))
+		printChangeImpact(changeImpact)
+	}
+
+	maxParallelBuilds, _ := configs.maxParallelBuilds()
+	b.SetParallelism(maxParallelBuilds)
+
+	commandTimeout, _ := configs.commandTimeout()
+	b.SetCommandTimeout(commandTimeout)
+
+	// the running build command is killed on SIGINT or SIGTERM, the step fails with the report written
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	b.SetContext(ctx)
+
+	report := newBuildReport(configs)
+	report.setBuildTool(resolvedTool)
+	report.setSolution(b.Solution(), b.ReferenceGraph())
+
+	var selectedProjectNames []string
+	for _, proj := range b.BuildableProjects(configs.XamarinConfiguration, configs.XamarinPlatform) {
+		selectedProjectNames = append(selectedProjectNames, proj.Name)
+	}
+	skippedProjects := b.SkippedProjects(configs.XamarinConfiguration, configs.XamarinPlatform)
+
+	printProjectSelection(selectedProjectNames, skippedProjects)
+	report.setSkippedProjects(skippedProjects)
+	report.ChangeImpact = changeImpact
+
+	if referenceWarnings := projectReferenceWarnings(b.ReferenceGraph()); len(referenceWarnings) > 0 {
+		fmt.Println()
+		log.Warnf("Project reference warnings:")
+		for _, warning := range referenceWarnings {
+			log.Warnf(warning)
+		}
+		report.Warnings = append(report.Warnings, referenceWarnings...)
+	}
+
+	if changeImpact != nil {
+		changeImpact.NothingToBuild = len(selectedProjectNames) == 0
+		if err := exportNothingToBuild(changeImpact.NothingToBuild); err != nil {
+			failWithReportf(report, configs.DeployDir, "Failed to export change impact, error: %s", err)
+		}
+
+		if changeImpact.NothingToBuild {
+			report.Status = buildStatusNothingToBuild
+
+			reportPth, err := report.write(configs.DeployDir)
+			if err != nil {
+				failWithReportf(report, configs.DeployDir, "Failed to write build report, error: %s", err)
+			}
+
+			fmt.Println()
+			log.Donef("None of the projects to build is affected by the changed files, nothing to build")
+			log.Printf("The build report path is now available in the Environment Variable: %s\nvalue: %s", buildReportEnvKey, reportPth)
+			return
+		}
+	}
+
+	versionProperties := map[string][]string{} // Project name - version MSBuild properties
+
 	prepareCallback := func(solutionName string, projectName string, sdk constants.SDK, testFramework constants.TestFramework, command *tools.Editable) {
-		options, ok := projectTypeCustomOptions[sdk]
-		if ok {
+		options := append([]string{}, projectTypeCustomOptions[sdk]...)
+		options = append(options, versionProperties[projectName]...)
+		if len(options) > 0 {
 			(*command).SetCustomOptions(options...)
 		}
 	}
 
+	propertyPrefix := "/p:"
+	if buildTool == buildtools.Dotnet {
+		propertyPrefix = "-p:"
+	}
+
+	androidVersion, _ := configs.androidVersion()
+	if androidVersion.Code != "" || androidVersion.Name != "" {
+		fmt.Println()
+		log.Infof("Stamping Android versions:")
+
+		stamps, properties, err := stampAndroidVersions(b.BuildableProjects(configs.XamarinConfiguration, configs.XamarinPlatform), androidVersion, configs.RestoreVersionedFiles == "yes", configs.DryRun == "yes", propertyPrefix)
+		if err != nil {
+			failWithReportf(report, configs.DeployDir, "Failed to stamp Android version, error: %s", err)
+		}
+		report.VersionStamps = append(report.VersionStamps, stamps...)
+		for projectName, projectProperties := range properties {
+			versionProperties[projectName] = append(versionProperties[projectName], projectProperties...)
+		}
+	}
+
+	appleVersion, _ := configs.appleVersion()
+	if appleVersion.BundleVersion != "" || appleVersion.BundleShortVersion != "" {
+		fmt.Println()
+		log.Infof("Stamping Apple versions:")
+
+		stamps, properties, err := stampAppleVersions(b.BuildableProjects(configs.XamarinConfiguration, configs.XamarinPlatform), appleVersion, configs.RestoreVersionedFiles == "yes", configs.DryRun == "yes", propertyPrefix)
+		if err != nil {
+			failWithReportf(report, configs.DeployDir, "Failed to stamp Apple version, error: %s", err)
+		}
+		report.VersionStamps = append(report.VersionStamps, stamps...)
+		for projectName, projectProperties := range properties {
+			versionProperties[projectName] = append(versionProperties[projectName], projectProperties...)
+		}
+	}
+
+	if configs.DryRun == "yes" {
+		fmt.Println()
+		plan, err := b.Plan(configs.XamarinConfiguration, configs.XamarinPlatform, true, prepareCallback)
+		if err != nil {
+			failWithReportf(report, configs.DeployDir, "Failed to plan the build, error: %s", err)
+		}
+
+		printPlan(plan)
+
+		pth, err := writePlan(plan, configs.DeployDir)
+		if err != nil {
+			failWithReportf(report, configs.DeployDir, "Failed to write build plan, error: %s", err)
+		}
+
+		fmt.Println()
+		log.Donef("The build plan path is now available in the Environment Variable: %s (value: %s)", buildPlanEnvKey, pth)
+		log.Warnf("Dry run, no project is built")
+		return
+	}
+
+	logsDir, err := setupBuildLogs(&b, configs.DeployDir)
+	if err != nil {
+		failWithReportf(report, configs.DeployDir, "Failed to set up build logs, error: %s", err)
+	}
+	report.LogsDir = logsDir
+
 	callback := func(solutionName string, projectName string, sdk constants.SDK, testFramework constants.TestFramework, commandStr string, alreadyPerformed bool) {
 		fmt.Println()
 		log.Infof("Building project: %s", projectName)
 		log.Donef("$ %s", commandStr)
 		if alreadyPerformed {
 			log.Warnf("build command already performed, skipping...")
+			report.addSkippedCommand(projectName, sdk, commandStr)
 		}
 		fmt.Println()
 	}
 
+	b.SetCommandResultCallback(func(solutionName string, projectName string, sdk constants.SDK, testFramework constants.TestFramework, commandStr string, duration time.Duration, err error) {
+		report.addCommandResult(projectName, sdk, commandStr, duration, err)
+	})
+
 	startTime := time.Now()
 
 	warnings, err := b.BuildAllProjects(configs.XamarinConfiguration, configs.XamarinPlatform, true, prepareCallback, callback)
+	restoreStampedFiles()
+	report.Warnings = append(report.Warnings, warnings...)
 	if len(warnings) > 0 {
 		log.Warnf("Build warnings:")
 		for _, warning := range warnings {
 			log.Warnf(warning)
 		}
 	}
+
+	diags := b.Diagnostics()
+	report.Diagnostics = append(report.Diagnostics, diags...)
+	if exportErr := exportDiagnostics(diags, logsDir); exportErr != nil {
+		log.Warnf("Failed to export build diagnostics, error: %s", exportErr)
+	}
+
 	if err != nil {
-		failf("Build failed, error: %s", err)
+		printFailureSummary(diags)
+		failWithReportf(report, configs.DeployDir, "Build failed, error: %s", err)
 	}
 
 	endTime := time.Now()
 
 	output, err := b.CollectProjectOutputs(configs.XamarinConfiguration, configs.XamarinPlatform, startTime, endTime)
 	if err != nil {
-		failf("Failed to collect output, error: %s", err)
+		failWithReportf(report, configs.DeployDir, "Failed to collect output, error: %s", err)
 	}
 
 	if len(output) == 0 {
-		failf("No output generated")
+		failWithReportf(report, configs.DeployDir, "No output generated")
 	}
 	// ---
 
@@ -259,7 +512,29 @@ func main() {
 	fmt.Println()
 	log.Infof("Exporting generated outputs...")
 
-	for projectName, projectOutput := range output {
+	projectNames := output.ProjectNames()
+
+	mainProject, matchingProjects, err := selectMainProject(configs.MainProject, projectNames)
+	if err != nil {
+		failWithReportf(report, configs.DeployDir, "Failed to select main project, error: %s", err)
+	}
+	if configs.MainProject != "" {
+		if mainProject == "" {
+			log.Warnf("No project with outputs matches the main project (%s), available: %v", configs.MainProject, projectNames)
+		} else if len(matchingProjects) > 1 {
+			log.Warnf("Multiple projects match the main project (%s): %v, using: %s", configs.MainProject, matchingProjects, mainProject)
+		}
+	}
+
+	exports := newArtifactExports()
+
+	projectsByName := map[string]project.Model{}
+	for _, proj := range b.Solution().ProjectMap {
+		projectsByName[proj.Name] = proj
+	}
+
+	for _, projectName := range projectNames {
+		projectOutput := output[projectName]
 		outputNumber := len(projectOutput.Outputs)
 		fmt.Println()
 		log.Donef("%s outputs (%d):", projectName, outputNumber)
@@ -267,149 +542,90 @@ func main() {
 		for i, output := range projectOutput.Outputs {
 			log.Infof("%d/%d - %s - Type: %s", i+1, outputNumber, output.Pth, projectOutput.ProjectType)
 
-			// Android outputs
-			if projectOutput.ProjectType == constants.SDKAndroid {
-
-				if output.OutputType == constants.OutputTypeAPK {
-					envKey := "BITRISE_APK_PATH"
-					pth, err := exportArtifactFile(output.Pth, configs.DeployDir, envKey)
-					if err != nil {
-						failf("Failed to export apk, error: %s", err)
-					}
-					fmt.Println()
-					log.Printf("The apk path is now available in the Environment Variable: %s\nvalue: %s", envKey, pth)
-				}
-
-				if output.OutputType == constants.OutputTypeAAB {
-					envKey := "BITRISE_AAB_PATH"
-					pth, err := exportArtifactFile(output.Pth, configs.DeployDir, envKey)
-					if err != nil {
-						failf("Failed to export aab, error: %s", err)
-					}
-					fmt.Println()
-					log.Printf("The aab path is now available in the Environment Variable: %s\nvalue: %s", envKey, pth)
-				}
+			descriptor, ok := lookupOutputDescriptor(projectOutput.ProjectType, output.OutputType)
+			if !ok {
+				log.Warnf("Output type (%s) of project type (%s) is not exported", output.OutputType, projectOutput.ProjectType)
+				continue
+			}
+
+			pth, err := descriptor.Export(output.Pth, configs.DeployDir, exports.deployName(output.Pth, projectName))
+			if err != nil {
+				failWithReportf(report, configs.DeployDir, "Failed to export %s, error: %s", descriptor.Description, err)
 			}
 
-			// IOS outputs
-			if projectOutput.ProjectType == constants.SDKIOS {
-				if output.OutputType == constants.OutputTypeXCArchive {
-					envKey := "BITRISE_XCARCHIVE_PATH"
-					pth, err := exportArtifactDir(output.Pth, configs.DeployDir, envKey)
-					if err != nil {
-						failf("Failed to export xcarchive, error: %s", err)
-					}
-					fmt.Println()
-					log.Printf("The xcarchive path is now available in the Environment Variable: %s\nvalue: %s", envKey, pth)
-				}
-
-				if output.OutputType == constants.OutputTypeIPA {
-					envKey := "BITRISE_IPA_PATH"
-					pth, err := exportArtifactFile(output.Pth, configs.DeployDir, envKey)
-					if err != nil {
-						failf("Failed to export ipa, error: %s", err)
-					}
-					fmt.Println()
-					log.Printf("The ipa path is now available in the Environment Variable: %s\nvalue: %s", envKey, pth)
-				}
-
-				if output.OutputType == constants.OutputTypeDSYM {
-					envKey := "BITRISE_DSYM_PATH"
-					pth, err := exportZippedArtifactDir(output.Pth, configs.DeployDir, envKey)
-					if err != nil {
-						failf("Failed to export dsym, error: %s", err)
-					}
-					fmt.Println()
-					log.Printf("The dsym zip path is now available in the Environment Variable: %s\nvalue: %s", envKey, pth)
-				}
-
-				if output.OutputType == constants.OutputTypeAPP {
-					envKey := "BITRISE_APP_PATH"
-					pth, err := exportArtifactDir(output.Pth, configs.DeployDir, envKey)
-					if err != nil {
-						failf("Failed to export app, error: %s", err)
-					}
-					fmt.Println()
-					log.Printf("The app path is now available in the Environment Variable: %s\nvalue: %s", envKey, pth)
-				}
+			artifact, err := inspectArtifact(exportedArtifact{
+				ProjectName: projectName,
+				ProjectType: projectOutput.ProjectType,
+				OutputType:  output.OutputType,
+				SourcePth:   output.Pth,
+				EnvKey:      descriptor.EnvKey,
+				Pth:         pth,
+			})
+			if err != nil {
+				log.Warnf("Failed to inspect %s, error: %s", descriptor.Description, err)
+			}
+			printArtifactMetadata(descriptor.Description, artifact)
+
+			if warning := xcarchiveBundleIDWarning(artifact, projectsByName[projectName]); warning != "" {
+				log.Warnf("%s", warning)
+				report.Warnings = append(report.Warnings, warning)
 			}
 
-			// TvOS outputs
-			if projectOutput.ProjectType == constants.SDKTvOS {
-				if output.OutputType == constants.OutputTypeXCArchive {
-					envKey := "BITRISE_TVOS_XCARCHIVE_PATH"
-					pth, err := exportArtifactDir(output.Pth, configs.DeployDir, envKey)
-					if err != nil {
-						failf("Failed to export xcarchive, error: %s", err)
-					}
-					fmt.Println()
-					log.Printf("The xcarchive path is now available in the Environment Variable: %s\nvalue: %s", envKey, pth)
-				}
-
-				if output.OutputType == constants.OutputTypeIPA {
-					envKey := "BITRISE_TVOS_IPA_PATH"
-					pth, err := exportArtifactFile(output.Pth, configs.DeployDir, envKey)
-					if err != nil {
-						failf("Failed to export ipa, error: %s", err)
-					}
-					fmt.Println()
-					log.Printf("The ipa path is now available in the Environment Variable: %s\nvalue: %s", envKey, pth)
-				}
-
-				if output.OutputType == constants.OutputTypeDSYM {
-					envKey := "BITRISE_TVOS_DSYM_PATH"
-					pth, err := exportZippedArtifactDir(output.Pth, configs.DeployDir, envKey)
-					if err != nil {
-						failf("Failed to export dsym, error: %s", err)
-					}
-					fmt.Println()
-					log.Printf("The dsym zip path is now available in the Environment Variable: %s\nvalue: %s", envKey, pth)
-				}
-
-				if output.OutputType == constants.OutputTypeAPP {
-					envKey := "BITRISE_TVOS_APP_PATH"
-					pth, err := exportArtifactDir(output.Pth, configs.DeployDir, envKey)
-					if err != nil {
-						failf("Failed to export app, error: %s", err)
-					}
-					fmt.Println()
-					log.Printf("The app path is now available in the Environment Variable: %s\nvalue: %s", envKey, pth)
-				}
+			artifact, err = exports.add(artifact)
+			if err != nil {
+				failWithReportf(report, configs.DeployDir, "Failed to export %s, error: %s", descriptor.Description, err)
 			}
 
-			// MacOS outputs
-			if projectOutput.ProjectType == constants.SDKMacOS {
-				if output.OutputType == constants.OutputTypeXCArchive {
-					envKey := "BITRISE_MACOS_XCARCHIVE_PATH"
-					pth, err := exportArtifactDir(output.Pth, configs.DeployDir, envKey)
-					if err != nil {
-						failf("Failed to export xcarchive, error: %s", err)
-					}
-					fmt.Println()
-					log.Printf("The xcarchive path is now available in the Environment Variable: %s\nvalue: %s", envKey, pth)
-				}
-
-				if output.OutputType == constants.OutputTypeAPP {
-					envKey := "BITRISE_MACOS_APP_PATH"
-					pth, err := exportArtifactDir(output.Pth, configs.DeployDir, envKey)
-					if err != nil {
-						failf("Failed to export app, error: %s", err)
-					}
-					fmt.Println()
-					log.Printf("The app path is now available in the Environment Variable: %s\nvalue: %s", envKey, pth)
-				}
-
-				if output.OutputType == constants.OutputTypePKG {
-					envKey := "BITRISE_MACOS_PKG_PATH"
-					pth, err := exportArtifactFile(output.Pth, configs.DeployDir, envKey)
-					if err != nil {
-						failf("Failed to export pkg, error: %s", err)
-					}
-					fmt.Println()
-					log.Printf("The pkg path is now available in the Environment Variable: %s\nvalue: %s", envKey, pth)
-				}
+			if artifact.ProjectEnvKey != "" {
+				fmt.Println()
+				log.Printf("The %s path is now available in the Environment Variable: %s\nvalue: %s", descriptor.Description, artifact.ProjectEnvKey, pth)
 			}
 		}
 	}
+
+	primaries, err := exports.exportPrimaries(mainProject)
+	if err != nil {
+		failWithReportf(report, configs.DeployDir, "Failed to export outputs, error: %s", err)
+	}
+
+	fmt.Println()
+	log.Infof("Exported outputs:")
+	for _, artifact := range primaries {
+		log.Printf("- %s: %s (project: %s)", artifact.EnvKey, artifact.Pth, artifact.ProjectName)
+	}
+
+	metadataPrimaries, err := exports.exportMetadata(mainProject)
+	if err != nil {
+		failWithReportf(report, configs.DeployDir, "Failed to export output metadata, error: %s", err)
+	}
+	for _, artifact := range metadataPrimaries {
+		log.Printf("- %s metadata: %s (project: %s)", artifact.ProjectType, artifact.Pth, artifact.ProjectName)
+	}
+
+	lists, err := exports.exportLists()
+	if err != nil {
+		failWithReportf(report, configs.DeployDir, "Failed to export output lists, error: %s", err)
+	}
+
+	if len(lists) > 0 {
+		fmt.Println()
+		log.Infof("Exported output lists:")
+		for _, envKey := range exports.envKeys {
+			key := listEnvKey(envKey)
+			log.Printf("- %s: %s", key, lists[key])
+		}
+	}
+	// ---
+
+	// Build report
+	report.setArtifacts(exports, primaries)
+
+	reportPth, err := report.write(configs.DeployDir)
+	if err != nil {
+		failWithReportf(report, configs.DeployDir, "Failed to write build report, error: %s", err)
+	}
+
+	fmt.Println()
+	log.Printf("The build report path is now available in the Environment Variable: %s\nvalue: %s", buildReportEnvKey, reportPth)
 	// ---
 }
@@ -0,0 +1,376 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	steputiltools "github.com/bitrise-io/go-steputils/tools"
+	"github.com/bitrise-io/go-utils/command"
+	"github.com/bitrise-io/go-utils/log"
+	"github.com/bitrise-steplib/steps-xamarin-archive/go-xamarin/analyzers/apk"
+	"github.com/bitrise-steplib/steps-xamarin-archive/go-xamarin/analyzers/bundle"
+	"github.com/bitrise-steplib/steps-xamarin-archive/go-xamarin/constants"
+)
+
+const listSeparator = "|"
+
+// artifactExporter copies the given output into the deploy dir under the given name and returns the deployed path.
+type artifactExporter func(pth, deployDir, name string) (string, error)
+
+// outputDescriptor describes how a project output type is deployed and which env key it is exported to.
+type outputDescriptor struct {
+	EnvKey      string
+	Description string
+	Export      artifactExporter
+}
+
+var outputDescriptors = map[constants.SDK]map[constants.OutputType]outputDescriptor{
+	constants.SDKAndroid: {
+		constants.OutputTypeAPK: {EnvKey: "BITRISE_APK_PATH", Description: "apk", Export: exportArtifactFile},
+		constants.OutputTypeAAB: {EnvKey: "BITRISE_AAB_PATH", Description: "aab", Export: exportArtifactFile},
+	},
+	constants.SDKIOS: {
+		constants.OutputTypeXCArchive: {EnvKey: "BITRISE_XCARCHIVE_PATH", Description: "xcarchive", Export: exportArtifactDir},
+		constants.OutputTypeIPA:       {EnvKey: "BITRISE_IPA_PATH", Description: "ipa", Export: exportArtifactFile},
+		constants.OutputTypeDSYM:      {EnvKey: "BITRISE_DSYM_PATH", Description: "dsym zip", Export: exportZippedArtifactDir},
+		constants.OutputTypeAPP:       {EnvKey: "BITRISE_APP_PATH", Description: "app", Export: exportArtifactDir},
+	},
+	constants.SDKTvOS: {
+		constants.OutputTypeXCArchive: {EnvKey: "BITRISE_TVOS_XCARCHIVE_PATH", Description: "xcarchive", Export: exportArtifactDir},
+		constants.OutputTypeIPA:       {EnvKey: "BITRISE_TVOS_IPA_PATH", Description: "ipa", Export: exportArtifactFile},
+		constants.OutputTypeDSYM:      {EnvKey: "BITRISE_TVOS_DSYM_PATH", Description: "dsym zip", Export: exportZippedArtifactDir},
+		constants.OutputTypeAPP:       {EnvKey: "BITRISE_TVOS_APP_PATH", Description: "app", Export: exportArtifactDir},
+	},
+	constants.SDKMacOS: {
+		constants.OutputTypeXCArchive: {EnvKey: "BITRISE_MACOS_XCARCHIVE_PATH", Description: "xcarchive", Export: exportArtifactDir},
+		constants.OutputTypeAPP:       {EnvKey: "BITRISE_MACOS_APP_PATH", Description: "app", Export: exportArtifactDir},
+		constants.OutputTypePKG:       {EnvKey: "BITRISE_MACOS_PKG_PATH", Description: "pkg", Export: exportArtifactFile},
+	},
+}
+
+// lookupOutputDescriptor returns the descriptor of the given project type's output type, if it is exportable.
+func lookupOutputDescriptor(projectType constants.SDK, outputType constants.OutputType) (outputDescriptor, bool) {
+	descriptors, ok := outputDescriptors[projectType]
+	if !ok {
+		return outputDescriptor{}, false
+	}
+	descriptor, ok := descriptors[outputType]
+	return descriptor, ok
+}
+
+// listEnvKey returns the env key holding every exported path of the given output env key.
+func listEnvKey(envKey string) string {
+	return envKey + "_LIST"
+}
+
+var nonEnvKeyCharRegexp = regexp.MustCompile(`[^A-Z0-9]+`)
+
+// projectEnvKey returns the project specific variant of the given output env key,
+// for example: BITRISE_APK_PATH + Multiplatform.Droid = BITRISE_APK_PATH_MULTIPLATFORM_DROID.
+func projectEnvKey(envKey, projectName string) string {
+	suffix := nonEnvKeyCharRegexp.ReplaceAllString(strings.ToUpper(projectName), "_")
+	suffix = strings.Trim(suffix, "_")
+	if suffix == "" {
+		return envKey
+	}
+	return envKey + "_" + suffix
+}
+
+// exportedArtifact is a deployed project output.
+type exportedArtifact struct {
+	ProjectName string
+	ProjectType constants.SDK
+	OutputType  constants.OutputType
+	SourcePth   string
+
+	EnvKey        string
+	ProjectEnvKey string
+	Pth           string
+
+	Metadata []metadataEnv // Exported once per project and once per project type, see exportMetadata
+	APK      *apk.Model
+	Bundle   *bundle.Model // Metadata of the application in an ipa or xcarchive
+}
+
+// artifactExports collects the deployed paths per output env key, in the order of exporting.
+type artifactExports struct {
+	envKeys        []string
+	artifacts      []exportedArtifact
+	projectEnvKeys map[string]string // Project env key - deployed path
+	deployNames    map[string]bool
+}
+
+func newArtifactExports() *artifactExports {
+	return &artifactExports{
+		projectEnvKeys: map[string]string{},
+		deployNames:    map[string]bool{},
+	}
+}
+
+// deployName returns the name of the given output in the deploy dir. Outputs of different projects often share
+// their file name (like App.app or App.ipa), the project name is added to the name of every output after the first one,
+// so that they do not overwrite each other.
+func (exports *artifactExports) deployName(pth, projectName string) string {
+	name := filepath.Base(pth)
+	if exports.deployNames[name] {
+		ext := filepath.Ext(name)
+		if ext == ".dSYM" {
+			ext = filepath.Ext(strings.TrimSuffix(name, ext)) + ext
+		}
+		stem := strings.TrimSuffix(name, ext)
+
+		name = stem + "-" + projectName + ext
+		for i := 2; exports.deployNames[name]; i++ {
+			name = fmt.Sprintf("%s-%s-%d%s", stem, projectName, i, ext)
+		}
+	}
+
+	exports.deployNames[name] = true
+	return name
+}
+
+// add exports the deployed path into the project specific env key and records it for the singular and list env keys.
+// If the project specific env key is already exported, by another output of the same project
+// or by a project with a colliding name (like App.iOS and App_iOS), a number is added to the key: BITRISE_IPA_PATH_APP_IOS_2.
+func (exports *artifactExports) add(artifact exportedArtifact) (exportedArtifact, error) {
+	if len(exports.artifactsOf(artifact.EnvKey)) == 0 {
+		exports.envKeys = append(exports.envKeys, artifact.EnvKey)
+	}
+
+	if key := exports.uniqueProjectEnvKey(artifact); key != artifact.EnvKey {
+		if err := steputiltools.ExportEnvironmentWithEnvman(key, artifact.Pth); err != nil {
+			return exportedArtifact{}, fmt.Errorf("failed to export artifact path (%s) into (%s)", artifact.Pth, key)
+		}
+
+		exports.projectEnvKeys[key] = artifact.Pth
+		artifact.ProjectEnvKey = key
+	}
+
+	exports.artifacts = append(exports.artifacts, artifact)
+
+	return artifact, nil
+}
+
+// uniqueProjectEnvKey returns the project specific env key of the artifact, numbered if the key is already exported.
+func (exports *artifactExports) uniqueProjectEnvKey(artifact exportedArtifact) string {
+	key := projectEnvKey(artifact.EnvKey, artifact.ProjectName)
+	if _, ok := exports.projectEnvKeys[key]; !ok {
+		return key
+	}
+
+	numbered := key
+	for i := 2; ; i++ {
+		numbered = fmt.Sprintf("%s_%d", key, i)
+		if _, ok := exports.projectEnvKeys[numbered]; !ok {
+			break
+		}
+	}
+	log.Warnf("Project specific env key (%s) is already exported, exporting (%s) into (%s)", key, artifact.Pth, numbered)
+	return numbered
+}
+
+func (exports *artifactExports) artifactsOf(envKey string) []exportedArtifact {
+	var artifacts []exportedArtifact
+	for _, artifact := range exports.artifacts {
+		if artifact.EnvKey == envKey {
+			artifacts = append(artifacts, artifact)
+		}
+	}
+	return artifacts
+}
+
+// primaryArtifact returns the artifact of the given env key which belongs to the main project,
+// or the first exported one if the main project has no such artifact.
+func (exports *artifactExports) primaryArtifact(envKey, mainProject string) exportedArtifact {
+	artifacts := exports.artifactsOf(envKey)
+	for _, artifact := range artifacts {
+		if mainProject != "" && artifact.ProjectName == mainProject {
+			return artifact
+		}
+	}
+	return artifacts[0]
+}
+
+// exportPrimaries exports the primary artifact of every recorded output env key into the singular env key.
+func (exports *artifactExports) exportPrimaries(mainProject string) ([]exportedArtifact, error) {
+	var primaries []exportedArtifact
+	for _, envKey := range exports.envKeys {
+		artifact := exports.primaryArtifact(envKey, mainProject)
+		if err := steputiltools.ExportEnvironmentWithEnvman(envKey, artifact.Pth); err != nil {
+			return nil, fmt.Errorf("failed to export artifact path (%s) into (%s)", artifact.Pth, envKey)
+		}
+		primaries = append(primaries, artifact)
+	}
+	return primaries, nil
+}
+
+// metadataOutputTypes are the output types the metadata is exported from, in the order of preference.
+var metadataOutputTypes = []constants.OutputType{constants.OutputTypeIPA, constants.OutputTypeXCArchive, constants.OutputTypeAPK}
+
+// metadataArtifact returns the first artifact with metadata of the most preferred output type,
+// the artifact of the main project is preferred among the artifacts of the same output type.
+func metadataArtifact(artifacts []exportedArtifact, mainProject string) (exportedArtifact, bool) {
+	for _, outputType := range metadataOutputTypes {
+		var candidates []exportedArtifact
+		for _, artifact := range artifacts {
+			if artifact.OutputType == outputType && len(artifact.Metadata) > 0 {
+				candidates = append(candidates, artifact)
+			}
+		}
+		if len(candidates) == 0 {
+			continue
+		}
+
+		for _, artifact := range candidates {
+			if mainProject != "" && artifact.ProjectName == mainProject {
+				return artifact, true
+			}
+		}
+		return candidates[0], true
+	}
+	return exportedArtifact{}, false
+}
+
+// exportMetadata exports the metadata of a single artifact of every project into the project specific env keys,
+// and the metadata of the primary artifact of every project type into the singular env keys (like BITRISE_IOS_BUNDLE_ID).
+// The artifact is picked by metadataArtifact, so that the metadata of an ipa and an xcarchive, or of two apps, is not mixed.
+// It returns the artifacts whose metadata is exported into the singular env keys.
+func (exports *artifactExports) exportMetadata(mainProject string) ([]exportedArtifact, error) {
+	var projectNames []string
+	var projectTypes []constants.SDK
+	artifactsByProject := map[string][]exportedArtifact{}
+	artifactsByType := map[constants.SDK][]exportedArtifact{}
+	for _, artifact := range exports.artifacts {
+		if _, ok := artifactsByProject[artifact.ProjectName]; !ok {
+			projectNames = append(projectNames, artifact.ProjectName)
+		}
+		artifactsByProject[artifact.ProjectName] = append(artifactsByProject[artifact.ProjectName], artifact)
+
+		if _, ok := artifactsByType[artifact.ProjectType]; !ok {
+			projectTypes = append(projectTypes, artifact.ProjectType)
+		}
+		artifactsByType[artifact.ProjectType] = append(artifactsByType[artifact.ProjectType], artifact)
+	}
+
+	for _, projectName := range projectNames {
+		artifact, ok := metadataArtifact(artifactsByProject[projectName], "")
+		if !ok || artifact.ProjectEnvKey == "" {
+			continue
+		}
+
+		// the number added to the artifact's project specific env key, if it collides with another project's key
+		suffix := strings.TrimPrefix(artifact.ProjectEnvKey, projectEnvKey(artifact.EnvKey, artifact.ProjectName))
+		for _, env := range artifact.Metadata {
+			if err := exportMetadataEnv(projectEnvKey(env.Key, artifact.ProjectName)+suffix, env.Value); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	var primaries []exportedArtifact
+	for _, projectType := range projectTypes {
+		artifact, ok := metadataArtifact(artifactsByType[projectType], mainProject)
+		if !ok {
+			continue
+		}
+
+		for _, env := range artifact.Metadata {
+			if err := exportMetadataEnv(env.Key, env.Value); err != nil {
+				return nil, err
+			}
+		}
+		primaries = append(primaries, artifact)
+	}
+	return primaries, nil
+}
+
+func exportMetadataEnv(key, value string) error {
+	if err := steputiltools.ExportEnvironmentWithEnvman(key, value); err != nil {
+		return fmt.Errorf("failed to export artifact metadata (%s) into (%s)", value, key)
+	}
+	return nil
+}
+
+// exportLists exports the list env key of every recorded output env key and returns the exported key - value pairs.
+func (exports *artifactExports) exportLists() (map[string]string, error) {
+	lists := map[string]string{}
+	for _, envKey := range exports.envKeys {
+		var pths []string
+		for _, artifact := range exports.artifactsOf(envKey) {
+			pths = append(pths, artifact.Pth)
+		}
+
+		key := listEnvKey(envKey)
+		value := strings.Join(pths, listSeparator)
+		if err := steputiltools.ExportEnvironmentWithEnvman(key, value); err != nil {
+			return nil, fmt.Errorf("failed to export artifact paths (%s) into (%s)", value, key)
+		}
+		lists[key] = value
+	}
+	return lists, nil
+}
+
+// selectMainProject returns the first project name (in the given order) matching the main project pattern,
+// the pattern is either a project name or a glob.
+func selectMainProject(pattern string, projectNames []string) (string, []string, error) {
+	if pattern == "" {
+		return "", nil, nil
+	}
+
+	var matching []string
+	for _, projectName := range projectNames {
+		if projectName == pattern {
+			matching = append(matching, projectName)
+			continue
+		}
+
+		match, err := filepath.Match(pattern, projectName)
+		if err != nil {
+			return "", nil, fmt.Errorf("invalid main project pattern (%s), error: %s", pattern, err)
+		}
+		if match {
+			matching = append(matching, projectName)
+		}
+	}
+
+	if len(matching) == 0 {
+		return "", nil, nil
+	}
+	return matching[0], matching, nil
+}
+
+func exportZippedArtifactDir(pth, deployDir, name string) (string, error) {
+	parentDir := filepath.Dir(pth)
+	dirName := filepath.Base(pth)
+	deployPth := filepath.Join(deployDir, name+".zip")
+	cmd := command.New("/usr/bin/zip", "-rTy", deployPth, dirName)
+	cmd.SetDir(parentDir)
+	out, err := cmd.RunAndReturnTrimmedCombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("failed to zip dir: %s, output: %s, error: %s", pth, out, err)
+	}
+
+	return deployPth, nil
+}
+
+func exportArtifactDir(pth, deployDir, name string) (string, error) {
+	deployPth := filepath.Join(deployDir, name)
+
+	if err := command.CopyDir(pth, deployPth, true); err != nil {
+		return "", fmt.Errorf("failed to move artifact (%s) to (%s)", pth, deployPth)
+	}
+
+	return deployPth, nil
+}
+
+func exportArtifactFile(pth, deployDir, name string) (string, error) {
+	deployPth := filepath.Join(deployDir, name)
+
+	if err := command.CopyFile(pth, deployPth); err != nil {
+		return "", fmt.Errorf("failed to move artifact (%s) to (%s)", pth, deployPth)
+	}
+
+	return deployPth, nil
+}
@@ -0,0 +1,152 @@
+package main
+
+import "testing"
+
+func TestDeployName(t *testing.T) {
+	type output struct {
+		pth         string
+		projectName string
+		want        string
+	}
+
+	tests := []struct {
+		name    string
+		outputs []output
+	}{
+		{
+			name: "unique names are kept",
+			outputs: []output{
+				{pth: "/src/Droid/bin/Release/com.app.droid-Signed.apk", projectName: "Droid", want: "com.app.droid-Signed.apk"},
+				{pth: "/src/iOS/bin/iPhone/Release/App.ipa", projectName: "iOS", want: "App.ipa"},
+			},
+		},
+		{
+			name: "colliding names get the project name",
+			outputs: []output{
+				{pth: "/src/App.iOS/bin/App.ipa", projectName: "App.iOS", want: "App.ipa"},
+				{pth: "/src/App.Beta/bin/App.ipa", projectName: "App.Beta", want: "App-App.Beta.ipa"},
+				{pth: "/src/App.Store/bin/App.ipa", projectName: "App.Store", want: "App-App.Store.ipa"},
+			},
+		},
+		{
+			name: "colliding names of the same project are numbered",
+			outputs: []output{
+				{pth: "/src/iOS/bin/Debug/App.app", projectName: "iOS", want: "App.app"},
+				{pth: "/src/iOS/bin/Release/App.app", projectName: "iOS", want: "App-iOS.app"},
+				{pth: "/src/iOS/bin/AppStore/App.app", projectName: "iOS", want: "App-iOS-2.app"},
+			},
+		},
+		{
+			name: "dSYM keeps its double extension",
+			outputs: []output{
+				{pth: "/src/iOS/bin/App.app.dSYM", projectName: "iOS", want: "App.app.dSYM"},
+				{pth: "/src/Watch/bin/App.app.dSYM", projectName: "Watch", want: "App-Watch.app.dSYM"},
+			},
+		},
+		{
+			name: "name without extension",
+			outputs: []output{
+				{pth: "/src/Mac/bin/App", projectName: "Mac", want: "App"},
+				{pth: "/src/Mac2/bin/App", projectName: "Mac2", want: "App-Mac2"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exports := newArtifactExports()
+			for _, output := range tt.outputs {
+				if got := exports.deployName(output.pth, output.projectName); got != output.want {
+					t.Errorf("deployName(%s, %s) = %s, want %s", output.pth, output.projectName, got, output.want)
+				}
+			}
+		})
+	}
+}
+
+func TestProjectEnvKey(t *testing.T) {
+	tests := []struct {
+		envKey      string
+		projectName string
+		want        string
+	}{
+		{envKey: "BITRISE_APK_PATH", projectName: "Multiplatform.Droid", want: "BITRISE_APK_PATH_MULTIPLATFORM_DROID"},
+		{envKey: "BITRISE_IPA_PATH", projectName: "my-app iOS", want: "BITRISE_IPA_PATH_MY_APP_IOS"},
+		{envKey: "BITRISE_IPA_PATH", projectName: "_App.iOS_", want: "BITRISE_IPA_PATH_APP_IOS"},
+		{envKey: "BITRISE_APK_PATH", projectName: "App2", want: "BITRISE_APK_PATH_APP2"},
+		{envKey: "BITRISE_APK_PATH", projectName: "...", want: "BITRISE_APK_PATH"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.projectName, func(t *testing.T) {
+			if got := projectEnvKey(tt.envKey, tt.projectName); got != tt.want {
+				t.Errorf("projectEnvKey(%s, %s) = %s, want %s", tt.envKey, tt.projectName, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUniqueProjectEnvKey(t *testing.T) {
+	tests := []struct {
+		name      string
+		artifacts []exportedArtifact
+		want      []string
+	}{
+		{
+			name: "different projects",
+			artifacts: []exportedArtifact{
+				{EnvKey: "BITRISE_APK_PATH", ProjectName: "App.Droid", Pth: "/deploy/app.apk"},
+				{EnvKey: "BITRISE_APK_PATH", ProjectName: "Wear.Droid", Pth: "/deploy/wear.apk"},
+			},
+			want: []string{"BITRISE_APK_PATH_APP_DROID", "BITRISE_APK_PATH_WEAR_DROID"},
+		},
+		{
+			name: "outputs of the same project are numbered",
+			artifacts: []exportedArtifact{
+				{EnvKey: "BITRISE_APK_PATH", ProjectName: "Droid", Pth: "/deploy/arm.apk"},
+				{EnvKey: "BITRISE_APK_PATH", ProjectName: "Droid", Pth: "/deploy/x86.apk"},
+				{EnvKey: "BITRISE_APK_PATH", ProjectName: "Droid", Pth: "/deploy/x64.apk"},
+			},
+			want: []string{"BITRISE_APK_PATH_DROID", "BITRISE_APK_PATH_DROID_2", "BITRISE_APK_PATH_DROID_3"},
+		},
+		{
+			name: "colliding project names are numbered",
+			artifacts: []exportedArtifact{
+				{EnvKey: "BITRISE_IPA_PATH", ProjectName: "App.iOS", Pth: "/deploy/App.ipa"},
+				{EnvKey: "BITRISE_IPA_PATH", ProjectName: "App_iOS", Pth: "/deploy/App-App_iOS.ipa"},
+			},
+			want: []string{"BITRISE_IPA_PATH_APP_IOS", "BITRISE_IPA_PATH_APP_IOS_2"},
+		},
+		{
+			name: "numbering skips the exported numbered keys",
+			artifacts: []exportedArtifact{
+				{EnvKey: "BITRISE_APK_PATH", ProjectName: "App 2", Pth: "/deploy/a.apk"},
+				{EnvKey: "BITRISE_APK_PATH", ProjectName: "App", Pth: "/deploy/b.apk"},
+				{EnvKey: "BITRISE_APK_PATH", ProjectName: "App", Pth: "/deploy/c.apk"},
+			},
+			want: []string{"BITRISE_APK_PATH_APP_2", "BITRISE_APK_PATH_APP", "BITRISE_APK_PATH_APP_3"},
+		},
+		{
+			name: "same project with different output types",
+			artifacts: []exportedArtifact{
+				{EnvKey: "BITRISE_IPA_PATH", ProjectName: "iOS", Pth: "/deploy/App.ipa"},
+				{EnvKey: "BITRISE_DSYM_PATH", ProjectName: "iOS", Pth: "/deploy/App.dSYM.zip"},
+			},
+			want: []string{"BITRISE_IPA_PATH_IOS", "BITRISE_DSYM_PATH_IOS"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exports := newArtifactExports()
+			for i, artifact := range tt.artifacts {
+				got := exports.uniqueProjectEnvKey(artifact)
+				if got != tt.want[i] {
+					t.Errorf("uniqueProjectEnvKey(%s, %s) = %s, want %s", artifact.EnvKey, artifact.ProjectName, got, tt.want[i])
+				}
+				// recorded like add does after exporting the key
+				exports.projectEnvKeys[got] = artifact.Pth
+			}
+		})
+	}
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	steputiltools "github.com/bitrise-io/go-steputils/tools"
+	"github.com/bitrise-io/go-utils/fileutil"
+	"github.com/bitrise-io/go-utils/log"
+	"github.com/bitrise-steplib/steps-xamarin-archive/go-xamarin/builder"
+	"github.com/bitrise-steplib/steps-xamarin-archive/go-xamarin/tools"
+)
+
+const (
+	buildPlanFileName = "build-plan.json"
+	buildPlanEnvKey   = "BITRISE_XAMARIN_BUILD_PLAN_PATH"
+)
+
+// printPlan logs the projects to build, the commands to run and the expected outputs.
+func printPlan(plan builder.PlanModel) {
+	log.Infof("Build plan for %s|%s:", plan.Configuration, plan.Platform)
+
+	for _, proj := range plan.Projects {
+		fmt.Println()
+		log.Donef("%s (%s, %s)", proj.Name, proj.SDK, proj.Configuration)
+		for _, command := range proj.Commands {
+			log.Printf("$ %s", command)
+		}
+		for _, output := range proj.Outputs {
+			log.Printf("- %s: %s", output.OutputType, strings.Join(output.ExpectedPaths, ", "))
+		}
+	}
+
+	fmt.Println()
+	log.Infof("Commands to run (%d):", len(plan.Commands))
+	for _, command := range plan.Commands {
+		log.Printf("$ %s", command.Command)
+		if len(command.ProjectNames) > 1 {
+			log.Printf("  shared by: %s", strings.Join(command.ProjectNames, ", "))
+		}
+	}
+
+	if len(plan.SkippedProjects) > 0 {
+		fmt.Println()
+		log.Warnf("Skipped projects:")
+		for _, skippedProject := range plan.SkippedProjects {
+			log.Warnf(skippedProject.Reason)
+		}
+	}
+
+	if len(plan.Warnings) > 0 {
+		fmt.Println()
+		log.Warnf("Plan warnings:")
+		for _, warning := range plan.Warnings {
+			log.Warnf(warning)
+		}
+	}
+}
+
+// writePlan writes the plan into the deploy dir and exports its path.
+func writePlan(plan builder.PlanModel, deployDir string) (string, error) {
+	content, err := json.MarshalIndent(plan, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to serialize build plan, error: %s", err)
+	}
+	content = tools.RedactJSON(content)
+
+	pth := filepath.Join(deployDir, buildPlanFileName)
+	if err := fileutil.WriteBytesToFile(pth, content); err != nil {
+		return "", fmt.Errorf("failed to write build plan to (%s), error: %s", pth, err)
+	}
+
+	if err := steputiltools.ExportEnvironmentWithEnvman(buildPlanEnvKey, pth); err != nil {
+		return "", fmt.Errorf("failed to export build plan path (%s) into (%s)", pth, buildPlanEnvKey)
+	}
+
+	return pth, nil
+}
@@ -0,0 +1,269 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"time"
+
+	steputiltools "github.com/bitrise-io/go-steputils/tools"
+	"github.com/bitrise-io/go-utils/fileutil"
+	"github.com/bitrise-io/go-utils/log"
+	"github.com/bitrise-steplib/steps-xamarin-archive/go-xamarin/analyzers/apk"
+	"github.com/bitrise-steplib/steps-xamarin-archive/go-xamarin/analyzers/bundle"
+	"github.com/bitrise-steplib/steps-xamarin-archive/go-xamarin/analyzers/solution"
+	"github.com/bitrise-steplib/steps-xamarin-archive/go-xamarin/builder"
+	"github.com/bitrise-steplib/steps-xamarin-archive/go-xamarin/constants"
+	"github.com/bitrise-steplib/steps-xamarin-archive/go-xamarin/tools"
+	"github.com/bitrise-steplib/steps-xamarin-archive/go-xamarin/tools/buildtools/diagnostics"
+	"github.com/bitrise-steplib/steps-xamarin-archive/go-xamarin/tools/toolpath"
+)
+
+const (
+	buildReportFileName = "build-report.json"
+	buildReportEnvKey   = "BITRISE_XAMARIN_BUILD_REPORT_PATH"
+
+	buildStatusSucceeded      = "succeeded"
+	buildStatusFailed         = "failed"
+	buildStatusNothingToBuild = "nothing_to_build"
+)
+
+// buildReport is the machine-readable summary of the step run, written into the deploy dir.
+type buildReport struct {
+	Solution      reportSolution `json:"solution"`
+	Configuration string         `json:"configuration"`
+	Platform      string         `json:"platform"`
+	BuildTool     string         `json:"build_tool"`
+
+	BuildToolPath    string `json:"build_tool_path"`
+	BuildToolVersion string `json:"build_tool_version"`
+
+	LogsDir string `json:"logs_dir,omitempty"`
+
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+
+	ChangeImpact  *reportChangeImpact   `json:"change_impact,omitempty"`
+	VersionStamps []*reportVersionStamp `json:"version_stamps"`
+
+	Commands        []reportCommand          `json:"commands"`
+	SkippedProjects []reportSkippedProject   `json:"skipped_projects"`
+	Warnings        []string                 `json:"warnings"`
+	Diagnostics     []diagnostics.Diagnostic `json:"diagnostics"`
+	Artifacts       []reportArtifact         `json:"artifacts"`
+}
+
+type reportSolution struct {
+	ID         string          `json:"id"`
+	Name       string          `json:"name"`
+	Path       string          `json:"path"`
+	FilterPath string          `json:"filter_path,omitempty"`
+	Projects   []reportProject `json:"projects"`
+}
+
+type reportProject struct {
+	ID             string `json:"id"`
+	TypeID         string `json:"type_id"`
+	Name           string `json:"name"`
+	Path           string `json:"path"`
+	SolutionFolder string `json:"solution_folder"`
+	SDK            string `json:"sdk"`
+	OutputType     string `json:"output_type"`
+
+	References []string `json:"references"`
+}
+
+type reportCommand struct {
+	Project          string  `json:"project"`
+	SDK              string  `json:"sdk"`
+	Command          string  `json:"command"`
+	AlreadyPerformed bool    `json:"already_performed"`
+	DurationSeconds  float64 `json:"duration_seconds"`
+	ExitCode         int     `json:"exit_code"`
+	Error            string  `json:"error,omitempty"`
+}
+
+type reportSkippedProject struct {
+	Project string `json:"project"`
+	Reason  string `json:"reason"`
+}
+
+type reportArtifact struct {
+	Project       string `json:"project"`
+	ProjectType   string `json:"project_type"`
+	OutputType    string `json:"output_type"`
+	SourcePath    string `json:"source_path"`
+	Path          string `json:"path"`
+	EnvKey        string `json:"env_key"`
+	MainArtifact  bool   `json:"main_artifact"`
+	ProjectEnvKey string `json:"project_env_key,omitempty"`
+	ListEnvKey    string `json:"list_env_key"`
+
+	APK    *apk.Model    `json:"apk,omitempty"`
+	Bundle *bundle.Model `json:"bundle,omitempty"`
+}
+
+func newBuildReport(configs ConfigsModel) *buildReport {
+	return &buildReport{
+		Configuration:   configs.XamarinConfiguration,
+		Platform:        configs.XamarinPlatform,
+		BuildTool:       configs.BuildTool,
+		Commands:        []reportCommand{},
+		SkippedProjects: []reportSkippedProject{},
+		VersionStamps:   []*reportVersionStamp{},
+		Warnings:        []string{},
+		Diagnostics:     []diagnostics.Diagnostic{},
+		Artifacts:       []reportArtifact{},
+	}
+}
+
+func (report *buildReport) setSolution(sln solution.Model, graph solution.GraphModel) {
+	report.Solution = reportSolution{
+		ID:         sln.ID,
+		Name:       sln.Name,
+		Path:       sln.Pth,
+		FilterPath: sln.FilterPth,
+		Projects:   []reportProject{},
+	}
+
+	for projectID, proj := range sln.ProjectMap {
+		references := []string{}
+		for _, referredID := range graph.Dependencies(projectID) {
+			references = append(references, graph.Name(referredID))
+		}
+		references = append(references, graph.MissingReferences(projectID)...)
+
+		report.Solution.Projects = append(report.Solution.Projects, reportProject{
+			ID:             proj.ID,
+			TypeID:         proj.TypeID,
+			Name:           proj.Name,
+			Path:           proj.Pth,
+			SolutionFolder: proj.SolutionFolder,
+			SDK:            string(proj.SDK),
+			OutputType:     proj.OutputType,
+			References:     references,
+		})
+	}
+	sort.Slice(report.Solution.Projects, func(i, j int) bool {
+		return report.Solution.Projects[i].Name < report.Solution.Projects[j].Name
+	})
+}
+
+func (report *buildReport) setBuildTool(tool toolpath.Resolved) {
+	report.BuildToolPath = tool.Path
+	report.BuildToolVersion = tool.Version
+}
+
+func (report *buildReport) setSkippedProjects(skippedProjects []builder.SkippedProjectModel) {
+	for _, skippedProject := range skippedProjects {
+		report.SkippedProjects = append(report.SkippedProjects, reportSkippedProject{
+			Project: skippedProject.ProjectName,
+			Reason:  skippedProject.Reason,
+		})
+	}
+}
+
+// addSkippedCommand records a command which was not run, because the same command was already performed.
+func (report *buildReport) addSkippedCommand(projectName string, sdk constants.SDK, commandStr string) {
+	report.Commands = append(report.Commands, reportCommand{
+		Project:          projectName,
+		SDK:              string(sdk),
+		Command:          commandStr,
+		AlreadyPerformed: true,
+	})
+}
+
+// addCommandResult records a performed command.
+func (report *buildReport) addCommandResult(projectName string, sdk constants.SDK, commandStr string, duration time.Duration, err error) {
+	command := reportCommand{
+		Project:         projectName,
+		SDK:             string(sdk),
+		Command:         commandStr,
+		DurationSeconds: duration.Seconds(),
+		ExitCode:        exitCode(err),
+	}
+	if err != nil {
+		command.Error = err.Error()
+	}
+	report.Commands = append(report.Commands, command)
+}
+
+func (report *buildReport) setArtifacts(exports *artifactExports, primaries []exportedArtifact) {
+	isPrimary := func(artifact exportedArtifact) bool {
+		for _, primary := range primaries {
+			if primary.EnvKey == artifact.EnvKey && primary.Pth == artifact.Pth {
+				return true
+			}
+		}
+		return false
+	}
+
+	for _, artifact := range exports.artifacts {
+		report.Artifacts = append(report.Artifacts, reportArtifact{
+			Project:       artifact.ProjectName,
+			ProjectType:   string(artifact.ProjectType),
+			OutputType:    string(artifact.OutputType),
+			SourcePath:    artifact.SourcePth,
+			Path:          artifact.Pth,
+			EnvKey:        artifact.EnvKey,
+			MainArtifact:  isPrimary(artifact),
+			ProjectEnvKey: artifact.ProjectEnvKey,
+			ListEnvKey:    listEnvKey(artifact.EnvKey),
+			APK:           artifact.APK,
+			Bundle:        artifact.Bundle,
+		})
+	}
+}
+
+func (report *buildReport) fail(err error) {
+	report.Status = buildStatusFailed
+	report.Error = err.Error()
+}
+
+// write writes the report into the deploy dir and exports its path.
+func (report *buildReport) write(deployDir string) (string, error) {
+	if report.Status == "" {
+		report.Status = buildStatusSucceeded
+	}
+
+	content, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to serialize build report, error: %s", err)
+	}
+	content = tools.RedactJSON(content)
+
+	pth := filepath.Join(deployDir, buildReportFileName)
+	if err := fileutil.WriteBytesToFile(pth, content); err != nil {
+		return "", fmt.Errorf("failed to write build report to (%s), error: %s", pth, err)
+	}
+
+	if err := steputiltools.ExportEnvironmentWithEnvman(buildReportEnvKey, pth); err != nil {
+		return "", fmt.Errorf("failed to export build report path (%s) into (%s)", pth, buildReportEnvKey)
+	}
+
+	return pth, nil
+}
+
+// failWithReportf marks the report as failed, restores the stamped files, writes the report into the deploy dir and exits.
+// The files are restored before writing, so that the report records whether restoring succeeded.
+func failWithReportf(report *buildReport, deployDir, format string, v ...interface{}) {
+	report.fail(fmt.Errorf(format, v...))
+	restoreStampedFiles()
+	if _, err := report.write(deployDir); err != nil {
+		log.Warnf("Failed to write build report, error: %s", err)
+	}
+	failf(format, v...)
+}
+
+// exitCode returns the exit status of a finished command: 0 on success, -1 if the command did not exit normally.
+func exitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+	if exitErr, ok := err.(*exec.ExitError); ok {
+		return exitErr.ExitCode()
+	}
+	return -1
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"github.com/bitrise-steplib/steps-xamarin-archive/go-xamarin/tools"
+	"github.com/kballard/go-shellquote"
+)
+
+// registerSecrets registers the values to mask in the printed commands, logs and reports:
+// the sensitive inputs, the values of the listed env vars and the sensitive MSBuild properties of the custom options.
+func registerSecrets(configs ConfigsModel) {
+	tools.RegisterSecrets(configs.AndroidKeystorePassword, configs.AndroidKeyPassword)
+	tools.RegisterSecretEnvs(splitPatterns(configs.SecretEnvVars)...)
+
+	for _, rawOptions := range []string{configs.AndroidCustomOptions, configs.IOSCustomOptions, configs.TvOSCustomOptions, configs.MacOSCustomOptions} {
+		options, err := shellquote.Split(rawOptions)
+		if err != nil {
+			// the options are reported once they are parsed for the build
+			continue
+		}
+		tools.RegisterSensitiveArgs(options...)
+	}
+}
+
+// secretInput returns the printable value of a secret input: the secret mask if the input is set,
+// short secrets are not masked by tools.Redact.
+func secretInput(value string) string {
+	if value == "" {
+		return ""
+	}
+	return tools.SecretMask
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/bitrise-io/go-utils/log"
+	"github.com/bitrise-steplib/steps-xamarin-archive/go-xamarin/analyzers/solution"
+	"github.com/bitrise-steplib/steps-xamarin-archive/go-xamarin/builder"
+)
+
+// splitPatterns splits a comma or newline separated list, like the project patterns.
+func splitPatterns(list string) []string {
+	var patterns []string
+	for _, item := range strings.FieldsFunc(list, func(r rune) bool { return r == ',' || r == '\n' }) {
+		if pattern := strings.TrimSpace(item); pattern != "" {
+			patterns = append(patterns, pattern)
+		}
+	}
+	return patterns
+}
+
+// printProjectSelection logs the projects to build and the skipped projects with the reason of skipping.
+func printProjectSelection(projectNames []string, skippedProjects []builder.SkippedProjectModel) {
+	fmt.Println()
+	log.Infof("Selected projects (%d):", len(projectNames))
+	for _, projectName := range projectNames {
+		log.Printf("- %s", projectName)
+	}
+
+	if len(skippedProjects) > 0 {
+		log.Infof("Skipped projects (%d):", len(skippedProjects))
+		for _, skippedProject := range skippedProjects {
+			log.Printf("- %s: %s", skippedProject.ProjectName, skippedProject.Reason)
+		}
+	}
+}
+
+// projectReferenceWarnings returns the references to projects missing from the solution and the reference cycle, if any.
+func projectReferenceWarnings(graph solution.GraphModel) []string {
+	warnings := append([]string{}, graph.Warnings...)
+	if _, err := graph.TopologicalOrder(); err != nil {
+		warnings = append(warnings, err.Error())
+	}
+	return warnings
+}
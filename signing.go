@@ -0,0 +1,33 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/bitrise-steplib/steps-xamarin-archive/go-xamarin/builder"
+)
+
+// androidSigning returns the keystore to sign the Android packages with, nil if no keystore is given.
+func (configs ConfigsModel) androidSigning() (*builder.AndroidSigningModel, error) {
+	keystorePth := strings.TrimSpace(configs.AndroidKeystorePath)
+	if keystorePth == "" {
+		if configs.AndroidKeystoreAlias != "" || configs.AndroidKeystorePassword != "" || configs.AndroidKeyPassword != "" {
+			return nil, fmt.Errorf("keystore alias and passwords are given without keystore path")
+		}
+		return nil, nil
+	}
+
+	if configs.AndroidKeystoreAlias == "" {
+		return nil, fmt.Errorf("no keystore alias given for keystore: %s", keystorePth)
+	}
+	if configs.AndroidKeystorePassword == "" {
+		return nil, fmt.Errorf("no keystore password given for keystore: %s", keystorePth)
+	}
+
+	return &builder.AndroidSigningModel{
+		KeystorePth:      keystorePth,
+		KeystorePassword: configs.AndroidKeystorePassword,
+		KeyAlias:         configs.AndroidKeystoreAlias,
+		KeyPassword:      configs.AndroidKeyPassword,
+	}, nil
+}
@@ -0,0 +1,326 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/bitrise-io/go-utils/fileutil"
+	"github.com/bitrise-io/go-utils/log"
+	"github.com/bitrise-steplib/steps-xamarin-archive/go-xamarin/analyzers/project"
+	"github.com/bitrise-steplib/steps-xamarin-archive/go-xamarin/constants"
+	"github.com/bitrise-steplib/steps-xamarin-archive/go-xamarin/versioning"
+)
+
+// reportVersionStamp is a version written into a project file before the build.
+type reportVersionStamp struct {
+	Project  string            `json:"project"`
+	File     string            `json:"file"`
+	Values   map[string]string `json:"values"`   // Applied values by key, like android:versionCode
+	Original map[string]string `json:"original"` // Values before stamping, empty if the file did not define them
+	Restored bool              `json:"restored"`
+}
+
+// stampedFile is a project file rewritten by version stamping, which is restored after the build.
+// Projects may share the same file, each of them has a stamp in the report.
+type stampedFile struct {
+	pth      string
+	original []byte
+	stamps   []*reportVersionStamp
+}
+
+// stampedFiles are the files to restore after the build, also restored if the step fails.
+var stampedFiles []*stampedFile
+
+// addStampedFile records the original content of the stamped file, the first time the file is stamped.
+func addStampedFile(pth string, original []byte, stamp *reportVersionStamp) {
+	for _, file := range stampedFiles {
+		if file.pth == pth {
+			file.stamps = append(file.stamps, stamp)
+			return
+		}
+	}
+	stampedFiles = append(stampedFiles, &stampedFile{pth: pth, original: original, stamps: []*reportVersionStamp{stamp}})
+}
+
+// restoreStampedFiles writes back the original content of the stamped files.
+func restoreStampedFiles() {
+	files := stampedFiles
+	stampedFiles = nil
+
+	for _, file := range files {
+		if err := fileutil.WriteBytesToFile(file.pth, file.original); err != nil {
+			log.Warnf("Failed to restore (%s), error: %s", file.pth, err)
+			continue
+		}
+		for _, stamp := range file.stamps {
+			stamp.Restored = true
+		}
+		log.Printf("Restored: %s", file.pth)
+	}
+}
+
+// sharedStamp returns the stamp of the given project for a file already stamped for another project,
+// the file is not rewritten again, so that its original version and content are kept.
+func sharedStamp(projectName, pth string, stamped map[string]*reportVersionStamp, restore bool) (*reportVersionStamp, bool) {
+	first, ok := stamped[pth]
+	if !ok {
+		return nil, false
+	}
+
+	stamp := &reportVersionStamp{
+		Project:  projectName,
+		File:     pth,
+		Values:   first.Values,
+		Original: first.Original,
+	}
+	if restore {
+		addStampedFile(pth, nil, stamp)
+	}
+
+	log.Printf("- %s: %s (%s, shared with %s)", projectName, formatVersionValues(stamp.Values), pth, first.Project)
+	return stamp, true
+}
+
+// androidVersion returns the version to stamp, the inputs may refer env vars, like: $BITRISE_BUILD_NUMBER.
+func (configs ConfigsModel) androidVersion() (versioning.AndroidVersion, error) {
+	version := versioning.AndroidVersion{
+		Name: strings.TrimSpace(os.ExpandEnv(configs.AndroidVersionName)),
+	}
+
+	offset := 0
+	if configs.AndroidVersionCodeOffset != "" {
+		var err error
+		if offset, err = strconv.Atoi(configs.AndroidVersionCodeOffset); err != nil {
+			return versioning.AndroidVersion{}, fmt.Errorf("version code offset (%s) is not an integer", configs.AndroidVersionCodeOffset)
+		}
+	}
+
+	code := strings.TrimSpace(os.ExpandEnv(configs.AndroidVersionCode))
+	if code == "" {
+		if offset != 0 {
+			return versioning.AndroidVersion{}, fmt.Errorf("version code offset (%d) is set without a version code", offset)
+		}
+		return version, nil
+	}
+
+	var err error
+	version.Code, err = versioning.ParseAndroidVersionCode(code, offset)
+	return version, err
+}
+
+// appleVersion returns the version to stamp, the inputs may refer env vars, like: $BITRISE_BUILD_NUMBER.
+func (configs ConfigsModel) appleVersion() (versioning.AppleVersion, error) {
+	version := versioning.AppleVersion{
+		BundleVersion:      strings.TrimSpace(os.ExpandEnv(configs.AppleBundleVersion)),
+		BundleShortVersion: strings.TrimSpace(os.ExpandEnv(configs.AppleBundleShortVersion)),
+	}
+
+	if version.BundleVersion != "" {
+		if err := versioning.ValidateAppleVersion(version.BundleVersion); err != nil {
+			return versioning.AppleVersion{}, fmt.Errorf("CFBundleVersion: %s", err)
+		}
+	}
+	if version.BundleShortVersion != "" {
+		if err := versioning.ValidateAppleVersion(version.BundleShortVersion); err != nil {
+			return versioning.AppleVersion{}, fmt.Errorf("CFBundleShortVersionString: %s", err)
+		}
+	}
+	return version, nil
+}
+
+// versionStamper is the platform specific part of version stamping: which projects it stamps,
+// the file holding their version and how the version is read and written.
+type versionStamper struct {
+	fileName    string // The name of the version file, like Info.plist
+	sdks        []constants.SDK
+	versionFile func(proj project.Model) string
+
+	values                    map[string]string // Values to stamp by key, like android:versionCode, empty values are not stamped
+	applicationVersion        string            // The ApplicationVersion property of SDK-style projects
+	applicationDisplayVersion string            // The ApplicationDisplayVersion property of SDK-style projects
+
+	read  func(pth string) (map[string]string, error) // Returns the values of the file by key
+	write func(pth string) ([]byte, error)            // Stamps the file and returns its original content
+}
+
+func androidVersionStamper(version versioning.AndroidVersion) versionStamper {
+	return versionStamper{
+		fileName:    "AndroidManifest.xml",
+		sdks:        []constants.SDK{constants.SDKAndroid},
+		versionFile: func(proj project.Model) string { return proj.ManifestPth },
+
+		values: map[string]string{
+			"android:versionCode": version.Code,
+			"android:versionName": version.Name,
+		},
+		applicationVersion:        version.Code,
+		applicationDisplayVersion: version.Name,
+
+		read: func(pth string) (map[string]string, error) {
+			original, err := versioning.ReadAndroidManifestVersion(pth)
+			if err != nil {
+				return nil, err
+			}
+			return map[string]string{
+				"android:versionCode": original.Code,
+				"android:versionName": original.Name,
+			}, nil
+		},
+		write: func(pth string) ([]byte, error) {
+			return versioning.StampAndroidManifest(pth, version)
+		},
+	}
+}
+
+func appleVersionStamper(version versioning.AppleVersion) versionStamper {
+	return versionStamper{
+		fileName:    "Info.plist",
+		sdks:        []constants.SDK{constants.SDKIOS, constants.SDKTvOS, constants.SDKMacOS},
+		versionFile: func(proj project.Model) string { return proj.InfoPlistPth },
+
+		values: map[string]string{
+			"CFBundleVersion":            version.BundleVersion,
+			"CFBundleShortVersionString": version.BundleShortVersion,
+		},
+		applicationVersion:        version.BundleVersion,
+		applicationDisplayVersion: version.BundleShortVersion,
+
+		read: func(pth string) (map[string]string, error) {
+			original, err := versioning.ReadInfoPlistVersion(pth)
+			if err != nil {
+				return nil, err
+			}
+			return map[string]string{
+				"CFBundleVersion":            original.BundleVersion,
+				"CFBundleShortVersionString": original.BundleShortVersion,
+			}, nil
+		},
+		write: func(pth string) ([]byte, error) {
+			return versioning.StampInfoPlist(pth, version)
+		},
+	}
+}
+
+// stampAndroidVersions writes the version into the manifest of every given Android project
+// and returns the MSBuild properties to pass to the build of each SDK-style project, by project name.
+// SDK-style projects define the version by the ApplicationVersion and ApplicationDisplayVersion properties,
+// which override the manifest. In dry run mode the manifests are not written.
+func stampAndroidVersions(projects []project.Model, version versioning.AndroidVersion, restore, dryRun bool, propertyPrefix string) ([]*reportVersionStamp, map[string][]string, error) {
+	return stampVersions(projects, androidVersionStamper(version), restore, dryRun, propertyPrefix)
+}
+
+// stampAppleVersions writes the version into the Info.plist of every given Apple project
+// and returns the MSBuild properties to pass to the build of each SDK-style project, by project name.
+// SDK-style projects define the version by the ApplicationVersion and ApplicationDisplayVersion properties,
+// which override the Info.plist. In dry run mode the Info.plists are not written.
+func stampAppleVersions(projects []project.Model, version versioning.AppleVersion, restore, dryRun bool, propertyPrefix string) ([]*reportVersionStamp, map[string][]string, error) {
+	return stampVersions(projects, appleVersionStamper(version), restore, dryRun, propertyPrefix)
+}
+
+// stampVersions writes the version into the version file of every project of the stamper's platforms,
+// a file shared by several projects is stamped once. It returns the stamps and the MSBuild properties
+// of the SDK-style projects, by project name.
+func stampVersions(projects []project.Model, stamper versionStamper, restore, dryRun bool, propertyPrefix string) ([]*reportVersionStamp, map[string][]string, error) {
+	stamps := []*reportVersionStamp{}
+	properties := map[string][]string{}
+	stamped := map[string]*reportVersionStamp{} // Version file path - first stamp
+
+	for _, proj := range projects {
+		if !containsSDK(stamper.sdks, proj.SDK) {
+			continue
+		}
+
+		if proj.SDKStyle {
+			if stamper.applicationVersion != "" {
+				properties[proj.Name] = append(properties[proj.Name], propertyPrefix+"ApplicationVersion="+stamper.applicationVersion)
+			}
+			if stamper.applicationDisplayVersion != "" {
+				properties[proj.Name] = append(properties[proj.Name], propertyPrefix+"ApplicationDisplayVersion="+stamper.applicationDisplayVersion)
+			}
+		}
+
+		if stamper.versionFile(proj) == "" {
+			if !proj.SDKStyle {
+				log.Warnf("Project (%s) has no %s, version not stamped", proj.Name, stamper.fileName)
+			}
+			continue
+		}
+
+		pth := filepath.Clean(stamper.versionFile(proj))
+		if stamp, ok := sharedStamp(proj.Name, pth, stamped, restore); ok {
+			stamps = append(stamps, stamp)
+			continue
+		}
+
+		originalValues, err := stamper.read(pth)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		var original []byte
+		if !dryRun {
+			original, err = stamper.write(pth)
+			if err != nil {
+				return nil, nil, err
+			}
+		}
+
+		stamp := &reportVersionStamp{
+			Project:  proj.Name,
+			File:     pth,
+			Values:   map[string]string{},
+			Original: map[string]string{},
+		}
+		for key, value := range stamper.values {
+			if value == "" {
+				continue
+			}
+			stamp.Values[key] = value
+			if originalValues[key] != "" {
+				stamp.Original[key] = originalValues[key]
+			}
+		}
+		stamps = append(stamps, stamp)
+		stamped[pth] = stamp
+
+		if dryRun {
+			log.Printf("- %s: %s (%s, dry run, not written)", proj.Name, formatVersionValues(stamp.Values), pth)
+			continue
+		}
+
+		if restore {
+			addStampedFile(pth, original, stamp)
+		}
+
+		log.Printf("- %s: %s (%s)", proj.Name, formatVersionValues(stamp.Values), pth)
+	}
+
+	return stamps, properties, nil
+}
+
+func containsSDK(sdks []constants.SDK, sdk constants.SDK) bool {
+	for _, s := range sdks {
+		if s == sdk {
+			return true
+		}
+	}
+	return false
+}
+
+func formatVersionValues(values map[string]string) string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var formatted []string
+	for _, key := range keys {
+		formatted = append(formatted, key+"="+values[key])
+	}
+	return strings.Join(formatted, ", ")
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/bitrise-steplib/steps-xamarin-archive/go-xamarin/analyzers/project"
+	"github.com/bitrise-steplib/steps-xamarin-archive/go-xamarin/constants"
+	"github.com/bitrise-steplib/steps-xamarin-archive/go-xamarin/versioning"
+)
+
+const testManifest = `<manifest xmlns:android="http://schemas.android.com/apk/res/android" android:versionCode="1" package="com.app"></manifest>`
+
+func TestStampAndroidVersions(t *testing.T) {
+	type wantStamp struct {
+		project  string
+		file     string // relative to the test dir
+		original map[string]string
+	}
+
+	tests := []struct {
+		name           string
+		projects       []project.Model // manifest paths are relative to the test dir
+		dryRun         bool
+		wantStamps     []wantStamp
+		wantProperties map[string][]string
+		wantManifests  map[string]string // Manifest path - content after stamping
+	}{
+		{
+			name: "every Android project is stamped",
+			projects: []project.Model{
+				{Name: "Droid", SDK: constants.SDKAndroid, ManifestPth: "Droid/AndroidManifest.xml"},
+				{Name: "iOS", SDK: constants.SDKIOS, InfoPlistPth: "iOS/Info.plist"},
+				{Name: "Wear", SDK: constants.SDKAndroid, ManifestPth: "Wear/AndroidManifest.xml"},
+			},
+			wantStamps: []wantStamp{
+				{project: "Droid", file: "Droid/AndroidManifest.xml", original: map[string]string{"android:versionCode": "1"}},
+				{project: "Wear", file: "Wear/AndroidManifest.xml", original: map[string]string{"android:versionCode": "1"}},
+			},
+			wantProperties: map[string][]string{},
+			wantManifests: map[string]string{
+				"Droid/AndroidManifest.xml": `<manifest xmlns:android="http://schemas.android.com/apk/res/android" android:versionCode="42" package="com.app" android:versionName="1.4"></manifest>`,
+				"Wear/AndroidManifest.xml":  `<manifest xmlns:android="http://schemas.android.com/apk/res/android" android:versionCode="42" package="com.app" android:versionName="1.4"></manifest>`,
+			},
+		},
+		{
+			name: "shared manifest is stamped once",
+			projects: []project.Model{
+				{Name: "Free", SDK: constants.SDKAndroid, ManifestPth: "Shared/AndroidManifest.xml"},
+				{Name: "Paid", SDK: constants.SDKAndroid, ManifestPth: "Shared/../Shared/AndroidManifest.xml"},
+			},
+			wantStamps: []wantStamp{
+				{project: "Free", file: "Shared/AndroidManifest.xml", original: map[string]string{"android:versionCode": "1"}},
+				{project: "Paid", file: "Shared/AndroidManifest.xml", original: map[string]string{"android:versionCode": "1"}},
+			},
+			wantProperties: map[string][]string{},
+			wantManifests: map[string]string{
+				"Shared/AndroidManifest.xml": `<manifest xmlns:android="http://schemas.android.com/apk/res/android" android:versionCode="42" package="com.app" android:versionName="1.4"></manifest>`,
+			},
+		},
+		{
+			name: "SDK-style projects get the version properties",
+			projects: []project.Model{
+				{Name: "Maui", SDK: constants.SDKAndroid, SDKStyle: true, ManifestPth: "Maui/Platforms/Android/AndroidManifest.xml"},
+				{Name: "NoManifest", SDK: constants.SDKAndroid, SDKStyle: true},
+			},
+			wantStamps: []wantStamp{
+				{project: "Maui", file: "Maui/Platforms/Android/AndroidManifest.xml", original: map[string]string{"android:versionCode": "1"}},
+			},
+			wantProperties: map[string][]string{
+				"Maui":       {"/p:ApplicationVersion=42", "/p:ApplicationDisplayVersion=1.4"},
+				"NoManifest": {"/p:ApplicationVersion=42", "/p:ApplicationDisplayVersion=1.4"},
+			},
+			wantManifests: map[string]string{
+				"Maui/Platforms/Android/AndroidManifest.xml": `<manifest xmlns:android="http://schemas.android.com/apk/res/android" android:versionCode="42" package="com.app" android:versionName="1.4"></manifest>`,
+			},
+		},
+		{
+			name: "dry run does not write the manifests",
+			projects: []project.Model{
+				{Name: "Droid", SDK: constants.SDKAndroid, ManifestPth: "Droid/AndroidManifest.xml"},
+			},
+			dryRun: true,
+			wantStamps: []wantStamp{
+				{project: "Droid", file: "Droid/AndroidManifest.xml", original: map[string]string{"android:versionCode": "1"}},
+			},
+			wantProperties: map[string][]string{},
+			wantManifests: map[string]string{
+				"Droid/AndroidManifest.xml": testManifest,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpDir, err := ioutil.TempDir("", "versions")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer func() {
+				if err := os.RemoveAll(tmpDir); err != nil {
+					t.Log(err)
+				}
+			}()
+
+			projects := []project.Model{}
+			for _, proj := range tt.projects {
+				if proj.ManifestPth != "" {
+					proj.ManifestPth = filepath.Join(tmpDir, proj.ManifestPth)
+					if err := os.MkdirAll(filepath.Dir(proj.ManifestPth), 0755); err != nil {
+						t.Fatal(err)
+					}
+					if err := ioutil.WriteFile(proj.ManifestPth, []byte(testManifest), 0644); err != nil {
+						t.Fatal(err)
+					}
+				}
+				projects = append(projects, proj)
+			}
+
+			version := versioning.AndroidVersion{Code: "42", Name: "1.4"}
+			stamps, properties, err := stampAndroidVersions(projects, version, true, tt.dryRun, "/p:")
+			if err != nil {
+				t.Fatalf("stampAndroidVersions() error = %s", err)
+			}
+
+			if len(stamps) != len(tt.wantStamps) {
+				t.Fatalf("stampAndroidVersions() returned %d stamps, want %d", len(stamps), len(tt.wantStamps))
+			}
+			for i, want := range tt.wantStamps {
+				stamp := stamps[i]
+				if stamp.Project != want.project || stamp.File != filepath.Join(tmpDir, want.file) {
+					t.Errorf("stamp %d = %s (%s), want %s (%s)", i, stamp.Project, stamp.File, want.project, want.file)
+				}
+				if wantValues := map[string]string{"android:versionCode": "42", "android:versionName": "1.4"}; !reflect.DeepEqual(stamp.Values, wantValues) {
+					t.Errorf("stamp %d values = %v, want %v", i, stamp.Values, wantValues)
+				}
+				if !reflect.DeepEqual(stamp.Original, want.original) {
+					t.Errorf("stamp %d original = %v, want %v", i, stamp.Original, want.original)
+				}
+			}
+
+			if !reflect.DeepEqual(properties, tt.wantProperties) {
+				t.Errorf("stampAndroidVersions() properties = %v, want %v", properties, tt.wantProperties)
+			}
+
+			for pth, want := range tt.wantManifests {
+				content, err := ioutil.ReadFile(filepath.Join(tmpDir, pth))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if string(content) != want {
+					t.Errorf("manifest (%s):\n%s\nwant:\n%s", pth, content, want)
+				}
+			}
+
+			// every stamped file is restored once, and every stamp is marked as restored
+			restoreStampedFiles()
+			for pth := range tt.wantManifests {
+				content, err := ioutil.ReadFile(filepath.Join(tmpDir, pth))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if string(content) != testManifest {
+					t.Errorf("restored manifest (%s):\n%s\nwant:\n%s", pth, content, testManifest)
+				}
+			}
+			for i, stamp := range stamps {
+				if stamp.Restored == tt.dryRun {
+					t.Errorf("stamp %d restored = %v, want %v", i, stamp.Restored, !tt.dryRun)
+				}
+			}
+		})
+	}
+}